@@ -4,6 +4,10 @@ import (
 	"context"
 	"evently/internal/api"
 	"evently/internal/config"
+	"evently/internal/data"
+	"evently/internal/jwtkeys"
+	"evently/internal/notify"
+	"evently/internal/service"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -11,6 +15,9 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	// Venue timezones are validated against the embedded zone database, so
+	// they do not depend on the image having tzdata installed.
+	_ "time/tzdata"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
@@ -43,7 +50,54 @@ func main() {
 
 	logger.Info("database connection established")
 
-	router := api.NewRouter(pool, logger, cfg.JWTSecret)
+	keys, err := jwtkeys.LoadDir(cfg.JWTKeysDir, cfg.JWTSigningKeyID)
+	if err != nil {
+		logger.Error("failed to load JWT keys", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("JWT keys loaded", "dir", cfg.JWTKeysDir, "keys", len(keys.JWKS().Keys))
+
+	notifier, err := newNotifier(cfg, logger)
+	if err != nil {
+		logger.Error("failed to configure notifier", "error", err)
+		os.Exit(1)
+	}
+
+	mailQueue := service.NewMailQueue(cfg.MailQueueSize, cfg.MailWorkers, logger)
+	router := api.NewRouter(pool, logger, cfg, keys, notifier, mailQueue)
+
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	bookingRepo := &data.BookingRepository{DB: pool}
+	holdService := service.NewHoldService(pool, &data.HoldRepository{DB: pool}, bookingRepo, nil, cfg.HoldTTL, logger)
+	go holdService.RunSweeper(workerCtx, cfg.HoldSweepInterval)
+	waitlistService := service.NewWaitlistService(pool, &data.WaitlistRepository{DB: pool}, bookingRepo, logger)
+	go waitlistService.RunOfferSweeper(workerCtx, cfg.OfferSweepInterval)
+	seriesService := service.NewSeriesService(pool, &data.SeriesRepository{DB: pool}, &data.EventRepository{DB: pool}, bookingRepo, &data.VenueRepository{DB: pool}, cfg.SeriesHorizon, logger)
+	go seriesService.RunGenerator(workerCtx, cfg.SeriesInterval)
+
+	dispatcher := service.NewNotificationDispatcher(&data.OutboxRepository{DB: pool}, notifier, logger)
+	go dispatcher.Run(workerCtx, cfg.NotifyInterval)
+	webhookDispatcher := service.NewWebhookDispatcher(&data.WebhookRepository{DB: pool}, cfg.WebhookTimeout, logger)
+	go webhookDispatcher.Run(workerCtx, cfg.WebhookInterval)
+
+	idempotencyRepo := &data.IdempotencyRepository{DB: pool}
+	go purgeHourly(workerCtx, "idempotency keys", func(ctx context.Context) (int64, error) {
+		return idempotencyRepo.DeleteOlderThan(ctx, time.Now().Add(-cfg.IdempotencyKeyTTL))
+	}, logger)
+	tokenRepo := &data.TokenRepository{DB: pool}
+	go purgeHourly(workerCtx, "expired sessions", func(ctx context.Context) (int64, error) {
+		return tokenRepo.DeleteExpired(ctx, time.Now())
+	}, logger)
+	loginAttemptRepo := &data.LoginAttemptRepository{DB: pool}
+	go purgeHourly(workerCtx, "stale login failures", func(ctx context.Context) (int64, error) {
+		return loginAttemptRepo.DeleteStale(ctx, time.Now().Add(-cfg.LoginFailureWindow))
+	}, logger)
+	twoFactorRepo := &data.TwoFactorRepository{DB: pool}
+	go purgeHourly(workerCtx, "expired login challenges", twoFactorRepo.DeleteExpiredChallenges, logger)
+	oidcRepo := &data.OIDCRepository{DB: pool}
+	go purgeHourly(workerCtx, "expired sso logins", oidcRepo.DeleteExpired, logger)
 
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Port),
@@ -66,6 +120,7 @@ func main() {
 	<-quit
 
 	logger.Info("shutting down server...")
+	stopWorkers()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -74,6 +129,55 @@ func main() {
 		logger.Error("server shutdown failed", "error", err)
 		os.Exit(1)
 	}
+	// Handlers have returned, so nothing more is queued; send what is left.
+	if err := mailQueue.Shutdown(ctx); err != nil {
+		logger.Error("mail queue shutdown failed", "error", err)
+	}
 
 	logger.Info("server exited gracefully")
 }
+
+// purgeHourly runs purge once per hour until ctx is cancelled, logging how
+// many rows of what it removed.
+func purgeHourly(ctx context.Context, what string, purge func(context.Context) (int64, error), logger *slog.Logger) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := purge(ctx)
+			if err != nil {
+				logger.Error("failed to purge "+what, "error", err)
+				continue
+			}
+			if deleted > 0 {
+				logger.Info("purged "+what, "count", deleted)
+			}
+		}
+	}
+}
+
+func newNotifier(cfg *config.Config, logger *slog.Logger) (notify.Notifier, error) {
+	switch cfg.Notifier {
+	case "log":
+		return &notify.LogNotifier{Log: logger}, nil
+	case "file":
+		return &notify.FileNotifier{Path: cfg.NotifierFile}, nil
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required when NOTIFIER=smtp")
+		}
+		return &notify.SMTPNotifier{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown NOTIFIER %q, expected log, file or smtp", cfg.Notifier)
+	}
+}
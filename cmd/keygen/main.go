@@ -0,0 +1,109 @@
+// Command keygen manages the JWT signing keys in JWT_KEYS_DIR.
+//
+//	keygen -dir keys -alg EdDSA     write a new signing key
+//	keygen -dir keys -retire <kid>  keep only the public half of a key
+//
+// New keys are named after their creation time so the newest sorts last.
+package main
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func main() {
+	dir := flag.String("dir", "keys", "key directory")
+	alg := flag.String("alg", "EdDSA", "key algorithm: EdDSA or RS256")
+	bits := flag.Int("bits", 3072, "RSA key size")
+	retire := flag.String("retire", "", "kid of a key to reduce to its public half")
+	flag.Parse()
+
+	var err error
+	if *retire != "" {
+		err = retireKey(*dir, *retire)
+	} else {
+		err = generateKey(*dir, *alg, *bits)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "keygen:", err)
+		os.Exit(1)
+	}
+}
+
+func generateKey(dir, alg string, bits int) error {
+	var signer crypto.Signer
+	var err error
+	switch alg {
+	case "EdDSA":
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	case "RS256":
+		signer, err = rsa.GenerateKey(rand.Reader, bits)
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	if err != nil {
+		return err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	kid := time.Now().UTC().Format("20060102T150405Z")
+	path := filepath.Join(dir, kid+".pem")
+	if err := writePEM(path, "PRIVATE KEY", der, 0o600); err != nil {
+		return err
+	}
+	fmt.Println(kid)
+	return nil
+}
+
+func retireKey(dir, kid string) error {
+	path := filepath.Join(dir, kid+".pem")
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return fmt.Errorf("%s is not a private key", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return err
+	}
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return fmt.Errorf("%s holds an unsupported key type", path)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return err
+	}
+	return writePEM(path, "PUBLIC KEY", der, 0o644)
+}
+
+// writePEM replaces path atomically so a running server never reads a
+// half-written key.
+func writePEM(path, blockType string, der []byte, perm os.FileMode) error {
+	tmp := path + ".tmp"
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
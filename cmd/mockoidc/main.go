@@ -0,0 +1,295 @@
+// Command mockoidc is a minimal OpenID Connect provider for trying single
+// sign-on locally. Its sign-in page asks for an email address and name and
+// vouches for whatever is entered, so never expose it.
+//
+//	mockoidc -addr :9000 -client-id evently
+//
+// then run the API with OIDC_ISSUER=http://localhost:9000 and
+// OIDC_CLIENT_ID=evently. Signing keys are generated at startup.
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	codeTTL    = time.Minute
+	idTokenTTL = 5 * time.Minute
+	keyID      = "mock-1"
+)
+
+// authCode is what the provider remembers between /authorize and /token.
+type authCode struct {
+	clientID      string
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+	email         string
+	name          string
+	emailVerified bool
+	expiresAt     time.Time
+}
+
+type provider struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	key          ed25519.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]authCode
+}
+
+func main() {
+	addr := flag.String("addr", ":9000", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9000", "issuer URL, as the API reaches it")
+	clientID := flag.String("client-id", "evently", "accepted client_id")
+	clientSecret := flag.String("client-secret", "", "client secret; empty accepts a public client")
+	flag.Parse()
+
+	p, err := newProvider(*issuer, *clientID, *clientSecret)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("mock OIDC provider %s listening on %s", p.issuer, *addr)
+	log.Fatal(http.ListenAndServe(*addr, p.routes()))
+}
+
+// newProvider creates a provider with a freshly generated signing key.
+func newProvider(issuer, clientID, clientSecret string) (*provider, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &provider{
+		issuer:       strings.TrimRight(issuer, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		key:          key,
+		codes:        map[string]authCode{},
+	}, nil
+}
+
+func (p *provider) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("GET /jwks", p.jwks)
+	mux.HandleFunc("GET /authorize", p.authorizeForm)
+	mux.HandleFunc("POST /authorize", p.authorize)
+	mux.HandleFunc("POST /token", p.token)
+	return mux
+}
+
+func (p *provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                p.issuer,
+		"authorization_endpoint":                p.issuer + "/authorize",
+		"token_endpoint":                        p.issuer + "/token",
+		"jwks_uri":                              p.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"EdDSA"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"scopes_supported":                      []string{"openid", "email", "profile"},
+	})
+}
+
+func (p *provider) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.Public().(ed25519.PublicKey)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "OKP", "crv": "Ed25519", "use": "sig", "alg": "EdDSA", "kid": keyID,
+			"x": base64.RawURLEncoding.EncodeToString(pub),
+		}},
+	})
+}
+
+var formTemplate = template.Must(template.New("form").Parse(`<!DOCTYPE html>
+<html><head><title>Mock OIDC sign-in</title></head>
+<body style="font-family: sans-serif; max-width: 24rem; margin: 4rem auto">
+<h1>Mock sign-in</h1>
+<p>Signing in to <b>{{.ClientID}}</b>. Any address is accepted.</p>
+<form method="post" action="/authorize?{{.Query}}">
+  <p><label>Email<br><input name="email" type="email" required autofocus></label></p>
+  <p><label>Name<br><input name="name"></label></p>
+  <p><label><input name="email_verified" type="checkbox" checked> Email verified</label></p>
+  <p><button type="submit">Sign in</button> <button type="submit" name="deny" value="1">Deny</button></p>
+</form>
+</body></html>`))
+
+// authorizeForm checks the request before showing the sign-in page, so a
+// misconfigured client gets an error here rather than after signing in.
+func (p *provider) authorizeForm(w http.ResponseWriter, r *http.Request) {
+	if msg := p.checkAuthorizeRequest(r.URL.Query()); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	formTemplate.Execute(w, map[string]any{
+		"ClientID": r.URL.Query().Get("client_id"),
+		"Query":    template.URL(r.URL.RawQuery),
+	})
+}
+
+func (p *provider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if msg := p.checkAuthorizeRequest(query); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	redirect, _ := url.Parse(query.Get("redirect_uri"))
+	params := url.Values{"state": {query.Get("state")}}
+
+	if r.FormValue("deny") != "" {
+		params.Set("error", "access_denied")
+	} else {
+		email := strings.TrimSpace(r.FormValue("email"))
+		if email == "" {
+			http.Error(w, "email is required", http.StatusBadRequest)
+			return
+		}
+		code := randomString()
+		p.mu.Lock()
+		p.codes[code] = authCode{
+			clientID:      query.Get("client_id"),
+			redirectURI:   query.Get("redirect_uri"),
+			codeChallenge: query.Get("code_challenge"),
+			nonce:         query.Get("nonce"),
+			email:         email,
+			name:          strings.TrimSpace(r.FormValue("name")),
+			emailVerified: r.FormValue("email_verified") != "",
+			expiresAt:     time.Now().Add(codeTTL),
+		}
+		p.mu.Unlock()
+		params.Set("code", code)
+	}
+
+	if redirect.RawQuery != "" {
+		redirect.RawQuery += "&"
+	}
+	redirect.RawQuery += params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (p *provider) checkAuthorizeRequest(query url.Values) string {
+	switch {
+	case query.Get("response_type") != "code":
+		return "response_type must be code"
+	case query.Get("client_id") != p.clientID:
+		return "unknown client_id"
+	case query.Get("redirect_uri") == "":
+		return "redirect_uri is required"
+	case !strings.Contains(" "+query.Get("scope")+" ", " openid "):
+		return "scope must include openid"
+	case query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256":
+		return "a S256 code_challenge is required"
+	}
+	if _, err := url.Parse(query.Get("redirect_uri")); err != nil {
+		return "redirect_uri is invalid"
+	}
+	return ""
+}
+
+func (p *provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type", "only authorization_code is supported")
+		return
+	}
+
+	clientID, secret, basic := r.BasicAuth()
+	if basic {
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID = r.PostForm.Get("client_id")
+	}
+	if clientID != p.clientID || (p.clientSecret != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(p.clientSecret)) != 1) {
+		w.Header().Set("WWW-Authenticate", "Basic")
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	p.mu.Lock()
+	code, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	switch {
+	case !ok || time.Now().After(code.expiresAt) || code.clientID != clientID:
+		tokenError(w, "invalid_grant", "code is invalid or expired")
+		return
+	case code.redirectURI != r.PostForm.Get("redirect_uri"):
+		tokenError(w, "invalid_grant", "redirect_uri does not match")
+		return
+	case base64.RawURLEncoding.EncodeToString(sum[:]) != code.codeChallenge:
+		tokenError(w, "invalid_grant", "code_verifier does not match")
+		return
+	}
+
+	now := time.Now()
+	subject := sha256.Sum256([]byte(strings.ToLower(code.email)))
+	claims := jwt.MapClaims{
+		"iss":            p.issuer,
+		"sub":            hex.EncodeToString(subject[:16]),
+		"aud":            clientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(idTokenTTL).Unix(),
+		"nonce":          code.nonce,
+		"email":          code.email,
+		"email_verified": code.emailVerified,
+	}
+	if code.name != "" {
+		claims["name"] = code.name
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = keyID
+	idToken, err := token.SignedString(p.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   int(idTokenTTL.Seconds()),
+		"id_token":     idToken,
+	})
+}
+
+func tokenError(w http.ResponseWriter, code, description string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code, "error_description": description})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("mockoidc: write response: %v", err)
+	}
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"evently/internal/oidc"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const testRedirectURL = "http://app.test/auth/oidc/callback"
+
+// startMock runs the mock provider on a local port and returns a relying
+// party configured for it.
+func startMock(t *testing.T, clientSecret string) *oidc.Provider {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(nil)
+	issuer := "http://" + srv.Listener.Addr().String()
+	p, err := newProvider(issuer, "evently", clientSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Config.Handler = p.routes()
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	return oidc.NewProvider(oidc.Config{
+		Issuer:       issuer,
+		ClientID:     "evently",
+		ClientSecret: clientSecret,
+		RedirectURL:  testRedirectURL,
+	})
+}
+
+// signIn submits the mock's sign-in form for the authorization URL and
+// returns the parameters it redirects back with.
+func signIn(t *testing.T, authURL string, form url.Values) url.Values {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET authorize status = %d, want 200", resp.StatusCode)
+	}
+
+	resp, err = client.PostForm(authURL, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("POST authorize status = %d, want 302", resp.StatusCode)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(location.String(), testRedirectURL+"?") {
+		t.Fatalf("redirected to %s, want %s", location, testRedirectURL)
+	}
+	return location.Query()
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	for _, secret := range []string{"", "s3cret"} {
+		t.Run("secret="+secret, func(t *testing.T) {
+			ctx := context.Background()
+			rp := startMock(t, secret)
+			verifier := "verifier-0123456789-0123456789-0123456789"
+
+			authURL, err := rp.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+			if err != nil {
+				t.Fatal(err)
+			}
+			params := signIn(t, authURL, url.Values{"email": {"ada@example.com"}, "name": {"Ada"}, "email_verified": {"on"}})
+			if params.Get("state") != "state-1" {
+				t.Errorf("state = %q, want state-1", params.Get("state"))
+			}
+
+			claims, err := rp.Exchange(ctx, params.Get("code"), verifier, "nonce-1")
+			if err != nil {
+				t.Fatalf("Exchange() error = %v", err)
+			}
+			if claims.Subject == "" || claims.Email != "ada@example.com" || !bool(claims.EmailVerified) || claims.Name != "Ada" {
+				t.Errorf("claims = %+v", claims)
+			}
+
+			if _, err := rp.Exchange(ctx, params.Get("code"), verifier, "nonce-1"); err == nil {
+				t.Error("Exchange() accepted a code twice")
+			}
+		})
+	}
+}
+
+func TestAuthorizationCodeFlowRejections(t *testing.T) {
+	ctx := context.Background()
+	rp := startMock(t, "")
+	verifier := "verifier-0123456789-0123456789-0123456789"
+	form := url.Values{"email": {"ada@example.com"}}
+
+	t.Run("wrong code verifier", func(t *testing.T) {
+		authURL, _ := rp.AuthCodeURL(ctx, "s", "n", verifier)
+		params := signIn(t, authURL, form)
+		if _, err := rp.Exchange(ctx, params.Get("code"), verifier+"x", "n"); err == nil {
+			t.Error("Exchange() accepted the wrong PKCE verifier")
+		}
+	})
+
+	t.Run("wrong nonce", func(t *testing.T) {
+		authURL, _ := rp.AuthCodeURL(ctx, "s", "n", verifier)
+		params := signIn(t, authURL, form)
+		_, err := rp.Exchange(ctx, params.Get("code"), verifier, "other")
+		if !errors.Is(err, oidc.ErrInvalidIDToken) {
+			t.Errorf("Exchange() error = %v, want ErrInvalidIDToken", err)
+		}
+	})
+
+	t.Run("unverified email", func(t *testing.T) {
+		authURL, _ := rp.AuthCodeURL(ctx, "s", "n", verifier)
+		params := signIn(t, authURL, form)
+		claims, err := rp.Exchange(ctx, params.Get("code"), verifier, "n")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if claims.EmailVerified {
+			t.Error("email_verified = true, want false")
+		}
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		authURL, _ := rp.AuthCodeURL(ctx, "s", "n", verifier)
+		params := signIn(t, authURL, url.Values{"deny": {"1"}})
+		if params.Get("error") != "access_denied" || params.Get("code") != "" {
+			t.Errorf("redirect params = %v, want error=access_denied", params)
+		}
+	})
+}
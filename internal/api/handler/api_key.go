@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"evently/internal/api/middleware"
+	"evently/internal/data"
+	"evently/internal/service"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// APIKeyHandler serves both /me/api-keys, where users manage their own keys,
+// and /admin/users/{id}/api-keys, where admins manage anyone's.
+type APIKeyHandler struct {
+	apiKeyService *service.APIKeyService
+	log           *slog.Logger
+}
+
+func NewAPIKeyHandler(apiKeyService *service.APIKeyService, log *slog.Logger) *APIKeyHandler {
+	return &APIKeyHandler{apiKeyService: apiKeyService, log: log}
+}
+
+// keyOwner is the route's {id} on admin routes and the caller otherwise.
+func keyOwner(r *http.Request) (string, bool) {
+	if id := chi.URLParam(r, "id"); id != "" {
+		return id, true
+	}
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	return userID, ok
+}
+
+func (h *APIKeyHandler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	ownerID, ok := keyOwner(r)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+
+	keys, err := h.apiKeyService.List(r.Context(), ownerID)
+	if err != nil {
+		h.log.Error("Failed to list api keys", "user_id", ownerID, "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not fetch API keys")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, keys)
+}
+
+func (h *APIKeyHandler) CreateKey(w http.ResponseWriter, r *http.Request) {
+	ownerID, ok := keyOwner(r)
+	actorID, okActor := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || !okActor {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+
+	var input struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON payload")
+		return
+	}
+
+	key, err := h.apiKeyService.Create(r.Context(), ownerID, actorID, input.Name, input.Scopes, input.ExpiresAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNotFound):
+			RespondWithError(w, http.StatusNotFound, "not_found", "User not found")
+		case errors.Is(err, service.ErrInvalidAPIKeyName):
+			RespondWithError(w, http.StatusUnprocessableEntity, "invalid_name", err.Error())
+		case errors.Is(err, service.ErrInvalidScopes):
+			RespondWithError(w, http.StatusUnprocessableEntity, "invalid_scopes", err.Error())
+		case errors.Is(err, service.ErrInvalidExpiry):
+			RespondWithError(w, http.StatusUnprocessableEntity, "invalid_expiry", err.Error())
+		default:
+			h.log.Error("Failed to create api key", "user_id", ownerID, "error", err)
+			RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not create API key")
+		}
+		return
+	}
+	RespondWithJSON(w, http.StatusCreated, key)
+}
+
+func (h *APIKeyHandler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	ownerID, ok := keyOwner(r)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+	keyID := chi.URLParam(r, "keyID")
+
+	if err := h.apiKeyService.Revoke(r.Context(), ownerID, keyID); err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			RespondWithError(w, http.StatusNotFound, "not_found", "API key not found")
+			return
+		}
+		h.log.Error("Failed to revoke api key", "key_id", keyID, "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not revoke API key")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
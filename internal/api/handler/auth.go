@@ -3,9 +3,13 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"evently/internal/api/middleware"
 	"evently/internal/service"
 	"log/slog"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 
 	"github.com/jackc/pgx/v5/pgconn"
 )
@@ -58,11 +62,287 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.authService.Login(r.Context(), input.Email, input.Password)
+	result, err := h.authService.Login(r.Context(), input.Email, input.Password, clientIP(r))
 	if err != nil {
-		RespondWithError(w, http.StatusUnauthorized, "unauthorized", err.Error())
+		var throttled *service.LoginThrottledError
+		switch {
+		case errors.Is(err, service.ErrInvalidCredentials):
+			RespondWithError(w, http.StatusUnauthorized, "unauthorized", err.Error())
+		case errors.Is(err, service.ErrAccountDisabled):
+			RespondWithError(w, http.StatusForbidden, "account_disabled", err.Error())
+		case errors.Is(err, service.ErrPasswordResetNeeded):
+			RespondWithError(w, http.StatusForbidden, "password_reset_required", err.Error())
+		case errors.As(err, &throttled):
+			respondThrottled(w, throttled)
+		default:
+			h.log.Error("Failure during login", "error", err)
+			RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not log in")
+		}
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, result)
+}
+
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.RefreshToken == "" {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "refresh_token is required")
+		return
+	}
+
+	tokens, err := h.authService.Refresh(r.Context(), input.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidRefreshToken):
+			RespondWithError(w, http.StatusUnauthorized, "invalid_refresh_token", err.Error())
+		case errors.Is(err, service.ErrRefreshTokenReused):
+			h.log.Warn("Refresh token reuse detected, session revoked")
+			RespondWithError(w, http.StatusUnauthorized, "refresh_token_reused", err.Error())
+		default:
+			h.log.Error("Failure during token refresh", "error", err)
+			RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not refresh token")
+		}
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, tokens)
+}
+
+// Logout takes the refresh token rather than the access token so a client can
+// end its session even after the access token has expired.
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.RefreshToken == "" {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "refresh_token is required")
+		return
+	}
+
+	if err := h.authService.Logout(r.Context(), input.RefreshToken); err != nil {
+		h.log.Error("Failure during logout", "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not log out")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+
+	revoked, err := h.authService.LogoutAll(r.Context(), userID)
+	if err != nil {
+		h.log.Error("Failure during logout-all", "user_id", userID, "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not log out")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, map[string]int64{"sessions_revoked": revoked})
+}
+
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Token == "" {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "token is required")
+		return
+	}
+
+	if err := h.authService.VerifyEmail(r.Context(), input.Token); err != nil {
+		if errors.Is(err, service.ErrInvalidUserToken) {
+			RespondWithError(w, http.StatusBadRequest, "invalid_token", err.Error())
+			return
+		}
+		h.log.Error("Failure during email verification", "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not verify email")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, map[string]string{"status": "email verified"})
+}
+
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+
+	if err := h.authService.ResendVerification(r.Context(), userID); err != nil {
+		if errors.Is(err, service.ErrAlreadyVerified) {
+			RespondWithError(w, http.StatusConflict, "already_verified", err.Error())
+			return
+		}
+		h.log.Error("Failure resending verification email", "user_id", userID, "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not send verification email")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusAccepted, map[string]string{"status": "verification email sent"})
+}
+
+// ForgotPassword answers 202 so callers cannot tell which emails have
+// accounts, or 429 when the address has asked for too many resets.
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Email == "" {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "email is required")
 		return
 	}
 
-	RespondWithJSON(w, http.StatusOK, map[string]string{"token": token})
+	if err := h.authService.RequestPasswordReset(r.Context(), input.Email); err != nil {
+		var throttled *service.LoginThrottledError
+		if errors.As(err, &throttled) {
+			respondThrottled(w, throttled)
+			return
+		}
+		h.log.Error("Failure requesting password reset", "error", err)
+	}
+
+	RespondWithJSON(w, http.StatusAccepted, map[string]string{"status": "if the address has an account, a reset link has been sent"})
+}
+
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Token == "" {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "token and password are required")
+		return
+	}
+
+	if err := h.authService.ResetPassword(r.Context(), input.Token, input.Password); err != nil {
+		switch {
+		case errors.Is(err, service.ErrWeakPassword):
+			RespondWithError(w, http.StatusUnprocessableEntity, "weak_password", err.Error())
+		case errors.Is(err, service.ErrInvalidUserToken):
+			RespondWithError(w, http.StatusBadRequest, "invalid_token", err.Error())
+		default:
+			h.log.Error("Failure during password reset", "error", err)
+			RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not reset password")
+		}
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, map[string]string{"status": "password updated, please log in again"})
+}
+
+// VerifyTwoFactor completes a login that answered with mfa_required, using
+// either a code from the authenticator app or a recovery code.
+func (h *AuthHandler) VerifyTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+		RecoveryCode   string `json:"recovery_code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.ChallengeToken == "" || (input.Code == "" && input.RecoveryCode == "") {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "challenge_token and either code or recovery_code are required")
+		return
+	}
+
+	tokens, err := h.authService.VerifyLoginChallenge(r.Context(), input.ChallengeToken, input.Code, input.RecoveryCode, clientIP(r))
+	if err != nil {
+		var throttled *service.LoginThrottledError
+		switch {
+		case errors.Is(err, service.ErrInvalidChallenge):
+			RespondWithError(w, http.StatusUnauthorized, "invalid_challenge", err.Error())
+		case errors.Is(err, service.ErrInvalidTOTPCode):
+			RespondWithError(w, http.StatusUnauthorized, "invalid_code", err.Error())
+		case errors.As(err, &throttled):
+			respondThrottled(w, throttled)
+		default:
+			h.log.Error("Failure during two-factor login", "error", err)
+			RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not log in")
+		}
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, tokens)
+}
+
+func (h *AuthHandler) SetupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+
+	setup, err := h.authService.SetupTwoFactor(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, service.ErrTwoFactorEnabled) {
+			RespondWithError(w, http.StatusConflict, "two_factor_enabled", err.Error())
+			return
+		}
+		h.log.Error("Failure starting two-factor setup", "user_id", userID, "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not start two-factor setup")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, setup)
+}
+
+func (h *AuthHandler) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+
+	var input struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Code == "" {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "code is required")
+		return
+	}
+
+	codes, err := h.authService.ConfirmTwoFactor(r.Context(), userID, input.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrTwoFactorEnabled):
+			RespondWithError(w, http.StatusConflict, "two_factor_enabled", err.Error())
+		case errors.Is(err, service.ErrTwoFactorNotStarted):
+			RespondWithError(w, http.StatusConflict, "two_factor_not_started", err.Error())
+		case errors.Is(err, service.ErrInvalidTOTPCode):
+			RespondWithError(w, http.StatusBadRequest, "invalid_code", err.Error())
+		default:
+			h.log.Error("Failure confirming two-factor setup", "user_id", userID, "error", err)
+			RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not enable two-factor authentication")
+		}
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, map[string]any{"recovery_codes": codes})
+}
+
+// clientIP returns the address chi's RealIP middleware left in RemoteAddr,
+// without the port when one is present.
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// respondThrottled answers a login attempt the login guard refused, telling
+// the client how long to wait.
+func respondThrottled(w http.ResponseWriter, throttled *service.LoginThrottledError) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+	code := "too_many_attempts"
+	if throttled.Locked {
+		code = "login_locked"
+	}
+	RespondWithError(w, http.StatusTooManyRequests, code, throttled.Error())
 }
@@ -4,9 +4,12 @@ import (
 	"encoding/json"
 	"errors"
 	"evently/internal/api/middleware"
+	"evently/internal/data"
 	"evently/internal/service"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -29,18 +32,30 @@ func (h *BookingHandler) CreateBooking(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var input struct {
-		Quantity int `json:"quantity"`
+		Quantity int      `json:"quantity"`
+		TierID   string   `json:"tier_id"`
+		SeatIDs  []string `json:"seat_ids"`
+		// Sections, MaxPriceCents and AllowSplit guide the seats picked
+		// for a quantity at events with reserved seating.
+		Sections      []string `json:"sections"`
+		MaxPriceCents *int64   `json:"max_price_cents"`
+		AllowSplit    bool     `json:"allow_split"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		input.Quantity = 1 // Default to 1 if no body or parsing fails
 	}
 
+	if input.SeatIDs != nil {
+		h.bookSeats(w, r, eventID, userID, input.TierID, input.SeatIDs)
+		return
+	}
 	if input.Quantity <= 0 {
 		RespondWithError(w, http.StatusBadRequest, "invalid_quantity", "Quantity must be greater than zero")
 		return
 	}
 
-	err := h.bookingService.CreateBooking(r.Context(), eventID, userID, input.Quantity)
+	prefs := service.SeatPreferences{Sections: input.Sections, MaxPriceCents: input.MaxPriceCents, AllowSplit: input.AllowSplit}
+	booking, err := h.bookingService.CreateBooking(r.Context(), eventID, userID, input.TierID, input.Quantity, prefs)
 	if err != nil {
 		switch {
 		case errors.Is(err, service.ErrAddedToWaitlist), errors.Is(err, service.ErrJoinWaitlist):
@@ -49,6 +64,20 @@ func (h *BookingHandler) CreateBooking(w http.ResponseWriter, r *http.Request) {
 			RespondWithError(w, http.StatusConflict, "sold_out", err.Error())
 		case errors.Is(err, service.ErrBookingConflict):
 			RespondWithError(w, http.StatusConflict, "booking_conflict", err.Error())
+		case errors.Is(err, service.ErrEventNotOpen):
+			RespondWithError(w, http.StatusConflict, "event_unavailable", err.Error())
+		case errors.Is(err, service.ErrEmailNotVerified):
+			RespondWithError(w, http.StatusForbidden, "email_not_verified", err.Error())
+		case errors.Is(err, service.ErrNoAdjacentSeats):
+			RespondWithError(w, http.StatusConflict, "no_adjacent_seats", err.Error())
+		case errors.Is(err, service.ErrTierRequired):
+			RespondWithError(w, http.StatusUnprocessableEntity, "tier_required", err.Error())
+		case errors.Is(err, service.ErrTierNotFound):
+			RespondWithError(w, http.StatusNotFound, "tier_not_found", err.Error())
+		case errors.Is(err, service.ErrTierNotOnSale):
+			RespondWithError(w, http.StatusConflict, "tier_not_on_sale", err.Error())
+		case errors.Is(err, data.ErrNotFound):
+			RespondWithError(w, http.StatusNotFound, "not_found", "Event not found")
 		default:
 			h.log.Error("Failed to create booking", "error", err)
 			RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not create booking")
@@ -56,7 +85,92 @@ func (h *BookingHandler) CreateBooking(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	RespondWithJSON(w, http.StatusCreated, map[string]interface{}{"status": "booking created", "quantity": input.Quantity})
+	if booking != nil {
+		RespondWithJSON(w, http.StatusCreated, map[string]interface{}{
+			"status": "booking created", "booking_id": booking.BookingID, "quantity": len(booking.Seats), "tier_id": booking.Seats[0].TierID, "seats": booking.Seats,
+		})
+		return
+	}
+	RespondWithJSON(w, http.StatusCreated, map[string]interface{}{"status": "booking created", "quantity": input.Quantity, "tier_id": input.TierID})
+}
+
+// bookSeats books the seats a user picked from the event's seat map.
+func (h *BookingHandler) bookSeats(w http.ResponseWriter, r *http.Request, eventID, userID, tierID string, seatIDs []string) {
+	if msg := checkSeatIDs(seatIDs); msg != "" {
+		RespondWithError(w, http.StatusUnprocessableEntity, "invalid_seats", msg)
+		return
+	}
+
+	booking, err := h.bookingService.BookSeats(r.Context(), eventID, userID, tierID, seatIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrSeatTaken):
+			RespondWithError(w, http.StatusConflict, "seat_taken", err.Error())
+		case errors.Is(err, service.ErrSeatNotFound):
+			RespondWithError(w, http.StatusUnprocessableEntity, "invalid_seats", err.Error())
+		case errors.Is(err, service.ErrNotSeated):
+			RespondWithError(w, http.StatusConflict, "not_seated", err.Error())
+		case errors.Is(err, service.ErrEventNotOpen):
+			RespondWithError(w, http.StatusConflict, "event_unavailable", err.Error())
+		case errors.Is(err, service.ErrEmailNotVerified):
+			RespondWithError(w, http.StatusForbidden, "email_not_verified", err.Error())
+		case errors.Is(err, service.ErrTierRequired):
+			RespondWithError(w, http.StatusUnprocessableEntity, "tier_required", err.Error())
+		case errors.Is(err, service.ErrTierNotFound):
+			RespondWithError(w, http.StatusNotFound, "tier_not_found", err.Error())
+		case errors.Is(err, service.ErrTierNotOnSale):
+			RespondWithError(w, http.StatusConflict, "tier_not_on_sale", err.Error())
+		case errors.Is(err, data.ErrNotFound):
+			RespondWithError(w, http.StatusNotFound, "not_found", "Event not found")
+		default:
+			h.log.Error("Failed to book seats", "event_id", eventID, "error", err)
+			RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not create booking")
+		}
+		return
+	}
+
+	RespondWithJSON(w, http.StatusCreated, map[string]interface{}{
+		"status": "booking created", "booking_id": booking.BookingID, "quantity": len(booking.Seats), "tier_id": tierID, "seats": booking.Seats,
+	})
+}
+
+// checkSeatIDs returns why a list of picked seats is unusable, or "" when
+// it is fine.
+func checkSeatIDs(seatIDs []string) string {
+	if len(seatIDs) == 0 {
+		return "seat_ids must list at least one seat"
+	}
+	seen := make(map[string]bool, len(seatIDs))
+	for _, id := range seatIDs {
+		if !isUUID(id) {
+			return fmt.Sprintf("seat id %q is not a valid UUID", id)
+		}
+		id = strings.ToLower(id)
+		if seen[id] {
+			return fmt.Sprintf("seat %s is listed more than once", id)
+		}
+		seen[id] = true
+	}
+	return ""
+}
+
+// isUUID reports whether s is a UUID in its canonical hyphenated form.
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case i == 8 || i == 13 || i == 18 || i == 23:
+			if c != '-' {
+				return false
+			}
+		case '0' <= c && c <= '9', 'a' <= c && c <= 'f', 'A' <= c && c <= 'F':
+		default:
+			return false
+		}
+	}
+	return true
 }
 
 func (h *BookingHandler) CancelBooking(w http.ResponseWriter, r *http.Request) {
@@ -105,3 +219,37 @@ func (h *BookingHandler) GetUserBookings(w http.ResponseWriter, r *http.Request)
 
 	RespondWithJSON(w, http.StatusOK, bookings)
 }
+
+// ListEventBookings lists an event's bookings for its organizers.
+func (h *BookingHandler) ListEventBookings(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "id")
+
+	limit, err := parseLimit(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid_limit", err.Error())
+		return
+	}
+	var after *data.EventBookingCursor
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		after = &data.EventBookingCursor{}
+		if err := decodeCursor(cursor, after); err != nil {
+			RespondWithError(w, http.StatusBadRequest, "invalid_cursor", "Cursor is invalid")
+			return
+		}
+	}
+
+	bookings, err := h.bookingService.ListEventBookings(r.Context(), eventID, after, limit+1)
+	if err != nil {
+		h.log.Error("Could not fetch event bookings", "event_id", eventID, "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not fetch bookings")
+		return
+	}
+
+	var nextCursor string
+	if len(bookings) > limit {
+		bookings = bookings[:limit]
+		last := bookings[limit-1]
+		nextCursor = encodeCursor(data.EventBookingCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	RespondWithPage(w, http.StatusOK, bookings, nextCursor)
+}
@@ -38,28 +38,105 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"evently/internal/api/middleware"
 	"evently/internal/data"
+	"evently/internal/service"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 )
 
+// defaultOfferMinutes is how long a waitlist offer stays open when an event is
+// created without an explicit window.
+const defaultOfferMinutes = 24 * 60
+
 type EventHandler struct {
-	eventRepo *data.EventRepository
+	eventRepo    *data.EventRepository
+	eventService *service.EventService
+	log          *slog.Logger
 }
 
-func NewEventHandler(eventRepo *data.EventRepository) *EventHandler {
-	return &EventHandler{eventRepo: eventRepo}
+func NewEventHandler(eventRepo *data.EventRepository, eventService *service.EventService, log *slog.Logger) *EventHandler {
+	return &EventHandler{eventRepo: eventRepo, eventService: eventService, log: log}
 }
 
+// ListEvents supports the query parameters q (name/venue search), venue,
+// venue_id, series_id, from/to (RFC 3339), available=true, include_past=true, sort
+// (start_time, -start_time, name, -name), limit and cursor. Past events are
+// hidden unless include_past or from is given.
 func (h *EventHandler) ListEvents(w http.ResponseWriter, r *http.Request) {
-	events, err := h.eventRepo.GetAll(r.Context())
+	query := r.URL.Query()
+
+	limit, err := parseLimit(r)
 	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid_limit", err.Error())
+		return
+	}
+
+	filter := data.EventFilter{
+		Search:        strings.TrimSpace(query.Get("q")),
+		Venue:         strings.TrimSpace(query.Get("venue")),
+		VenueID:       query.Get("venue_id"),
+		SeriesID:      query.Get("series_id"),
+		OnlyAvailable: query.Get("available") == "true",
+		Sort:          query.Get("sort"),
+		Limit:         limit + 1,
+	}
+
+	switch filter.Sort {
+	case "":
+		filter.Sort = data.EventSortStartAsc
+	case data.EventSortStartAsc, data.EventSortStartDesc, data.EventSortNameAsc, data.EventSortNameDesc:
+	default:
+		RespondWithError(w, http.StatusBadRequest, "invalid_sort", "sort must be one of start_time, -start_time, name, -name")
+		return
+	}
+
+	for param, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, "invalid_date", param+" must be an RFC 3339 timestamp")
+			return
+		}
+		*target = &t
+	}
+	if filter.From == nil && query.Get("include_past") != "true" {
+		now := time.Now()
+		filter.From = &now
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		var after data.EventCursor
+		if err := decodeCursor(cursor, &after); err != nil || after.Sort != filter.Sort {
+			RespondWithError(w, http.StatusBadRequest, "invalid_cursor", "Cursor is invalid or does not match the requested sort")
+			return
+		}
+		filter.After = &after
+	}
+
+	events, err := h.eventRepo.List(r.Context(), filter)
+	if err != nil {
+		h.log.Error("Failed to list events", "error", err)
 		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not fetch events")
 		return
 	}
-	RespondWithJSON(w, http.StatusOK, events)
+
+	var nextCursor string
+	if len(events) > limit {
+		events = events[:limit]
+		last := events[limit-1]
+		nextCursor = encodeCursor(data.EventCursor{Sort: filter.Sort, StartTime: last.StartTime, Name: last.Name, ID: last.ID})
+	}
+	RespondWithPage(w, http.StatusOK, events, nextCursor)
 }
 
 func (h *EventHandler) GetEvent(w http.ResponseWriter, r *http.Request) {
@@ -73,11 +150,73 @@ func (h *EventHandler) GetEvent(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+
 	var input struct {
-		Name      string    `json:"name"`
-		Venue     string    `json:"venue"`
-		StartTime time.Time `json:"start_time"`
-		Capacity  int       `json:"capacity"`
+		Name           string    `json:"name"`
+		Venue          string    `json:"venue"`
+		VenueID        *string   `json:"venue_id"`
+		StartTime      time.Time `json:"start_time"`
+		Capacity       *int      `json:"capacity"`
+		WaitlistPolicy string    `json:"waitlist_policy"`
+		OfferMinutes   int       `json:"waitlist_offer_minutes"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON payload")
+		return
+	}
+
+	if input.WaitlistPolicy == "" {
+		input.WaitlistPolicy = data.WaitlistPolicyAutoBook
+	}
+	if input.OfferMinutes == 0 {
+		input.OfferMinutes = defaultOfferMinutes
+	}
+
+	event, err := h.eventService.CreateEvent(r.Context(), service.NewEvent{
+		Name:           input.Name,
+		Venue:          input.Venue,
+		VenueID:        input.VenueID,
+		StartTime:      input.StartTime,
+		Capacity:       input.Capacity,
+		WaitlistPolicy: input.WaitlistPolicy,
+		OfferMinutes:   input.OfferMinutes,
+	}, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidWaitlist):
+			RespondWithError(w, http.StatusUnprocessableEntity, "invalid_waitlist_policy", err.Error())
+		case errors.Is(err, service.ErrVenueNotFound):
+			RespondWithError(w, http.StatusUnprocessableEntity, "unknown_venue", err.Error())
+		default:
+			h.log.Error("Failed to create event", "error", err)
+			RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not create event")
+		}
+		return
+	}
+
+	RespondWithJSON(w, http.StatusCreated, event)
+}
+
+func (h *EventHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var input struct {
+		Name      *string    `json:"name"`
+		Venue     *string    `json:"venue"`
+		VenueID   *string    `json:"venue_id"`
+		StartTime *time.Time `json:"start_time"`
+		Capacity  *int       `json:"capacity"`
+		Status    *string    `json:"status"`
+		Version   *int       `json:"version"`
+
+		WaitlistPolicy *string `json:"waitlist_policy"`
+		OfferMinutes   *int    `json:"waitlist_offer_minutes"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
@@ -85,18 +224,130 @@ func (h *EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	event := &data.Event{
+	if input.Version == nil {
+		RespondWithError(w, http.StatusBadRequest, "missing_version", "The current event version is required")
+		return
+	}
+	if r.Method == http.MethodPut && (input.Name == nil || input.Venue == nil && input.VenueID == nil || input.StartTime == nil || input.Capacity == nil) {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "PUT requires name, venue or venue_id, start_time and capacity")
+		return
+	}
+	if input.Capacity != nil && *input.Capacity < 0 {
+		RespondWithError(w, http.StatusBadRequest, "invalid_capacity", "Capacity cannot be negative")
+		return
+	}
+
+	event, err := h.eventService.UpdateEvent(r.Context(), id, service.EventUpdate{
 		Name:      input.Name,
 		Venue:     input.Venue,
+		VenueID:   input.VenueID,
 		StartTime: input.StartTime,
 		Capacity:  input.Capacity,
+		Status:    input.Status,
+		Version:   *input.Version,
+
+		WaitlistPolicy: input.WaitlistPolicy,
+		OfferMinutes:   input.OfferMinutes,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNotFound):
+			RespondWithError(w, http.StatusNotFound, "not_found", "Event not found")
+		case errors.Is(err, data.ErrConflict):
+			RespondWithError(w, http.StatusConflict, "edit_conflict", "The event was modified by someone else, reload and try again")
+		case errors.Is(err, service.ErrCapacityBelowBooked):
+			RespondWithError(w, http.StatusUnprocessableEntity, "capacity_too_low", err.Error())
+		case errors.Is(err, service.ErrInvalidStatus):
+			RespondWithError(w, http.StatusUnprocessableEntity, "invalid_status", err.Error())
+		case errors.Is(err, service.ErrInvalidWaitlist):
+			RespondWithError(w, http.StatusUnprocessableEntity, "invalid_waitlist_policy", err.Error())
+		case errors.Is(err, service.ErrEventCancelled):
+			RespondWithError(w, http.StatusConflict, "event_cancelled", err.Error())
+		case errors.Is(err, service.ErrVenueNotFound):
+			RespondWithError(w, http.StatusUnprocessableEntity, "unknown_venue", err.Error())
+		case errors.Is(err, service.ErrVenueLocked):
+			RespondWithError(w, http.StatusConflict, "venue_locked", err.Error())
+		default:
+			h.log.Error("Failed to update event", "event_id", id, "error", err)
+			RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not update event")
+		}
+		return
 	}
 
-	err := h.eventRepo.Create(r.Context(), event)
+	RespondWithJSON(w, http.StatusOK, event)
+}
+
+func (h *EventHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	version, err := strconv.Atoi(r.URL.Query().Get("version"))
 	if err != nil {
-		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not create event")
+		RespondWithError(w, http.StatusBadRequest, "missing_version", "The current event version is required")
 		return
 	}
 
-	RespondWithJSON(w, http.StatusCreated, event)
+	err = h.eventService.DeleteEvent(r.Context(), id, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNotFound):
+			RespondWithError(w, http.StatusNotFound, "not_found", "Event not found")
+		case errors.Is(err, data.ErrConflict):
+			RespondWithError(w, http.StatusConflict, "edit_conflict", "The event was modified by someone else, reload and try again")
+		case errors.Is(err, service.ErrEventHasBookings):
+			RespondWithError(w, http.StatusConflict, "event_has_bookings", err.Error())
+		default:
+			h.log.Error("Failed to delete event", "event_id", id, "error", err)
+			RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not delete event")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *EventHandler) CancelEvent(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	cancelledBy, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		input.Reason = "" // The reason is optional
+	}
+
+	cancellation, err := h.eventService.CancelEvent(r.Context(), id, cancelledBy, input.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNotFound):
+			RespondWithError(w, http.StatusNotFound, "not_found", "Event not found")
+		case errors.Is(err, service.ErrEventCancelled):
+			RespondWithError(w, http.StatusConflict, "event_cancelled", err.Error())
+		default:
+			h.log.Error("Failed to cancel event", "event_id", id, "error", err)
+			RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not cancel event")
+		}
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, cancellation)
+}
+
+func (h *EventHandler) GetCancellation(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	cancellation, err := h.eventService.GetCancellation(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			RespondWithError(w, http.StatusNotFound, "not_found", "Event has not been cancelled")
+			return
+		}
+		h.log.Error("Failed to fetch event cancellation", "event_id", id, "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not fetch cancellation")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, cancellation)
 }
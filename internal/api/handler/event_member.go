@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"evently/internal/api/middleware"
+	"evently/internal/authz"
+	"evently/internal/data"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// EventMemberHandler manages who, besides admins, may manage an event.
+type EventMemberHandler struct {
+	memberRepo *data.EventMemberRepository
+	log        *slog.Logger
+}
+
+func NewEventMemberHandler(memberRepo *data.EventMemberRepository, log *slog.Logger) *EventMemberHandler {
+	return &EventMemberHandler{memberRepo: memberRepo, log: log}
+}
+
+func (h *EventMemberHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "id")
+	members, err := h.memberRepo.List(r.Context(), eventID)
+	if err != nil {
+		h.log.Error("Failed to list event members", "event_id", eventID, "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not fetch members")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, members)
+}
+
+// PutMember adds a user to the event by email, or changes their member role.
+func (h *EventMemberHandler) PutMember(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "id")
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+
+	var input struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Email == "" {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "email and role are required")
+		return
+	}
+	if !authz.ValidMemberRole(input.Role) {
+		RespondWithError(w, http.StatusUnprocessableEntity, "invalid_role", "role must be owner or staff")
+		return
+	}
+
+	member, err := h.memberRepo.Put(r.Context(), eventID, input.Email, input.Role, userID)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			RespondWithError(w, http.StatusNotFound, "not_found", "Event or user not found")
+			return
+		}
+		h.log.Error("Failed to add event member", "event_id", eventID, "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not add member")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, member)
+}
+
+func (h *EventMemberHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "id")
+	memberID := chi.URLParam(r, "userID")
+
+	if err := h.memberRepo.Remove(r.Context(), eventID, memberID); err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			RespondWithError(w, http.StatusNotFound, "not_found", "Member not found")
+			return
+		}
+		h.log.Error("Failed to remove event member", "event_id", eventID, "user_id", memberID, "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not remove member")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"evently/internal/api/middleware"
+	"evently/internal/data"
+	"evently/internal/service"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type HoldHandler struct {
+	holdService *service.HoldService
+	log         *slog.Logger
+}
+
+func NewHoldHandler(holdService *service.HoldService, log *slog.Logger) *HoldHandler {
+	return &HoldHandler{holdService: holdService, log: log}
+}
+
+func (h *HoldHandler) CreateHold(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "id")
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+
+	var input struct {
+		Quantity int    `json:"quantity"`
+		TierID   string `json:"tier_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		input.Quantity = 1 // Default to 1 if no body or parsing fails
+	}
+
+	if input.Quantity <= 0 {
+		RespondWithError(w, http.StatusBadRequest, "invalid_quantity", "Quantity must be greater than zero")
+		return
+	}
+
+	hold, err := h.holdService.CreateHold(r.Context(), eventID, userID, input.TierID, input.Quantity)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNotFound):
+			RespondWithError(w, http.StatusNotFound, "not_found", "Event not found")
+		case errors.Is(err, service.ErrEventNotOpen):
+			RespondWithError(w, http.StatusConflict, "event_unavailable", err.Error())
+		case errors.Is(err, service.ErrEmailNotVerified):
+			RespondWithError(w, http.StatusForbidden, "email_not_verified", err.Error())
+		case errors.Is(err, service.ErrSeatsRequired):
+			RespondWithError(w, http.StatusUnprocessableEntity, "seats_required", err.Error())
+		case errors.Is(err, service.ErrTierRequired):
+			RespondWithError(w, http.StatusUnprocessableEntity, "tier_required", err.Error())
+		case errors.Is(err, service.ErrTierNotFound):
+			RespondWithError(w, http.StatusNotFound, "tier_not_found", err.Error())
+		case errors.Is(err, service.ErrTierNotOnSale):
+			RespondWithError(w, http.StatusConflict, "tier_not_on_sale", err.Error())
+		case errors.Is(err, service.ErrEventSoldOut):
+			RespondWithError(w, http.StatusConflict, "sold_out", err.Error())
+		case errors.Is(err, service.ErrBookingConflict):
+			RespondWithError(w, http.StatusConflict, "booking_conflict", err.Error())
+		default:
+			h.log.Error("Failed to create hold", "event_id", eventID, "error", err)
+			RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not hold tickets")
+		}
+		return
+	}
+
+	RespondWithJSON(w, http.StatusCreated, hold)
+}
+
+func (h *HoldHandler) ConfirmHold(w http.ResponseWriter, r *http.Request) {
+	holdID := chi.URLParam(r, "id")
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+
+	hold, err := h.holdService.ConfirmHold(r.Context(), holdID, userID)
+	if err != nil {
+		h.respondWithHoldError(w, holdID, err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusCreated, hold)
+}
+
+func (h *HoldHandler) ReleaseHold(w http.ResponseWriter, r *http.Request) {
+	holdID := chi.URLParam(r, "id")
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+
+	if err := h.holdService.ReleaseHold(r.Context(), holdID, userID); err != nil {
+		h.respondWithHoldError(w, holdID, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HoldHandler) respondWithHoldError(w http.ResponseWriter, holdID string, err error) {
+	switch {
+	case errors.Is(err, data.ErrNotFound):
+		RespondWithError(w, http.StatusNotFound, "not_found", "Hold not found")
+	case errors.Is(err, service.ErrHoldNotActive):
+		RespondWithError(w, http.StatusConflict, "hold_not_active", err.Error())
+	case errors.Is(err, service.ErrHoldExpired):
+		RespondWithError(w, http.StatusGone, "hold_expired", err.Error())
+	case errors.Is(err, service.ErrEventNotOpen):
+		RespondWithError(w, http.StatusConflict, "event_unavailable", err.Error())
+	default:
+		h.log.Error("Failed to process hold", "hold_id", holdID, "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not process hold")
+	}
+}
@@ -0,0 +1,19 @@
+package handler
+
+import (
+	"encoding/json"
+	"evently/internal/jwtkeys"
+	"net/http"
+)
+
+// JWKS serves the public verification keys. It is written as a bare JWK Set
+// rather than inside the usual response envelope, since that is what JWT
+// libraries expect to fetch.
+func JWKS(keys *jwtkeys.KeySet) http.HandlerFunc {
+	body, _ := json.Marshal(keys.JWKS())
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/jwk-set+json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		w.Write(body)
+	}
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"encoding/json"
+	"evently/internal/data"
+	"evently/internal/service"
+	"log/slog"
+	"net/http"
+)
+
+type LockoutHandler struct {
+	loginGuard *service.LoginGuard
+	log        *slog.Logger
+}
+
+func NewLockoutHandler(loginGuard *service.LoginGuard, log *slog.Logger) *LockoutHandler {
+	return &LockoutHandler{loginGuard: loginGuard, log: log}
+}
+
+func (h *LockoutHandler) ListLockouts(w http.ResponseWriter, r *http.Request) {
+	locked, err := h.loginGuard.ListLocked(r.Context())
+	if err != nil {
+		h.log.Error("Could not fetch login lockouts", "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not fetch lockouts")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, locked)
+}
+
+// Unlock clears the failure counters for an email, an IP, or both.
+func (h *LockoutHandler) Unlock(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email"`
+		IP    string `json:"ip"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || (input.Email == "" && input.IP == "") {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "email or ip is required")
+		return
+	}
+
+	cleared := false
+	for scope, key := range map[string]string{data.LoginScopeEmail: input.Email, data.LoginScopeIP: input.IP} {
+		if key == "" {
+			continue
+		}
+		ok, err := h.loginGuard.Unlock(r.Context(), scope, key)
+		if err != nil {
+			h.log.Error("Failed to unlock login", "scope", scope, "error", err)
+			RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not unlock")
+			return
+		}
+		cleared = cleared || ok
+	}
+	if !cleared {
+		RespondWithError(w, http.StatusNotFound, "not_found", "No failed attempts recorded for that email or ip")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"evently/internal/oidc"
+	"evently/internal/service"
+	"log/slog"
+	"net/http"
+)
+
+// OIDCHandler serves single sign-on. Start and Callback are browser
+// navigations and answer with redirects; Complete is called by the app.
+type OIDCHandler struct {
+	oidcService *service.OIDCService
+	log         *slog.Logger
+}
+
+func NewOIDCHandler(oidcService *service.OIDCService, log *slog.Logger) *OIDCHandler {
+	return &OIDCHandler{oidcService: oidcService, log: log}
+}
+
+// oidcStateCookie ties a sign-in to the browser that started it.
+const oidcStateCookie = "evently_oidc_state"
+
+func (h *OIDCHandler) Start(w http.ResponseWriter, r *http.Request) {
+	login, err := h.oidcService.StartLogin(r.Context())
+	if err != nil {
+		h.log.Error("Failed to start sso login", "error", err)
+		http.Redirect(w, r, h.oidcService.AppURL("", "sso_unavailable"), http.StatusFound)
+		return
+	}
+	// Lax still sends the cookie on the provider's top-level redirect back
+	// to the callback.
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    login.State,
+		Path:     "/auth/oidc",
+		Expires:  login.ExpiresAt,
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, login.URL, http.StatusFound)
+}
+
+// Callback is where the provider sends the browser back. Whatever happens,
+// the browser ends up back in the app, with an error code if sign-in failed.
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	var browserState string
+	if cookie, err := r.Cookie(oidcStateCookie); err == nil {
+		browserState = cookie.Value
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Path:     "/auth/oidc",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		h.log.Info("sso login refused by provider", "error", providerErr, "description", query.Get("error_description"))
+		http.Redirect(w, r, h.oidcService.AppURL("", "sso_denied"), http.StatusFound)
+		return
+	}
+
+	handoff, err := h.oidcService.HandleCallback(r.Context(), query.Get("state"), browserState, query.Get("code"))
+	if err != nil {
+		code := "sso_failed"
+		switch {
+		case errors.Is(err, service.ErrInvalidOIDCState):
+			code = "sso_expired"
+		case errors.Is(err, service.ErrOIDCEmailUnverified):
+			code = "sso_email_unverified"
+		case errors.Is(err, oidc.ErrInvalidIDToken):
+			h.log.Warn("Rejected sso id token", "error", err)
+		default:
+			h.log.Error("Failed to complete sso callback", "error", err)
+		}
+		http.Redirect(w, r, h.oidcService.AppURL("", code), http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, h.oidcService.AppURL(handoff, ""), http.StatusFound)
+}
+
+func (h *OIDCHandler) Complete(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Token == "" {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "token is required")
+		return
+	}
+
+	result, err := h.oidcService.CompleteLogin(r.Context(), input.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidHandoff):
+			RespondWithError(w, http.StatusBadRequest, "invalid_token", err.Error())
+		case errors.Is(err, service.ErrAccountDisabled):
+			RespondWithError(w, http.StatusForbidden, "account_disabled", err.Error())
+		default:
+			h.log.Error("Failure completing sso login", "error", err)
+			RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not log in")
+		}
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, result)
+}
+
+// isHTTPS reports whether the client reached the API over HTTPS, directly or
+// through a proxy that says so.
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"evently/internal/api/middleware"
+	"evently/internal/data"
+	"evently/internal/service"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+type ProfileHandler struct {
+	profileService *service.ProfileService
+	log            *slog.Logger
+}
+
+func NewProfileHandler(profileService *service.ProfileService, log *slog.Logger) *ProfileHandler {
+	return &ProfileHandler{profileService: profileService, log: log}
+}
+
+func (h *ProfileHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+
+	user, err := h.profileService.GetProfile(r.Context(), userID)
+	if err != nil {
+		h.respondWithError(w, userID, err)
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, user)
+}
+
+func (h *ProfileHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+	sessionID, _ := r.Context().Value(middleware.SessionIDKey).(string)
+
+	var input struct {
+		Name            *string `json:"name"`
+		Email           *string `json:"email"`
+		NewPassword     *string `json:"new_password"`
+		CurrentPassword string  `json:"current_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON payload")
+		return
+	}
+
+	user, err := h.profileService.UpdateProfile(r.Context(), userID, sessionID, service.ProfileUpdate{
+		Name:            input.Name,
+		Email:           input.Email,
+		NewPassword:     input.NewPassword,
+		CurrentPassword: input.CurrentPassword,
+	})
+	if err != nil {
+		h.respondWithError(w, userID, err)
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, user)
+}
+
+// Export sends the user's data as a downloadable JSON file.
+func (h *ProfileHandler) Export(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+
+	export, err := h.profileService.Export(r.Context(), userID)
+	if err != nil {
+		h.respondWithError(w, userID, err)
+		return
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="evently-export.json"`)
+	RespondWithJSON(w, http.StatusOK, export)
+}
+
+// DeleteAccount anonymizes the account. The password is asked for again so a
+// stolen access token cannot erase an account; accounts without a password
+// must make the request from a single sign-on session instead.
+func (h *ProfileHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+	sessionID, _ := r.Context().Value(middleware.SessionIDKey).(string)
+
+	var input struct {
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && !errors.Is(err, io.EOF) {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON payload")
+		return
+	}
+
+	if err := h.profileService.DeleteAccount(r.Context(), userID, sessionID, input.Password); err != nil {
+		h.respondWithError(w, userID, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ProfileHandler) respondWithError(w http.ResponseWriter, userID string, err error) {
+	switch {
+	case errors.Is(err, data.ErrNotFound):
+		RespondWithError(w, http.StatusNotFound, "not_found", "User not found")
+	case errors.Is(err, service.ErrWrongPassword):
+		RespondWithError(w, http.StatusForbidden, "wrong_password", err.Error())
+	case errors.Is(err, service.ErrSSORequired):
+		RespondWithError(w, http.StatusForbidden, "sso_required", err.Error())
+	case errors.Is(err, service.ErrEmailTaken):
+		RespondWithError(w, http.StatusConflict, "email_exists", err.Error())
+	case errors.Is(err, service.ErrInvalidName):
+		RespondWithError(w, http.StatusUnprocessableEntity, "invalid_name", err.Error())
+	case errors.Is(err, service.ErrWeakPassword):
+		RespondWithError(w, http.StatusUnprocessableEntity, "weak_password", err.Error())
+	default:
+		h.log.Error("Profile request failed", "user_id", userID, "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not complete the request")
+	}
+}
@@ -19,3 +19,16 @@ func RespondWithError(w http.ResponseWriter, code int, errCode, message string)
 	w.WriteHeader(code)
 	w.Write(response)
 }
+
+// RespondWithPage writes a list response with the cursor for the following
+// page. nextCursor is empty on the last page and is rendered as null.
+func RespondWithPage(w http.ResponseWriter, code int, payload interface{}, nextCursor string) {
+	var cursor interface{}
+	if nextCursor != "" {
+		cursor = nextCursor
+	}
+	response, _ := json.Marshal(map[string]interface{}{"data": payload, "error": nil, "next_cursor": cursor})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
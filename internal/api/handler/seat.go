@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"evently/internal/data"
+	"evently/internal/service"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// SeatHandler serves the seats of events with reserved seating.
+type SeatHandler struct {
+	seatingService *service.SeatingService
+	log            *slog.Logger
+}
+
+func NewSeatHandler(seatingService *service.SeatingService, log *slog.Logger) *SeatHandler {
+	return &SeatHandler{seatingService: seatingService, log: log}
+}
+
+func (h *SeatHandler) ListSeats(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "id")
+	seats, err := h.seatingService.ListEventSeats(r.Context(), eventID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotSeated):
+			RespondWithError(w, http.StatusNotFound, "not_seated", err.Error())
+		case errors.Is(err, data.ErrNotFound):
+			RespondWithError(w, http.StatusNotFound, "not_found", "Event not found")
+		default:
+			h.log.Error("Failed to list seats", "event_id", eventID, "error", err)
+			RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not fetch seats")
+		}
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, seats)
+}
+
+// AttachSeatMap gives the event reserved seating from a venue's seat map.
+// For events with tiers, section_tiers assigns each section a tier.
+func (h *SeatHandler) AttachSeatMap(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "id")
+	var input struct {
+		VenueID      string            `json:"venue_id"`
+		SectionTiers map[string]string `json:"section_tiers"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.VenueID == "" {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "venue_id is required")
+		return
+	}
+
+	seats, err := h.seatingService.AttachSeatMap(r.Context(), eventID, input.VenueID, input.SectionTiers)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrSeatMapLocked):
+			RespondWithError(w, http.StatusConflict, "seat_map_locked", err.Error())
+		case errors.Is(err, service.ErrNoSeats),
+			errors.Is(err, service.ErrSectionTierNeeded),
+			errors.Is(err, service.ErrInvalidSectionMap):
+			RespondWithError(w, http.StatusUnprocessableEntity, "invalid_seat_map", err.Error())
+		case errors.Is(err, data.ErrNotFound):
+			RespondWithError(w, http.StatusNotFound, "not_found", "Event or venue not found")
+		default:
+			h.log.Error("Failed to attach seat map", "event_id", eventID, "venue_id", input.VenueID, "error", err)
+			RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not attach seat map")
+		}
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, map[string]any{"event_id": eventID, "venue_id": input.VenueID, "seats": seats})
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"evently/internal/api/middleware"
+	"evently/internal/data"
+	"evently/internal/recurrence"
+	"evently/internal/service"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// SeriesHandler manages recurring event series.
+type SeriesHandler struct {
+	seriesService *service.SeriesService
+	log           *slog.Logger
+}
+
+func NewSeriesHandler(seriesService *service.SeriesService, log *slog.Logger) *SeriesHandler {
+	return &SeriesHandler{seriesService: seriesService, log: log}
+}
+
+func (h *SeriesHandler) CreateSeries(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+
+	var input service.SeriesInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON payload")
+		return
+	}
+	if input.WaitlistPolicy == "" {
+		input.WaitlistPolicy = data.WaitlistPolicyAutoBook
+	}
+	if input.OfferMinutes == 0 {
+		input.OfferMinutes = defaultOfferMinutes
+	}
+
+	series, err := h.seriesService.Create(r.Context(), input, userID)
+	if err != nil {
+		h.respondSeriesError(w, err, "", "Could not create event series")
+		return
+	}
+	RespondWithJSON(w, http.StatusCreated, series)
+}
+
+// GetSeries shows the series with its upcoming occurrences, or all of them
+// with include_past=true.
+func (h *SeriesHandler) GetSeries(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	series, err := h.seriesService.Get(r.Context(), id, r.URL.Query().Get("include_past") == "true")
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			RespondWithError(w, http.StatusNotFound, "not_found", "Event series not found")
+			return
+		}
+		h.log.Error("Failed to get event series", "series_id", id, "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not fetch event series")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, series)
+}
+
+// UpdateFollowing edits the event and the later occurrences of its series.
+// The body must carry the series version the client last read.
+func (h *SeriesHandler) UpdateFollowing(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "id")
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	role, _ := r.Context().Value(middleware.UserRoleKey).(string)
+	var input struct {
+		service.SeriesUpdate
+		Version *int `json:"version"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON payload")
+		return
+	}
+	if input.Version == nil {
+		RespondWithError(w, http.StatusBadRequest, "missing_version", "The current series version is required")
+		return
+	}
+
+	series, err := h.seriesService.UpdateFollowing(r.Context(), eventID, userID, role, *input.Version, input.SeriesUpdate)
+	if err != nil {
+		h.respondSeriesError(w, err, eventID, "Could not update event series")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, series)
+}
+
+func (h *SeriesHandler) respondSeriesError(w http.ResponseWriter, err error, eventID, message string) {
+	switch {
+	case errors.Is(err, recurrence.ErrInvalidRule):
+		RespondWithError(w, http.StatusUnprocessableEntity, "invalid_rrule", err.Error())
+	case errors.Is(err, service.ErrSeriesStartRequired),
+		errors.Is(err, service.ErrInvalidExclusion),
+		errors.Is(err, service.ErrNoOccurrences),
+		errors.Is(err, service.ErrInvalidCapacity),
+		errors.Is(err, service.ErrInvalidStartClock),
+		errors.Is(err, service.ErrInvalidTimezone):
+		RespondWithError(w, http.StatusUnprocessableEntity, "invalid_series", err.Error())
+	case errors.Is(err, service.ErrInvalidWaitlist):
+		RespondWithError(w, http.StatusUnprocessableEntity, "invalid_waitlist_policy", err.Error())
+	case errors.Is(err, service.ErrVenueNotFound):
+		RespondWithError(w, http.StatusUnprocessableEntity, "unknown_venue", err.Error())
+	case errors.Is(err, service.ErrCapacityBelowBooked):
+		RespondWithError(w, http.StatusUnprocessableEntity, "capacity_too_low", err.Error())
+	case errors.Is(err, service.ErrVenueLocked):
+		RespondWithError(w, http.StatusConflict, "venue_locked", err.Error())
+	case errors.Is(err, service.ErrNotSeriesOwner):
+		RespondWithError(w, http.StatusForbidden, "forbidden", err.Error())
+	case errors.Is(err, service.ErrNotInSeries):
+		RespondWithError(w, http.StatusConflict, "not_in_series", err.Error())
+	case errors.Is(err, data.ErrConflict):
+		RespondWithError(w, http.StatusConflict, "edit_conflict", "The series was modified by someone else, reload and try again")
+	case errors.Is(err, data.ErrNotFound):
+		RespondWithError(w, http.StatusNotFound, "not_found", "Event not found")
+	default:
+		h.log.Error(message, "event_id", eventID, "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", message)
+	}
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"evently/internal/api/middleware"
+	"evently/internal/data"
+	"evently/internal/service"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// SessionHandler manages the sessions of multi-session events and the
+// registrations attendees make for them.
+type SessionHandler struct {
+	sessionService *service.SessionService
+	log            *slog.Logger
+}
+
+func NewSessionHandler(sessionService *service.SessionService, log *slog.Logger) *SessionHandler {
+	return &SessionHandler{sessionService: sessionService, log: log}
+}
+
+// GetAgenda lists the event's sessions with the places left in each.
+func (h *SessionHandler) GetAgenda(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "id")
+	sessions, err := h.sessionService.Agenda(r.Context(), eventID)
+	if err != nil {
+		h.respondSessionError(w, err, eventID, "Could not fetch agenda")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, sessions)
+}
+
+func (h *SessionHandler) CreateSession(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "id")
+	var input service.SessionInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "Invalid request payload")
+		return
+	}
+
+	session, err := h.sessionService.Create(r.Context(), eventID, input)
+	if err != nil {
+		h.respondSessionError(w, err, eventID, "Could not create session")
+		return
+	}
+	RespondWithJSON(w, http.StatusCreated, session)
+}
+
+// UpdateSession changes the fields given in the body. The body must carry
+// the version the client last read.
+func (h *SessionHandler) UpdateSession(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "id")
+	sessionID := chi.URLParam(r, "sessionID")
+	var input struct {
+		service.SessionInput
+		Version *int `json:"version"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "Invalid request payload")
+		return
+	}
+	if input.Version == nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "version is required")
+		return
+	}
+
+	session, err := h.sessionService.Update(r.Context(), eventID, sessionID, *input.Version, input.SessionInput)
+	if err != nil {
+		h.respondSessionError(w, err, eventID, "Could not update session")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, session)
+}
+
+func (h *SessionHandler) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "id")
+	sessionID := chi.URLParam(r, "sessionID")
+	if err := h.sessionService.Delete(r.Context(), eventID, sessionID); err != nil {
+		h.respondSessionError(w, err, eventID, "Could not delete session")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *SessionHandler) Register(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "id")
+	sessionID := chi.URLParam(r, "sessionID")
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+
+	registration, err := h.sessionService.Register(r.Context(), eventID, sessionID, userID)
+	if err != nil {
+		h.respondSessionError(w, err, eventID, "Could not register for session")
+		return
+	}
+	RespondWithJSON(w, http.StatusCreated, registration)
+}
+
+func (h *SessionHandler) Unregister(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "id")
+	sessionID := chi.URLParam(r, "sessionID")
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+
+	if err := h.sessionService.Unregister(r.Context(), eventID, sessionID, userID); err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			RespondWithError(w, http.StatusNotFound, "not_found", "Session registration not found")
+			return
+		}
+		h.respondSessionError(w, err, eventID, "Could not cancel session registration")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetMyAgenda lists the upcoming sessions the user is registered for,
+// across all events.
+func (h *SessionHandler) GetMyAgenda(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+
+	agenda, err := h.sessionService.UserAgenda(r.Context(), userID)
+	if err != nil {
+		h.log.Error("Failed to fetch user agenda", "user_id", userID, "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not fetch agenda")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, agenda)
+}
+
+func (h *SessionHandler) respondSessionError(w http.ResponseWriter, err error, eventID, message string) {
+	switch {
+	case errors.Is(err, service.ErrSessionFieldsRequired),
+		errors.Is(err, service.ErrInvalidSessionTitle),
+		errors.Is(err, service.ErrInvalidRoom),
+		errors.Is(err, service.ErrInvalidSessionTime),
+		errors.Is(err, service.ErrInvalidCapacity):
+		RespondWithError(w, http.StatusUnprocessableEntity, "invalid_session", err.Error())
+	case errors.Is(err, service.ErrSessionCapBelowTaken):
+		RespondWithError(w, http.StatusConflict, "capacity_below_registered", err.Error())
+	case errors.Is(err, service.ErrSessionInUse):
+		RespondWithError(w, http.StatusConflict, "session_in_use", err.Error())
+	case errors.Is(err, service.ErrEmailNotVerified):
+		RespondWithError(w, http.StatusForbidden, "email_not_verified", err.Error())
+	case errors.Is(err, service.ErrBookingRequired):
+		RespondWithError(w, http.StatusForbidden, "booking_required", err.Error())
+	case errors.Is(err, service.ErrEventNotOpen):
+		RespondWithError(w, http.StatusConflict, "event_unavailable", err.Error())
+	case errors.Is(err, service.ErrSessionStarted):
+		RespondWithError(w, http.StatusConflict, "session_started", err.Error())
+	case errors.Is(err, service.ErrSessionFull):
+		RespondWithError(w, http.StatusConflict, "session_full", err.Error())
+	case errors.Is(err, service.ErrAlreadyRegistered):
+		RespondWithError(w, http.StatusConflict, "already_registered", err.Error())
+	case errors.Is(err, service.ErrSessionOverlap):
+		RespondWithError(w, http.StatusConflict, "schedule_overlap", err.Error())
+	case errors.Is(err, service.ErrRegistrantsOverlap):
+		RespondWithError(w, http.StatusConflict, "registrants_overlap", err.Error())
+	case errors.Is(err, service.ErrBookingConflict):
+		RespondWithError(w, http.StatusConflict, "booking_conflict", err.Error())
+	case errors.Is(err, data.ErrConflict):
+		RespondWithError(w, http.StatusConflict, "edit_conflict", "The session was modified by someone else, reload and try again")
+	case errors.Is(err, data.ErrNotFound):
+		RespondWithError(w, http.StatusNotFound, "not_found", "Event or session not found")
+	default:
+		h.log.Error(message, "event_id", eventID, "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", message)
+	}
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"evently/internal/data"
+	"evently/internal/service"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// TicketTierHandler manages the ticket tiers an event sells.
+type TicketTierHandler struct {
+	tierService *service.TicketTierService
+	log         *slog.Logger
+}
+
+func NewTicketTierHandler(tierService *service.TicketTierService, log *slog.Logger) *TicketTierHandler {
+	return &TicketTierHandler{tierService: tierService, log: log}
+}
+
+func (h *TicketTierHandler) ListTiers(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "id")
+	tiers, err := h.tierService.List(r.Context(), eventID)
+	if err != nil {
+		h.log.Error("Failed to list ticket tiers", "event_id", eventID, "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not fetch ticket tiers")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, tiers)
+}
+
+func (h *TicketTierHandler) CreateTier(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "id")
+	var input service.TierInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "Invalid request payload")
+		return
+	}
+
+	tier, err := h.tierService.Create(r.Context(), eventID, input)
+	if err != nil {
+		h.respondTierError(w, err, eventID, "Could not create ticket tier")
+		return
+	}
+	RespondWithJSON(w, http.StatusCreated, tier)
+}
+
+// UpdateTier changes the fields given in the body. The body must carry the
+// version the client last read.
+func (h *TicketTierHandler) UpdateTier(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "id")
+	tierID := chi.URLParam(r, "tierID")
+	var input struct {
+		service.TierInput
+		Version *int `json:"version"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "Invalid request payload")
+		return
+	}
+	if input.Version == nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "version is required")
+		return
+	}
+
+	tier, err := h.tierService.Update(r.Context(), eventID, tierID, *input.Version, input.TierInput)
+	if err != nil {
+		h.respondTierError(w, err, eventID, "Could not update ticket tier")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, tier)
+}
+
+func (h *TicketTierHandler) DeleteTier(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "id")
+	tierID := chi.URLParam(r, "tierID")
+	if err := h.tierService.Delete(r.Context(), eventID, tierID); err != nil {
+		h.respondTierError(w, err, eventID, "Could not delete ticket tier")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *TicketTierHandler) respondTierError(w http.ResponseWriter, err error, eventID, message string) {
+	switch {
+	case errors.Is(err, service.ErrTierFieldsRequired),
+		errors.Is(err, service.ErrInvalidTierName),
+		errors.Is(err, service.ErrInvalidPrice),
+		errors.Is(err, service.ErrInvalidCurrency),
+		errors.Is(err, service.ErrInvalidTierQty),
+		errors.Is(err, service.ErrInvalidSalesWindow):
+		RespondWithError(w, http.StatusUnprocessableEntity, "invalid_tier", err.Error())
+	case errors.Is(err, service.ErrTierNameTaken):
+		RespondWithError(w, http.StatusConflict, "tier_name_taken", err.Error())
+	case errors.Is(err, service.ErrTierQtyBelowSold):
+		RespondWithError(w, http.StatusConflict, "quantity_below_sold", err.Error())
+	case errors.Is(err, service.ErrTierInUse):
+		RespondWithError(w, http.StatusConflict, "tier_in_use", err.Error())
+	case errors.Is(err, data.ErrConflict):
+		RespondWithError(w, http.StatusConflict, "edit_conflict", "The ticket tier was modified by someone else, reload and try again")
+	case errors.Is(err, data.ErrNotFound):
+		RespondWithError(w, http.StatusNotFound, "not_found", "Event or ticket tier not found")
+	default:
+		h.log.Error(message, "event_id", eventID, "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", message)
+	}
+}
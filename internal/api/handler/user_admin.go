@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"evently/internal/api/middleware"
+	"evently/internal/data"
+	"evently/internal/service"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type UserAdminHandler struct {
+	userAdminService *service.UserAdminService
+	log              *slog.Logger
+}
+
+func NewUserAdminHandler(userAdminService *service.UserAdminService, log *slog.Logger) *UserAdminHandler {
+	return &UserAdminHandler{userAdminService: userAdminService, log: log}
+}
+
+func (h *UserAdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit, err := parseLimit(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid_limit", err.Error())
+		return
+	}
+
+	filter := data.UserFilter{
+		Search: strings.TrimSpace(query.Get("q")),
+		Role:   query.Get("role"),
+		Limit:  limit + 1,
+	}
+	if cursor := query.Get("cursor"); cursor != "" {
+		filter.After = &data.UserCursor{}
+		if err := decodeCursor(cursor, filter.After); err != nil {
+			RespondWithError(w, http.StatusBadRequest, "invalid_cursor", "Cursor is invalid")
+			return
+		}
+	}
+
+	users, err := h.userAdminService.ListUsers(r.Context(), filter)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRole) {
+			RespondWithError(w, http.StatusBadRequest, "invalid_role", err.Error())
+			return
+		}
+		h.log.Error("Failed to list users", "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not fetch users")
+		return
+	}
+
+	var nextCursor string
+	if len(users) > limit {
+		users = users[:limit]
+		last := users[limit-1]
+		nextCursor = encodeCursor(data.UserCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	RespondWithPage(w, http.StatusOK, users, nextCursor)
+}
+
+func (h *UserAdminHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	user, err := h.userAdminService.GetUser(r.Context(), id)
+	if err != nil {
+		h.respondWithError(w, id, err)
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, user)
+}
+
+func (h *UserAdminHandler) GetUserBookings(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	bookings, err := h.userAdminService.GetUserBookings(r.Context(), id)
+	if err != nil {
+		h.respondWithError(w, id, err)
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, bookings)
+}
+
+func (h *UserAdminHandler) GetUserWaitlist(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	waitlist, err := h.userAdminService.GetUserWaitlist(r.Context(), id)
+	if err != nil {
+		h.respondWithError(w, id, err)
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, waitlist)
+}
+
+func (h *UserAdminHandler) SetRole(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+
+	var input struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Role == "" {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "role is required")
+		return
+	}
+
+	user, err := h.userAdminService.SetRole(r.Context(), actorID, id, input.Role)
+	if err != nil {
+		h.respondWithError(w, id, err)
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, user)
+}
+
+func (h *UserAdminHandler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, true)
+}
+
+func (h *UserAdminHandler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, false)
+}
+
+func (h *UserAdminHandler) setDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	id := chi.URLParam(r, "id")
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+
+	user, err := h.userAdminService.SetDisabled(r.Context(), actorID, id, disabled)
+	if err != nil {
+		h.respondWithError(w, id, err)
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, user)
+}
+
+func (h *UserAdminHandler) ForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+
+	if err := h.userAdminService.ForcePasswordReset(r.Context(), actorID, id); err != nil {
+		h.respondWithError(w, id, err)
+		return
+	}
+	RespondWithJSON(w, http.StatusAccepted, map[string]string{"status": "password reset email sent"})
+}
+
+func (h *UserAdminHandler) respondWithError(w http.ResponseWriter, id string, err error) {
+	switch {
+	case errors.Is(err, data.ErrNotFound):
+		RespondWithError(w, http.StatusNotFound, "not_found", "User not found")
+	case errors.Is(err, service.ErrInvalidRole):
+		RespondWithError(w, http.StatusUnprocessableEntity, "invalid_role", err.Error())
+	case errors.Is(err, service.ErrSelfModification):
+		RespondWithError(w, http.StatusConflict, "self_modification", err.Error())
+	default:
+		h.log.Error("User admin request failed", "user_id", id, "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not complete the request")
+	}
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"evently/internal/data"
+	"evently/internal/service"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// VenueHandler manages venues and their seat maps.
+type VenueHandler struct {
+	venueService *service.VenueService
+	log          *slog.Logger
+}
+
+func NewVenueHandler(venueService *service.VenueService, log *slog.Logger) *VenueHandler {
+	return &VenueHandler{venueService: venueService, log: log}
+}
+
+func (h *VenueHandler) CreateVenue(w http.ResponseWriter, r *http.Request) {
+	var input service.VenueInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "Invalid request payload")
+		return
+	}
+
+	venue, err := h.venueService.Create(r.Context(), input)
+	if err != nil {
+		h.respondVenueError(w, err, "", "Could not create venue")
+		return
+	}
+	RespondWithJSON(w, http.StatusCreated, venue)
+}
+
+// ListVenues supports the query parameters q (name/address search), limit
+// and cursor. Venues are ordered by name.
+func (h *VenueHandler) ListVenues(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit, err := parseLimit(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid_limit", err.Error())
+		return
+	}
+
+	filter := data.VenueFilter{
+		Search: strings.TrimSpace(query.Get("q")),
+		Limit:  limit + 1,
+	}
+	if cursor := query.Get("cursor"); cursor != "" {
+		filter.After = &data.VenueCursor{}
+		if err := decodeCursor(cursor, filter.After); err != nil {
+			RespondWithError(w, http.StatusBadRequest, "invalid_cursor", "Cursor is invalid")
+			return
+		}
+	}
+
+	venues, err := h.venueService.List(r.Context(), filter)
+	if err != nil {
+		h.log.Error("Failed to list venues", "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not fetch venues")
+		return
+	}
+
+	var nextCursor string
+	if len(venues) > limit {
+		venues = venues[:limit]
+		last := venues[limit-1]
+		nextCursor = encodeCursor(data.VenueCursor{Name: last.Name, ID: last.ID})
+	}
+	RespondWithPage(w, http.StatusOK, venues, nextCursor)
+}
+
+func (h *VenueHandler) GetVenue(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	venue, err := h.venueService.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			RespondWithError(w, http.StatusNotFound, "not_found", "Venue not found")
+			return
+		}
+		h.log.Error("Failed to get venue", "venue_id", id, "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not fetch venue")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, venue)
+}
+
+// UpdateVenue changes the details given in the body. The body must carry the
+// version the client last read.
+func (h *VenueHandler) UpdateVenue(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var input struct {
+		service.VenueUpdate
+		Version *int `json:"version"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "Invalid request payload")
+		return
+	}
+	if input.Version == nil {
+		RespondWithError(w, http.StatusBadRequest, "missing_version", "The current venue version is required")
+		return
+	}
+
+	venue, err := h.venueService.Update(r.Context(), id, *input.Version, input.VenueUpdate)
+	if err != nil {
+		h.respondVenueError(w, err, id, "Could not update venue")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, venue)
+}
+
+func (h *VenueHandler) DeleteVenue(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := h.venueService.Delete(r.Context(), id); err != nil {
+		h.respondVenueError(w, err, id, "Could not delete venue")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *VenueHandler) respondVenueError(w http.ResponseWriter, err error, venueID, message string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidVenueName),
+		errors.Is(err, service.ErrInvalidAddress),
+		errors.Is(err, service.ErrInvalidTimezone),
+		errors.Is(err, service.ErrInvalidVenueCap),
+		errors.Is(err, service.ErrInvalidLocation),
+		errors.Is(err, service.ErrInvalidSection),
+		errors.Is(err, service.ErrInvalidSeatRow),
+		errors.Is(err, service.ErrTooManySeats):
+		RespondWithError(w, http.StatusUnprocessableEntity, "invalid_venue", err.Error())
+	case errors.Is(err, service.ErrVenueNameTaken):
+		RespondWithError(w, http.StatusConflict, "venue_name_taken", err.Error())
+	case errors.Is(err, service.ErrVenueInUse):
+		RespondWithError(w, http.StatusConflict, "venue_in_use", err.Error())
+	case errors.Is(err, data.ErrConflict):
+		RespondWithError(w, http.StatusConflict, "edit_conflict", "The venue was modified by someone else, reload and try again")
+	case errors.Is(err, data.ErrNotFound):
+		RespondWithError(w, http.StatusNotFound, "not_found", "Venue not found")
+	default:
+		h.log.Error(message, "venue_id", venueID, "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", message)
+	}
+}
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"evently/internal/api/middleware"
+	"evently/internal/data"
+	"evently/internal/service"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type WaitlistHandler struct {
+	waitlistService *service.WaitlistService
+	log             *slog.Logger
+}
+
+func NewWaitlistHandler(waitlistService *service.WaitlistService, log *slog.Logger) *WaitlistHandler {
+	return &WaitlistHandler{waitlistService: waitlistService, log: log}
+}
+
+func (h *WaitlistHandler) GetEntries(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+
+	entries, err := h.waitlistService.GetEntries(r.Context(), userID)
+	if err != nil {
+		h.log.Error("Could not fetch waitlist entries", "user_id", userID, "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not fetch waitlist")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, entries)
+}
+
+func (h *WaitlistHandler) UpdateEntry(w http.ResponseWriter, r *http.Request) {
+	entryID := chi.URLParam(r, "id")
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+
+	var input struct {
+		Quantity int `json:"quantity"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON payload")
+		return
+	}
+	if input.Quantity <= 0 {
+		RespondWithError(w, http.StatusBadRequest, "invalid_quantity", "Quantity must be greater than zero")
+		return
+	}
+
+	entry, err := h.waitlistService.UpdateEntry(r.Context(), entryID, userID, input.Quantity)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			RespondWithError(w, http.StatusNotFound, "not_found", "Waitlist entry not found")
+			return
+		}
+		h.log.Error("Failed to update waitlist entry", "entry_id", entryID, "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not update waitlist entry")
+		return
+	}
+	if entry == nil {
+		RespondWithJSON(w, http.StatusOK, map[string]string{"status": "promoted from the waitlist, check your bookings and offers"})
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, entry)
+}
+
+func (h *WaitlistHandler) LeaveWaitlist(w http.ResponseWriter, r *http.Request) {
+	entryID := chi.URLParam(r, "id")
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+
+	if err := h.waitlistService.LeaveWaitlist(r.Context(), entryID, userID); err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			RespondWithError(w, http.StatusNotFound, "not_found", "Waitlist entry not found")
+			return
+		}
+		h.log.Error("Failed to leave waitlist", "entry_id", entryID, "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not leave waitlist")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WaitlistHandler) GetOffers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+
+	offers, err := h.waitlistService.GetOffers(r.Context(), userID)
+	if err != nil {
+		h.log.Error("Could not fetch waitlist offers", "user_id", userID, "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not fetch offers")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, offers)
+}
+
+func (h *WaitlistHandler) AcceptOffer(w http.ResponseWriter, r *http.Request) {
+	offerID := chi.URLParam(r, "id")
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+
+	offer, err := h.waitlistService.AcceptOffer(r.Context(), offerID, userID)
+	if err != nil {
+		h.respondWithOfferError(w, offerID, err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusCreated, offer)
+}
+
+func (h *WaitlistHandler) DeclineOffer(w http.ResponseWriter, r *http.Request) {
+	offerID := chi.URLParam(r, "id")
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized", "Invalid user context")
+		return
+	}
+
+	if err := h.waitlistService.DeclineOffer(r.Context(), offerID, userID); err != nil {
+		h.respondWithOfferError(w, offerID, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WaitlistHandler) respondWithOfferError(w http.ResponseWriter, offerID string, err error) {
+	switch {
+	case errors.Is(err, data.ErrNotFound):
+		RespondWithError(w, http.StatusNotFound, "not_found", "Offer not found")
+	case errors.Is(err, service.ErrOfferNotPending):
+		RespondWithError(w, http.StatusConflict, "offer_not_pending", err.Error())
+	case errors.Is(err, service.ErrOfferExpired):
+		RespondWithError(w, http.StatusGone, "offer_expired", err.Error())
+	case errors.Is(err, service.ErrEventNotOpen):
+		RespondWithError(w, http.StatusConflict, "event_unavailable", err.Error())
+	default:
+		h.log.Error("Failed to process waitlist offer", "offer_id", offerID, "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not process offer")
+	}
+}
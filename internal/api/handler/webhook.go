@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"evently/internal/data"
+	"evently/internal/service"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type WebhookHandler struct {
+	webhookService *service.WebhookService
+	log            *slog.Logger
+}
+
+func NewWebhookHandler(webhookService *service.WebhookService, log *slog.Logger) *WebhookHandler {
+	return &WebhookHandler{webhookService: webhookService, log: log}
+}
+
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		URL        string   `json:"url"`
+		Secret     string   `json:"secret"`
+		EventTypes []string `json:"event_types"`
+		Active     *bool    `json:"active"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON payload")
+		return
+	}
+
+	sub := &data.WebhookSubscription{URL: input.URL, Secret: input.Secret, EventTypes: input.EventTypes, Active: true}
+	if input.Active != nil {
+		sub.Active = *input.Active
+	}
+
+	if err := h.webhookService.CreateSubscription(r.Context(), sub); err != nil {
+		h.respondWithError(w, "", err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusCreated, sub)
+}
+
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.webhookService.ListSubscriptions(r.Context())
+	if err != nil {
+		h.log.Error("Could not fetch webhooks", "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not fetch webhooks")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, subs)
+}
+
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	sub, err := h.webhookService.GetSubscription(r.Context(), id)
+	if err != nil {
+		h.respondWithError(w, id, err)
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, sub)
+}
+
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var input struct {
+		URL        *string  `json:"url"`
+		EventTypes []string `json:"event_types"`
+		Active     *bool    `json:"active"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON payload")
+		return
+	}
+
+	sub, err := h.webhookService.UpdateSubscription(r.Context(), id, service.WebhookUpdate{
+		URL:        input.URL,
+		EventTypes: input.EventTypes,
+		Active:     input.Active,
+	})
+	if err != nil {
+		h.respondWithError(w, id, err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, sub)
+}
+
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := h.webhookService.DeleteSubscription(r.Context(), id); err != nil {
+		h.respondWithError(w, id, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries is the delivery log for one subscription, newest first. It
+// accepts status (pending, delivered, dead), limit and cursor.
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	limit, err := parseLimit(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid_limit", err.Error())
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", data.DeliveryStatusPending, data.DeliveryStatusDelivered, data.DeliveryStatusDead:
+	default:
+		RespondWithError(w, http.StatusBadRequest, "invalid_status", "status must be one of pending, delivered, dead")
+		return
+	}
+
+	var after *data.DeliveryCursor
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		after = &data.DeliveryCursor{}
+		if err := decodeCursor(cursor, after); err != nil {
+			RespondWithError(w, http.StatusBadRequest, "invalid_cursor", "Cursor is invalid")
+			return
+		}
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(r.Context(), id, status, after, limit+1)
+	if err != nil {
+		h.respondWithError(w, id, err)
+		return
+	}
+
+	var nextCursor string
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+		last := deliveries[limit-1]
+		nextCursor = encodeCursor(data.DeliveryCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	RespondWithPage(w, http.StatusOK, deliveries, nextCursor)
+}
+
+func (h *WebhookHandler) RetryDelivery(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	deliveryID := chi.URLParam(r, "deliveryID")
+
+	if err := h.webhookService.RetryDelivery(r.Context(), id, deliveryID); err != nil {
+		h.respondWithError(w, id, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *WebhookHandler) respondWithError(w http.ResponseWriter, id string, err error) {
+	switch {
+	case errors.Is(err, data.ErrNotFound):
+		RespondWithError(w, http.StatusNotFound, "not_found", "Webhook or delivery not found")
+	case errors.Is(err, service.ErrInvalidWebhookURL):
+		RespondWithError(w, http.StatusUnprocessableEntity, "invalid_url", err.Error())
+	case errors.Is(err, service.ErrInvalidEventTypes):
+		RespondWithError(w, http.StatusUnprocessableEntity, "invalid_event_types", err.Error())
+	case errors.Is(err, service.ErrDeliveryNotDead):
+		RespondWithError(w, http.StatusConflict, "delivery_not_dead", err.Error())
+	default:
+		h.log.Error("Failed to process webhook request", "webhook_id", id, "error", err)
+		RespondWithError(w, http.StatusInternalServerError, "server_error", "Could not process webhook request")
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"context"
+	"evently/internal/data"
+	"net/http"
+	"strings"
+)
+
+const apiKeyScheme = "ApiKey "
+
+const APIKeyIDKey contextKey = "apiKeyID"
+
+// ScopesKey holds the scopes of the API key used for the request. It is
+// unset for requests authenticated with a login session.
+const ScopesKey contextKey = "scopes"
+
+// APIKeyVerifier resolves an API key to the user it acts as, returning a nil
+// identity for keys that cannot be used.
+type APIKeyVerifier interface {
+	VerifyAPIKey(ctx context.Context, key string) (*data.APIKeyIdentity, error)
+}
+
+// Authenticate accepts either an "Authorization: ApiKey ..." header or
+// whatever jwtAuth accepts. API key requests get the same UserIDKey and
+// UserRoleKey values as a login session, but no session ID, and are limited
+// to their scopes by RequirePermission.
+func Authenticate(jwtAuth func(http.Handler) http.Handler, apiKeys APIKeyVerifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		withJWT := jwtAuth(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			raw, ok := strings.CutPrefix(r.Header.Get("Authorization"), apiKeyScheme)
+			if !ok {
+				withJWT.ServeHTTP(w, r)
+				return
+			}
+
+			identity, err := apiKeys.VerifyAPIKey(r.Context(), strings.TrimSpace(raw))
+			if err != nil {
+				http.Error(w, "Could not verify API key", http.StatusInternalServerError)
+				return
+			}
+			if identity == nil {
+				http.Error(w, "Invalid API key", http.StatusUnauthorized)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserIDKey, identity.UserID)
+			ctx = context.WithValue(ctx, UserRoleKey, identity.Role)
+			ctx = context.WithValue(ctx, APIKeyIDKey, identity.KeyID)
+			ctx = context.WithValue(ctx, ScopesKey, identity.Scopes)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
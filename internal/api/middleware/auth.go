@@ -2,10 +2,13 @@ package middleware
 
 import (
 	"context"
-	"fmt"
+	"evently/internal/authz"
+	"evently/internal/jwtkeys"
 	"net/http"
+	"slices"
 	"strings"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -13,8 +16,18 @@ type contextKey string
 
 const UserIDKey contextKey = "userID"
 const UserRoleKey contextKey = "userRole"
+const SessionIDKey contextKey = "sessionID"
 
-func JWTAuth(jwtSecret string) func(http.Handler) http.Handler {
+// MFAKey is true when the session was started with a second factor.
+const MFAKey contextKey = "mfa"
+
+// SessionChecker reports whether a login session has been revoked, so access
+// tokens can be rejected before they expire.
+type SessionChecker interface {
+	IsSessionRevoked(ctx context.Context, sessionID string) (bool, error)
+}
+
+func JWTAuth(keys *jwtkeys.KeySet, issuer string, sessions SessionChecker) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
@@ -24,12 +37,11 @@ func JWTAuth(jwtSecret string) func(http.Handler) http.Handler {
 			}
 
 			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-			token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-				}
-				return []byte(jwtSecret), nil
-			})
+			token, err := jwt.Parse(tokenString, keys.Keyfunc,
+				jwt.WithValidMethods(keys.Algorithms()),
+				jwt.WithIssuer(issuer),
+				jwt.WithExpirationRequired(),
+			)
 
 			if err != nil || !token.Valid {
 				http.Error(w, "Invalid token", http.StatusUnauthorized)
@@ -39,14 +51,27 @@ func JWTAuth(jwtSecret string) func(http.Handler) http.Handler {
 			if claims, ok := token.Claims.(jwt.MapClaims); ok {
 				userID, okID := claims["sub"].(string)
 				userRole, okRole := claims["role"].(string)
+				sessionID, okSession := claims["sid"].(string)
 
-				if !okID || !okRole {
+				if !okID || !okRole || !okSession {
 					http.Error(w, "Invalid token claims", http.StatusUnauthorized)
 					return
 				}
 
+				revoked, err := sessions.IsSessionRevoked(r.Context(), sessionID)
+				if err != nil {
+					http.Error(w, "Could not verify session", http.StatusInternalServerError)
+					return
+				}
+				if revoked {
+					http.Error(w, "Token has been revoked", http.StatusUnauthorized)
+					return
+				}
+
 				ctx := context.WithValue(r.Context(), UserIDKey, userID)
 				ctx = context.WithValue(ctx, UserRoleKey, userRole)
+				ctx = context.WithValue(ctx, SessionIDKey, sessionID)
+				ctx = context.WithValue(ctx, MFAKey, hasAMR(claims, "otp"))
 				next.ServeHTTP(w, r.WithContext(ctx))
 			} else {
 				http.Error(w, "Invalid token claims", http.StatusUnauthorized)
@@ -55,13 +80,68 @@ func JWTAuth(jwtSecret string) func(http.Handler) http.Handler {
 	}
 }
 
-func AdminOnly(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		role, ok := r.Context().Value(UserRoleKey).(string)
-		if !ok || role != "admin" {
-			http.Error(w, "Admin access required", http.StatusForbidden)
-			return
+// hasAMR reports whether the token's amr claim lists method.
+func hasAMR(claims jwt.MapClaims, method string) bool {
+	methods, _ := claims["amr"].([]any)
+	for _, m := range methods {
+		if m == method {
+			return true
 		}
-		next.ServeHTTP(w, r)
-	})
+	}
+	return false
+}
+
+// RequireMFA rejects sessions of users with the given role that were not
+// started with a second factor.
+func RequireMFA(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userRole, _ := r.Context().Value(UserRoleKey).(string)
+			if mfa, _ := r.Context().Value(MFAKey).(bool); userRole == role && !mfa {
+				http.Error(w, "Two-factor authentication required", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequirePermission rejects users whose global role does not grant perm, and
+// API keys without perm among their scopes.
+func RequirePermission(az *authz.Authorizer, perm authz.Permission) func(http.Handler) http.Handler {
+	return requirePermission(az, perm, "")
+}
+
+// RequireEventPermission rejects users who hold perm neither globally nor on
+// the event named by the route's {param}.
+func RequireEventPermission(az *authz.Authorizer, perm authz.Permission, param string) func(http.Handler) http.Handler {
+	return requirePermission(az, perm, param)
+}
+
+func requirePermission(az *authz.Authorizer, perm authz.Permission, param string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, _ := r.Context().Value(UserIDKey).(string)
+			role, _ := r.Context().Value(UserRoleKey).(string)
+			if scopes, ok := r.Context().Value(ScopesKey).([]string); ok && !slices.Contains(scopes, string(perm)) {
+				http.Error(w, "API key lacks the "+string(perm)+" scope", http.StatusForbidden)
+				return
+			}
+			var eventID string
+			if param != "" {
+				eventID = chi.URLParam(r, param)
+			}
+
+			allowed, err := az.Can(r.Context(), userID, role, perm, eventID)
+			if err != nil {
+				http.Error(w, "Could not check permissions", http.StatusInternalServerError)
+				return
+			}
+			if !allowed {
+				http.Error(w, "Permission "+string(perm)+" required", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
 }
@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"evently/internal/data"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+const (
+	idempotencyHeader = "Idempotency-Key"
+	replayedHeader    = "Idempotent-Replayed"
+	maxIdempotencyKey = 255
+	maxIdempotentBody = 1 << 20
+)
+
+// IdempotencyStore keeps the reserved keys and their responses; it is
+// implemented by data.IdempotencyRepository.
+type IdempotencyStore interface {
+	Reserve(ctx context.Context, record *data.IdempotencyRecord) (bool, error)
+	Complete(ctx context.Context, userID, key string, status int, contentType string, body []byte) error
+	Release(ctx context.Context, userID, key string) error
+}
+
+// Idempotency replays the stored response when a client repeats a request
+// with the same Idempotency-Key header. It must run after JWTAuth because
+// keys are scoped to the authenticated user. Requests without the header
+// pass through untouched.
+func Idempotency(repo IdempotencyStore, log *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(idempotencyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKey {
+				http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+				return
+			}
+
+			userID, ok := r.Context().Value(UserIDKey).(string)
+			if !ok {
+				http.Error(w, "Invalid user context", http.StatusUnauthorized)
+				return
+			}
+
+			// Read one byte past the limit so a larger body is refused rather
+			// than hashed and replayed truncated.
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBody+1))
+			if err != nil {
+				http.Error(w, "Could not read request body", http.StatusBadRequest)
+				return
+			}
+			if len(body) > maxIdempotentBody {
+				http.Error(w, "Request body is too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			hash := sha256.New()
+			hash.Write([]byte(r.Method + "\n" + r.URL.Path + "\n"))
+			hash.Write(body)
+
+			record := &data.IdempotencyRecord{
+				UserID:        userID,
+				Key:           key,
+				RequestMethod: r.Method,
+				RequestPath:   r.URL.Path,
+				RequestHash:   hex.EncodeToString(hash.Sum(nil)),
+			}
+			requestHash := record.RequestHash
+
+			created, err := repo.Reserve(r.Context(), record)
+			if err != nil {
+				if errors.Is(err, data.ErrConflict) {
+					http.Error(w, "A request with this Idempotency-Key is already in progress", http.StatusConflict)
+					return
+				}
+				log.Error("failed to reserve idempotency key", "error", err)
+				http.Error(w, "Could not process request", http.StatusInternalServerError)
+				return
+			}
+
+			if !created {
+				switch {
+				case record.RequestHash != requestHash:
+					http.Error(w, "Idempotency-Key has already been used for a different request", http.StatusUnprocessableEntity)
+				case !record.Completed():
+					http.Error(w, "A request with this Idempotency-Key is already in progress", http.StatusConflict)
+				default:
+					if record.ResponseContentType != "" {
+						w.Header().Set("Content-Type", record.ResponseContentType)
+					}
+					w.Header().Set(replayedHeader, "true")
+					w.WriteHeader(*record.ResponseStatus)
+					w.Write(record.ResponseBody)
+				}
+				return
+			}
+
+			// Persist the outcome even if the client has already gone away.
+			ctx := context.WithoutCancel(r.Context())
+
+			// A handler that panics never completes the key; free it so a
+			// retry is not refused as in progress, then let the panic go on
+			// to the recoverer.
+			defer func() {
+				if p := recover(); p != nil {
+					if err := repo.Release(ctx, userID, key); err != nil {
+						log.Error("failed to release idempotency key", "error", err)
+					}
+					panic(p)
+				}
+			}()
+
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			if rec.status >= http.StatusInternalServerError {
+				if err := repo.Release(ctx, userID, key); err != nil {
+					log.Error("failed to release idempotency key", "error", err)
+				}
+				return
+			}
+			if err := repo.Complete(ctx, userID, key, rec.status, rec.Header().Get("Content-Type"), rec.body.Bytes()); err != nil {
+				log.Error("failed to store idempotent response", "error", err)
+			}
+		})
+	}
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
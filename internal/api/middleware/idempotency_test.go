@@ -0,0 +1,195 @@
+package middleware
+
+import (
+	"context"
+	"evently/internal/data"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memoryStore is an in-memory IdempotencyStore.
+type memoryStore struct {
+	mu       sync.Mutex
+	records  map[string]data.IdempotencyRecord
+	released int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{records: make(map[string]data.IdempotencyRecord)}
+}
+
+func (m *memoryStore) Reserve(ctx context.Context, record *data.IdempotencyRecord) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := record.UserID + "/" + record.Key
+	if stored, ok := m.records[id]; ok {
+		*record = stored
+		return false, nil
+	}
+	m.records[id] = *record
+	return true, nil
+}
+
+func (m *memoryStore) Complete(ctx context.Context, userID, key string, status int, contentType string, body []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := userID + "/" + key
+	record := m.records[id]
+	record.ResponseStatus = &status
+	record.ResponseContentType = contentType
+	record.ResponseBody = body
+	m.records[id] = record
+	return nil
+}
+
+func (m *memoryStore) Release(ctx context.Context, userID, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.records, userID+"/"+key)
+	m.released++
+	return nil
+}
+
+// countingHandler answers with status and counts how often it ran.
+type countingHandler struct {
+	status int
+	calls  int
+}
+
+func (h *countingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.calls++
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(h.status)
+	w.Write([]byte(`{"echo":"` + string(body) + `"}`))
+}
+
+func idempotentRequest(key, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/events/e1/book", strings.NewReader(body))
+	if key != "" {
+		r.Header.Set(idempotencyHeader, key)
+	}
+	return r.WithContext(context.WithValue(r.Context(), UserIDKey, "user-1"))
+}
+
+func serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestIdempotencyReplaysResponse(t *testing.T) {
+	store := newMemoryStore()
+	next := &countingHandler{status: http.StatusCreated}
+	h := Idempotency(store, slog.Default())(next)
+
+	first := serve(h, idempotentRequest("k1", "a"))
+	again := serve(h, idempotentRequest("k1", "a"))
+
+	if next.calls != 1 {
+		t.Fatalf("handler ran %d times, want 1", next.calls)
+	}
+	if again.Code != http.StatusCreated || again.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %q, want %d %q", again.Code, again.Body, first.Code, first.Body)
+	}
+	if again.Header().Get(replayedHeader) != "true" || again.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("replay headers = %v", again.Header())
+	}
+	if first.Header().Get(replayedHeader) != "" {
+		t.Error("first response marked as replayed")
+	}
+}
+
+func TestIdempotencyRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		first    string
+		key      string
+		body     string
+		wantCode int
+	}{
+		{name: "different body under the same key", first: "a", key: "k1", body: "b", wantCode: http.StatusUnprocessableEntity},
+		{name: "body over the size cap", key: "k2", body: strings.Repeat("x", maxIdempotentBody+1), wantCode: http.StatusRequestEntityTooLarge},
+		{name: "key too long", key: strings.Repeat("k", maxIdempotencyKey+1), body: "a", wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemoryStore()
+			next := &countingHandler{status: http.StatusCreated}
+			h := Idempotency(store, slog.Default())(next)
+			if tt.first != "" {
+				serve(h, idempotentRequest(tt.key, tt.first))
+			}
+			calls := next.calls
+
+			w := serve(h, idempotentRequest(tt.key, tt.body))
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if next.calls != calls {
+				t.Error("handler ran for a rejected request")
+			}
+		})
+	}
+}
+
+func TestIdempotencyBodyAtCapPasses(t *testing.T) {
+	next := &countingHandler{status: http.StatusOK}
+	h := Idempotency(newMemoryStore(), slog.Default())(next)
+	if w := serve(h, idempotentRequest("k1", strings.Repeat("x", maxIdempotentBody))); w.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", w.Code)
+	}
+}
+
+func TestIdempotencyReleasesKeyOnServerError(t *testing.T) {
+	store := newMemoryStore()
+	next := &countingHandler{status: http.StatusInternalServerError}
+	h := Idempotency(store, slog.Default())(next)
+
+	serve(h, idempotentRequest("k1", "a"))
+	if store.released != 1 {
+		t.Fatalf("released %d keys, want 1", store.released)
+	}
+
+	next.status = http.StatusCreated
+	if w := serve(h, idempotentRequest("k1", "a")); w.Code != http.StatusCreated || next.calls != 2 {
+		t.Errorf("retry = %d after %d calls, want 201 after 2", w.Code, next.calls)
+	}
+}
+
+func TestIdempotencyReleasesKeyOnPanic(t *testing.T) {
+	store := newMemoryStore()
+	h := Idempotency(store, slog.Default())(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	}))
+
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("recovered %v, want the handler's panic", p)
+			}
+		}()
+		serve(h, idempotentRequest("k1", "a"))
+	}()
+
+	if store.released != 1 || len(store.records) != 0 {
+		t.Errorf("released %d keys, %d left, want the key freed", store.released, len(store.records))
+	}
+}
+
+func TestIdempotencyWithoutKeyPassesThrough(t *testing.T) {
+	store := newMemoryStore()
+	next := &countingHandler{status: http.StatusCreated}
+	h := Idempotency(store, slog.Default())(next)
+
+	serve(h, idempotentRequest("", "a"))
+	serve(h, idempotentRequest("", "a"))
+	if next.calls != 2 || len(store.records) != 0 {
+		t.Errorf("handler ran %d times with %d keys stored, want 2 and none", next.calls, len(store.records))
+	}
+}
@@ -3,7 +3,12 @@ package api
 import (
 	"evently/internal/api/handler"
 	"evently/internal/api/middleware"
+	"evently/internal/authz"
+	"evently/internal/config"
 	"evently/internal/data"
+	"evently/internal/jwtkeys"
+	"evently/internal/notify"
+	"evently/internal/oidc"
 	"evently/internal/service"
 	"log/slog"
 	"net/http"
@@ -14,13 +19,14 @@ import (
 	"github.com/rs/cors"
 )
 
-func NewRouter(db *pgxpool.Pool, logger *slog.Logger, jwtSecret string) http.Handler {
+func NewRouter(db *pgxpool.Pool, logger *slog.Logger, cfg *config.Config, keys *jwtkeys.KeySet, notifier notify.Notifier, mail *service.MailQueue) http.Handler {
 	r := chi.NewRouter()
 
 	corsMiddleware := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "Idempotency-Key"},
+		ExposedHeaders:   []string{"Idempotent-Replayed", "Retry-After"},
 		AllowCredentials: true,
 	})
 	r.Use(corsMiddleware.Handler)
@@ -31,39 +37,231 @@ func NewRouter(db *pgxpool.Pool, logger *slog.Logger, jwtSecret string) http.Han
 	r.Use(chimiddleware.Recoverer)
 
 	userRepo := &data.UserRepository{DB: db}
+	tokenRepo := &data.TokenRepository{DB: db}
+	twoFactorRepo := &data.TwoFactorRepository{DB: db}
+	loginAttemptRepo := &data.LoginAttemptRepository{DB: db}
 	eventRepo := &data.EventRepository{DB: db}
+	eventMemberRepo := &data.EventMemberRepository{DB: db}
 	dataBookingRepo := &data.BookingRepository{DB: db}
+	holdRepo := &data.HoldRepository{DB: db}
+	tierRepo := &data.TierRepository{DB: db}
+	venueRepo := &data.VenueRepository{DB: db}
+	seatRepo := &data.SeatRepository{DB: db}
+	idempotencyRepo := &data.IdempotencyRepository{DB: db}
+	waitlistRepo := &data.WaitlistRepository{DB: db}
+	webhookRepo := &data.WebhookRepository{DB: db}
+	apiKeyRepo := &data.APIKeyRepository{DB: db}
 	bookingRepoWithTx := &service.BookingRepositoryWithTx{DB: db, BookingRepository: dataBookingRepo}
 
-	authService := service.NewAuthService(userRepo, jwtSecret)
-	bookingService := service.NewBookingService(bookingRepoWithTx, logger)
+	loginGuard := service.NewLoginGuard(loginAttemptRepo, service.LoginPolicy{
+		MaxAccountFailures: cfg.LoginMaxFailures,
+		MaxIPFailures:      cfg.LoginIPMaxFailures,
+		Window:             cfg.LoginFailureWindow,
+		Lockout:            cfg.LoginLockout,
+		FreeAttempts:       cfg.LoginFreeAttempts,
+		BaseDelay:          cfg.LoginBaseDelay,
+		MaxDelay:           cfg.LoginMaxDelay,
+		MaxResetRequests:   cfg.LoginMaxResets,
+	}, logger)
+	authService := service.NewAuthService(db, userRepo, tokenRepo, twoFactorRepo, keys, notifier, mail, loginGuard, service.AuthConfig{
+		Issuer:     cfg.JWTIssuer,
+		AccessTTL:  cfg.AccessTokenTTL,
+		RefreshTTL: cfg.RefreshTokenTTL,
+		AppBaseURL: cfg.AppBaseURL,
+	}, logger)
+	// A nil verifier lets unverified accounts book.
+	var verifier service.EmailVerifier
+	if cfg.RequireVerifiedEmail {
+		verifier = userRepo
+	}
+	bookingService := service.NewBookingService(bookingRepoWithTx, verifier, logger)
+	eventService := service.NewEventService(db, eventRepo, dataBookingRepo, venueRepo, logger)
+	ticketTierService := service.NewTicketTierService(tierRepo, logger)
+	venueService := service.NewVenueService(venueRepo, logger)
+	seriesService := service.NewSeriesService(db, &data.SeriesRepository{DB: db}, eventRepo, dataBookingRepo, venueRepo, cfg.SeriesHorizon, logger)
+	sessionService := service.NewSessionService(&data.SessionRepository{DB: db}, eventRepo, verifier, logger)
+	seatingService := service.NewSeatingService(seatRepo, eventRepo, venueRepo, tierRepo, logger)
+	holdService := service.NewHoldService(db, holdRepo, dataBookingRepo, verifier, cfg.HoldTTL, logger)
+	waitlistService := service.NewWaitlistService(db, waitlistRepo, dataBookingRepo, logger)
+	webhookService := service.NewWebhookService(webhookRepo)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, userRepo, logger)
+	profileService := service.NewProfileService(db, userRepo, tokenRepo, dataBookingRepo, holdRepo, waitlistRepo, authService, logger)
+	userAdminService := service.NewUserAdminService(db, userRepo, tokenRepo, dataBookingRepo, waitlistRepo, authService, logger)
 
 	authHandler := handler.NewAuthHandler(authService, logger)
-	eventHandler := handler.NewEventHandler(eventRepo)
+	lockoutHandler := handler.NewLockoutHandler(loginGuard, logger)
+	eventHandler := handler.NewEventHandler(eventRepo, eventService, logger)
 	bookingHandler := handler.NewBookingHandler(bookingService, logger) // Changed this line
+	holdHandler := handler.NewHoldHandler(holdService, logger)
+	ticketTierHandler := handler.NewTicketTierHandler(ticketTierService, logger)
+	venueHandler := handler.NewVenueHandler(venueService, logger)
+	seriesHandler := handler.NewSeriesHandler(seriesService, logger)
+	sessionHandler := handler.NewSessionHandler(sessionService, logger)
+	seatHandler := handler.NewSeatHandler(seatingService, logger)
+	waitlistHandler := handler.NewWaitlistHandler(waitlistService, logger)
+	webhookHandler := handler.NewWebhookHandler(webhookService, logger)
+
+	eventMemberHandler := handler.NewEventMemberHandler(eventMemberRepo, logger)
+	userAdminHandler := handler.NewUserAdminHandler(userAdminService, logger)
+	profileHandler := handler.NewProfileHandler(profileService, logger)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService, logger)
+
+	var oidcHandler *handler.OIDCHandler
+	if cfg.OIDCIssuer != "" {
+		provider := oidc.NewProvider(oidc.Config{
+			Issuer:       cfg.OIDCIssuer,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+			Scopes:       cfg.OIDCScopes,
+		})
+		oidcService := service.NewOIDCService(db, provider, &data.OIDCRepository{DB: db}, userRepo, tokenRepo, authService, cfg.AppBaseURL, logger)
+		oidcHandler = handler.NewOIDCHandler(oidcService, logger)
+	}
+
+	authorizer := authz.NewAuthorizer(eventMemberRepo)
+	jwtAuth := middleware.JWTAuth(keys, cfg.JWTIssuer, tokenRepo)
+	// Routes an integration may call accept API keys as well as sessions.
+	// Account and auth routes stay session-only.
+	keyOrJWT := middleware.Authenticate(jwtAuth, apiKeyService)
+	can := func(perm authz.Permission) func(http.Handler) http.Handler {
+		return middleware.RequirePermission(authorizer, perm)
+	}
+	canOnEvent := func(perm authz.Permission) func(http.Handler) http.Handler {
+		return middleware.RequireEventPermission(authorizer, perm, "id")
+	}
+	idempotent := middleware.Idempotency(idempotencyRepo, logger)
+
+	r.Get("/.well-known/jwks.json", handler.JWKS(keys))
 
 	r.Route("/auth", func(r chi.Router) {
 		r.Post("/register", authHandler.Register)
 		r.Post("/login", authHandler.Login)
+		r.Post("/refresh", authHandler.Refresh)
+		r.Post("/logout", authHandler.Logout)
+		r.With(jwtAuth).Post("/logout-all", authHandler.LogoutAll)
+		r.Post("/verify-email", authHandler.VerifyEmail)
+		r.With(jwtAuth).Post("/verify-email/resend", authHandler.ResendVerification)
+		r.Post("/password/forgot", authHandler.ForgotPassword)
+		r.Post("/password/reset", authHandler.ResetPassword)
+		r.Post("/2fa/verify", authHandler.VerifyTwoFactor)
+		r.With(jwtAuth).Post("/2fa/setup", authHandler.SetupTwoFactor)
+		r.With(jwtAuth).Post("/2fa/confirm", authHandler.ConfirmTwoFactor)
+		if oidcHandler != nil {
+			r.Get("/oidc/login", oidcHandler.Start)
+			r.Get("/oidc/callback", oidcHandler.Callback)
+			r.Post("/oidc/complete", oidcHandler.Complete)
+		}
+	})
+
+	r.Route("/me", func(r chi.Router) {
+		r.Use(jwtAuth)
+		r.Get("/", profileHandler.GetProfile)
+		r.Patch("/", profileHandler.UpdateProfile)
+		r.Delete("/", profileHandler.DeleteAccount)
+		r.Get("/export", profileHandler.Export)
+		r.Get("/api-keys", apiKeyHandler.ListKeys)
+		r.Post("/api-keys", apiKeyHandler.CreateKey)
+		r.Delete("/api-keys/{keyID}", apiKeyHandler.RevokeKey)
+		r.Get("/agenda", sessionHandler.GetMyAgenda)
 	})
 
 	r.Route("/events", func(r chi.Router) {
 		r.Get("/", eventHandler.ListEvents)
 		r.Get("/{id}", eventHandler.GetEvent)
-		r.With(middleware.JWTAuth(jwtSecret)).Post("/{id}/book", bookingHandler.CreateBooking)
+		r.Get("/{id}/seats", seatHandler.ListSeats)
+		r.Get("/{id}/agenda", sessionHandler.GetAgenda)
+		r.With(keyOrJWT, can(authz.TicketsBook), idempotent).Post("/{id}/book", bookingHandler.CreateBooking)
+		r.With(keyOrJWT, can(authz.TicketsBook), idempotent).Post("/{id}/holds", holdHandler.CreateHold)
+		r.With(keyOrJWT, can(authz.TicketsBook), idempotent).Post("/{id}/sessions/{sessionID}/register", sessionHandler.Register)
+		r.With(keyOrJWT, can(authz.TicketsBook)).Delete("/{id}/sessions/{sessionID}/register", sessionHandler.Unregister)
+	})
+
+	r.Get("/series/{id}", seriesHandler.GetSeries)
+
+	r.Route("/holds", func(r chi.Router) {
+		r.Use(keyOrJWT, can(authz.TicketsBook))
+		r.With(idempotent).Post("/{id}/confirm", holdHandler.ConfirmHold)
+		r.With(idempotent).Delete("/{id}", holdHandler.ReleaseHold)
 	})
 
-	// Inside the NewRouter function, change the /bookings route
 	r.Route("/bookings", func(r chi.Router) {
-		r.Use(middleware.JWTAuth(jwtSecret))
-		r.Get("/", bookingHandler.GetUserBookings)
-		r.Post("/{id}/cancel", bookingHandler.CancelBooking) // Change this line
+		r.Use(keyOrJWT)
+		r.With(can(authz.TicketsRead)).Get("/", bookingHandler.GetUserBookings)
+		r.With(can(authz.TicketsBook), idempotent).Post("/{id}/cancel", bookingHandler.CancelBooking)
+	})
+
+	r.Route("/waitlist", func(r chi.Router) {
+		r.Use(keyOrJWT)
+		r.With(can(authz.TicketsRead)).Get("/", waitlistHandler.GetEntries)
+		r.With(can(authz.TicketsBook)).Patch("/{id}", waitlistHandler.UpdateEntry)
+		r.With(can(authz.TicketsBook)).Delete("/{id}", waitlistHandler.LeaveWaitlist)
+		r.With(can(authz.TicketsRead)).Get("/offers", waitlistHandler.GetOffers)
+		r.With(can(authz.TicketsBook), idempotent).Post("/offers/{id}/accept", waitlistHandler.AcceptOffer)
+		r.With(can(authz.TicketsBook), idempotent).Post("/offers/{id}/decline", waitlistHandler.DeclineOffer)
 	})
 
+	// Routes under /admin are open to admins and, for the events they
+	// belong to, organizers and staff; each route names the permission it needs.
 	r.Route("/admin", func(r chi.Router) {
-		r.Use(middleware.JWTAuth(jwtSecret))
-		r.Use(middleware.AdminOnly)
-		r.Post("/events", eventHandler.CreateEvent)
+		r.Use(keyOrJWT)
+		if cfg.RequireAdmin2FA {
+			r.Use(middleware.RequireMFA(authz.RoleAdmin))
+		}
+		r.With(can(authz.EventsCreate)).Post("/events", eventHandler.CreateEvent)
+		r.With(canOnEvent(authz.EventsEdit)).Put("/events/{id}", eventHandler.UpdateEvent)
+		r.With(canOnEvent(authz.EventsEdit)).Patch("/events/{id}", eventHandler.UpdateEvent)
+		r.With(canOnEvent(authz.EventsDelete)).Delete("/events/{id}", eventHandler.DeleteEvent)
+		r.With(canOnEvent(authz.EventsCancel)).Post("/events/{id}/cancel", eventHandler.CancelEvent)
+		r.With(canOnEvent(authz.EventsEdit)).Get("/events/{id}/tiers", ticketTierHandler.ListTiers)
+		r.With(canOnEvent(authz.EventsEdit)).Post("/events/{id}/tiers", ticketTierHandler.CreateTier)
+		r.With(canOnEvent(authz.EventsEdit)).Patch("/events/{id}/tiers/{tierID}", ticketTierHandler.UpdateTier)
+		r.With(canOnEvent(authz.EventsEdit)).Delete("/events/{id}/tiers/{tierID}", ticketTierHandler.DeleteTier)
+		r.With(canOnEvent(authz.EventsEdit)).Put("/events/{id}/seat-map", seatHandler.AttachSeatMap)
+		r.With(canOnEvent(authz.EventsEdit)).Post("/events/{id}/sessions", sessionHandler.CreateSession)
+		r.With(canOnEvent(authz.EventsEdit)).Patch("/events/{id}/sessions/{sessionID}", sessionHandler.UpdateSession)
+		r.With(canOnEvent(authz.EventsEdit)).Delete("/events/{id}/sessions/{sessionID}", sessionHandler.DeleteSession)
+		r.With(can(authz.EventsCreate)).Post("/series", seriesHandler.CreateSeries)
+		r.With(canOnEvent(authz.EventsEdit)).Patch("/events/{id}/following", seriesHandler.UpdateFollowing)
+		r.With(can(authz.VenuesManage)).Post("/venues", venueHandler.CreateVenue)
+		r.With(can(authz.VenuesManage)).Get("/venues", venueHandler.ListVenues)
+		r.With(can(authz.VenuesManage)).Get("/venues/{id}", venueHandler.GetVenue)
+		r.With(can(authz.VenuesManage)).Patch("/venues/{id}", venueHandler.UpdateVenue)
+		r.With(can(authz.VenuesManage)).Delete("/venues/{id}", venueHandler.DeleteVenue)
+		r.With(canOnEvent(authz.EventsCancel)).Get("/events/{id}/cancellation", eventHandler.GetCancellation)
+		r.With(canOnEvent(authz.BookingsView)).Get("/events/{id}/bookings", bookingHandler.ListEventBookings)
+		r.With(canOnEvent(authz.EventsManageMembers)).Get("/events/{id}/members", eventMemberHandler.ListMembers)
+		r.With(canOnEvent(authz.EventsManageMembers)).Put("/events/{id}/members", eventMemberHandler.PutMember)
+		r.With(canOnEvent(authz.EventsManageMembers)).Delete("/events/{id}/members/{userID}", eventMemberHandler.RemoveMember)
+
+		r.Group(func(r chi.Router) {
+			r.Use(can(authz.UsersManage))
+			r.Get("/login-lockouts", lockoutHandler.ListLockouts)
+			r.Post("/login-lockouts/unlock", lockoutHandler.Unlock)
+
+			r.Get("/users", userAdminHandler.ListUsers)
+			r.Get("/users/{id}", userAdminHandler.GetUser)
+			r.Get("/users/{id}/bookings", userAdminHandler.GetUserBookings)
+			r.Get("/users/{id}/waitlist", userAdminHandler.GetUserWaitlist)
+			r.Put("/users/{id}/role", userAdminHandler.SetRole)
+			r.Post("/users/{id}/disable", userAdminHandler.DisableUser)
+			r.Post("/users/{id}/enable", userAdminHandler.EnableUser)
+			r.Post("/users/{id}/password-reset", userAdminHandler.ForcePasswordReset)
+			r.Get("/users/{id}/api-keys", apiKeyHandler.ListKeys)
+			r.Post("/users/{id}/api-keys", apiKeyHandler.CreateKey)
+			r.Delete("/users/{id}/api-keys/{keyID}", apiKeyHandler.RevokeKey)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(can(authz.WebhooksManage))
+			r.Post("/webhooks", webhookHandler.CreateWebhook)
+			r.Get("/webhooks", webhookHandler.ListWebhooks)
+			r.Get("/webhooks/{id}", webhookHandler.GetWebhook)
+			r.Patch("/webhooks/{id}", webhookHandler.UpdateWebhook)
+			r.Delete("/webhooks/{id}", webhookHandler.DeleteWebhook)
+			r.Get("/webhooks/{id}/deliveries", webhookHandler.ListDeliveries)
+			r.Post("/webhooks/{id}/deliveries/{deliveryID}/retry", webhookHandler.RetryDelivery)
+		})
 	})
 
 	return r
@@ -0,0 +1,123 @@
+// Package authz decides what a user may do. A permission is granted either by
+// the user's global role or, for permissions on a single event, by the user's
+// membership of that event.
+package authz
+
+import "context"
+
+type Permission string
+
+const (
+	// TicketsBook covers booking, holds and waitlists for oneself, and
+	// TicketsRead viewing them. Every role has both; they exist so API keys
+	// can be limited to them.
+	TicketsBook Permission = "tickets:book"
+	TicketsRead Permission = "tickets:read"
+
+	EventsCreate        Permission = "events:create"
+	EventsEdit          Permission = "events:edit"
+	EventsDelete        Permission = "events:delete"
+	EventsCancel        Permission = "events:cancel"
+	EventsManageMembers Permission = "events:members"
+	BookingsView        Permission = "bookings:view"
+	CheckinScan         Permission = "checkin:scan"
+	UsersManage         Permission = "users:manage"
+	WebhooksManage      Permission = "webhooks:manage"
+	// VenuesManage covers venues and their seat maps, which are shared
+	// between events.
+	VenuesManage Permission = "venues:manage"
+)
+
+// Global roles, matching the user_role enum.
+const (
+	RoleUser      = "user"
+	RoleOrganizer = "organizer"
+	RoleAdmin     = "admin"
+)
+
+// Event member roles, matching the event_member_role enum.
+const (
+	MemberOwner = "owner"
+	MemberStaff = "staff"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleAdmin: {
+		TicketsBook, TicketsRead,
+		EventsCreate, EventsEdit, EventsDelete, EventsCancel, EventsManageMembers,
+		BookingsView, CheckinScan, UsersManage, WebhooksManage, VenuesManage,
+	},
+	RoleOrganizer: {TicketsBook, TicketsRead, EventsCreate, VenuesManage},
+	RoleUser:      {TicketsBook, TicketsRead},
+}
+
+var memberPermissions = map[string][]Permission{
+	MemberOwner: {EventsEdit, EventsDelete, EventsCancel, EventsManageMembers, BookingsView, CheckinScan},
+	MemberStaff: {BookingsView, CheckinScan},
+}
+
+// ValidRole reports whether role is a global role.
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// ValidPermission reports whether perm names a known permission.
+func ValidPermission(perm string) bool {
+	return contains(rolePermissions[RoleAdmin], Permission(perm))
+}
+
+// ValidMemberRole reports whether role is an event member role.
+func ValidMemberRole(role string) bool {
+	_, ok := memberPermissions[role]
+	return ok
+}
+
+// RoleAllows reports whether the global role grants perm on every event.
+func RoleAllows(role string, perm Permission) bool {
+	return contains(rolePermissions[role], perm)
+}
+
+// MemberAllows reports whether an event member role grants perm on that event.
+func MemberAllows(memberRole string, perm Permission) bool {
+	return contains(memberPermissions[memberRole], perm)
+}
+
+// MembershipLookup returns the user's member role on an event, or "" when
+// they are not a member.
+type MembershipLookup interface {
+	EventMemberRole(ctx context.Context, eventID, userID string) (string, error)
+}
+
+type Authorizer struct {
+	members MembershipLookup
+}
+
+func NewAuthorizer(members MembershipLookup) *Authorizer {
+	return &Authorizer{members: members}
+}
+
+// Can reports whether the user may perform perm. eventID scopes the check to
+// one event; when it is empty only the global role is considered.
+func (a *Authorizer) Can(ctx context.Context, userID, role string, perm Permission, eventID string) (bool, error) {
+	if RoleAllows(role, perm) {
+		return true, nil
+	}
+	if eventID == "" {
+		return false, nil
+	}
+	memberRole, err := a.members.EventMemberRole(ctx, eventID, userID)
+	if err != nil {
+		return false, err
+	}
+	return MemberAllows(memberRole, perm), nil
+}
+
+func contains(perms []Permission, perm Permission) bool {
+	for _, p := range perms {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
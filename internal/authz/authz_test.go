@@ -0,0 +1,112 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRoleAllows(t *testing.T) {
+	tests := []struct {
+		role string
+		perm Permission
+		want bool
+	}{
+		{RoleAdmin, UsersManage, true},
+		{RoleAdmin, EventsEdit, true},
+		{RoleAdmin, WebhooksManage, true},
+		{RoleOrganizer, EventsCreate, true},
+		{RoleOrganizer, VenuesManage, true},
+		{RoleOrganizer, EventsEdit, false},
+		{RoleOrganizer, UsersManage, false},
+		{RoleUser, TicketsBook, true},
+		{RoleUser, TicketsRead, true},
+		{RoleUser, EventsCreate, false},
+		{"", TicketsBook, false},
+		{"superuser", TicketsBook, false},
+	}
+	for _, tt := range tests {
+		if got := RoleAllows(tt.role, tt.perm); got != tt.want {
+			t.Errorf("RoleAllows(%q, %s) = %v, want %v", tt.role, tt.perm, got, tt.want)
+		}
+	}
+}
+
+func TestMemberAllows(t *testing.T) {
+	tests := []struct {
+		role string
+		perm Permission
+		want bool
+	}{
+		{MemberOwner, EventsEdit, true},
+		{MemberOwner, EventsManageMembers, true},
+		{MemberOwner, UsersManage, false},
+		{MemberOwner, EventsCreate, false},
+		{MemberStaff, CheckinScan, true},
+		{MemberStaff, BookingsView, true},
+		{MemberStaff, EventsEdit, false},
+		{"", CheckinScan, false},
+	}
+	for _, tt := range tests {
+		if got := MemberAllows(tt.role, tt.perm); got != tt.want {
+			t.Errorf("MemberAllows(%q, %s) = %v, want %v", tt.role, tt.perm, got, tt.want)
+		}
+	}
+}
+
+func TestValid(t *testing.T) {
+	if !ValidPermission(string(VenuesManage)) || ValidPermission("events:everything") {
+		t.Error("ValidPermission does not match the admin's permissions")
+	}
+	if !ValidRole(RoleOrganizer) || ValidRole(MemberOwner) {
+		t.Error("ValidRole mixes up global and member roles")
+	}
+	if !ValidMemberRole(MemberStaff) || ValidMemberRole(RoleAdmin) {
+		t.Error("ValidMemberRole mixes up global and member roles")
+	}
+}
+
+// memberships maps "eventID/userID" to a member role.
+type memberships map[string]string
+
+func (m memberships) EventMemberRole(ctx context.Context, eventID, userID string) (string, error) {
+	if eventID == "broken" {
+		return "", errors.New("lookup failed")
+	}
+	return m[eventID+"/"+userID], nil
+}
+
+func TestAuthorizerCan(t *testing.T) {
+	a := NewAuthorizer(memberships{"e1/owner": MemberOwner, "e1/staff": MemberStaff})
+	tests := []struct {
+		name    string
+		userID  string
+		role    string
+		perm    Permission
+		eventID string
+		want    bool
+		wantErr bool
+	}{
+		{name: "role grants without membership", userID: "admin", role: RoleAdmin, perm: EventsEdit, eventID: "e1", want: true},
+		{name: "role grants without an event", userID: "admin", role: RoleAdmin, perm: EventsEdit, want: true},
+		{name: "owner edits own event", userID: "owner", role: RoleUser, perm: EventsEdit, eventID: "e1", want: true},
+		{name: "owner cannot edit another event", userID: "owner", role: RoleUser, perm: EventsEdit, eventID: "e2"},
+		{name: "membership ignored without an event", userID: "owner", role: RoleUser, perm: EventsEdit},
+		{name: "staff scans", userID: "staff", role: RoleUser, perm: CheckinScan, eventID: "e1", want: true},
+		{name: "staff cannot cancel", userID: "staff", role: RoleUser, perm: EventsCancel, eventID: "e1"},
+		{name: "stranger", userID: "someone", role: RoleUser, perm: BookingsView, eventID: "e1"},
+		{name: "role checked before lookup", userID: "admin", role: RoleAdmin, perm: BookingsView, eventID: "broken", want: true},
+		{name: "lookup error", userID: "owner", role: RoleUser, perm: EventsEdit, eventID: "broken", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := a.Can(context.Background(), tt.userID, tt.role, tt.perm, tt.eventID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Can() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Can() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
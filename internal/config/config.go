@@ -1,13 +1,78 @@
 package config
 
 import (
+	"errors"
+	"strings"
+	"time"
+
 	"github.com/caarlos0/env/v10"
 )
 
 type Config struct {
-	Port        int    `env:"PORT" envDefault:"8080"`
-	DatabaseURL string `env:"DATABASE_URL,required"`
-	JWTSecret   string `env:"JWT_SECRET,required"`
+	Port               int           `env:"PORT" envDefault:"8080"`
+	DatabaseURL        string        `env:"DATABASE_URL,required"`
+	JWTKeysDir         string        `env:"JWT_KEYS_DIR" envDefault:"keys"`
+	JWTSigningKeyID    string        `env:"JWT_SIGNING_KEY_ID"`
+	JWTIssuer          string        `env:"JWT_ISSUER" envDefault:"evently"`
+	AccessTokenTTL     time.Duration `env:"ACCESS_TOKEN_TTL" envDefault:"15m"`
+	RefreshTokenTTL    time.Duration `env:"REFRESH_TOKEN_TTL" envDefault:"720h"`
+	HoldTTL            time.Duration `env:"HOLD_TTL" envDefault:"10m"`
+	HoldSweepInterval  time.Duration `env:"HOLD_SWEEP_INTERVAL" envDefault:"30s"`
+	IdempotencyKeyTTL  time.Duration `env:"IDEMPOTENCY_KEY_TTL" envDefault:"24h"`
+	OfferSweepInterval time.Duration `env:"OFFER_SWEEP_INTERVAL" envDefault:"30s"`
+
+	// Occurrences of event series are generated SeriesHorizon ahead, topped
+	// up every SeriesInterval.
+	SeriesHorizon  time.Duration `env:"SERIES_HORIZON" envDefault:"2160h"`
+	SeriesInterval time.Duration `env:"SERIES_GENERATE_INTERVAL" envDefault:"1h"`
+
+	// Notifier selects how notifications are delivered: log, file or smtp.
+	Notifier       string        `env:"NOTIFIER" envDefault:"log"`
+	NotifierFile   string        `env:"NOTIFIER_FILE" envDefault:"notifications.log"`
+	NotifyInterval time.Duration `env:"NOTIFY_INTERVAL" envDefault:"10s"`
+	SMTPHost       string        `env:"SMTP_HOST"`
+	SMTPPort       int           `env:"SMTP_PORT" envDefault:"587"`
+	SMTPUsername   string        `env:"SMTP_USERNAME"`
+	SMTPPassword   string        `env:"SMTP_PASSWORD"`
+	SMTPFrom       string        `env:"SMTP_FROM" envDefault:"Evently <no-reply@evently.local>"`
+
+	WebhookInterval time.Duration `env:"WEBHOOK_INTERVAL" envDefault:"10s"`
+	WebhookTimeout  time.Duration `env:"WEBHOOK_TIMEOUT" envDefault:"10s"`
+
+	// AppBaseURL is where links in account emails point. RequireVerifiedEmail
+	// blocks bookings and holds until the user has confirmed their address.
+	AppBaseURL           string `env:"APP_BASE_URL" envDefault:"http://localhost:8080"`
+	RequireVerifiedEmail bool   `env:"REQUIRE_VERIFIED_EMAIL" envDefault:"false"`
+
+	// Login brute-force protection; see service.LoginPolicy.
+	LoginMaxFailures   int           `env:"LOGIN_MAX_FAILURES" envDefault:"5"`
+	LoginIPMaxFailures int           `env:"LOGIN_IP_MAX_FAILURES" envDefault:"50"`
+	LoginFailureWindow time.Duration `env:"LOGIN_FAILURE_WINDOW" envDefault:"15m"`
+	LoginLockout       time.Duration `env:"LOGIN_LOCKOUT" envDefault:"15m"`
+	LoginFreeAttempts  int           `env:"LOGIN_FREE_ATTEMPTS" envDefault:"3"`
+	LoginBaseDelay     time.Duration `env:"LOGIN_BASE_DELAY" envDefault:"1s"`
+	LoginMaxDelay      time.Duration `env:"LOGIN_MAX_DELAY" envDefault:"30s"`
+	LoginMaxResets     int           `env:"LOGIN_MAX_RESET_REQUESTS" envDefault:"5"`
+
+	// Account emails sent in the background, such as password reset links,
+	// go through a queue of MailQueueSize on MailWorkers workers.
+	MailQueueSize int `env:"MAIL_QUEUE_SIZE" envDefault:"100"`
+	MailWorkers   int `env:"MAIL_WORKERS" envDefault:"2"`
+
+	// RequireAdmin2FA only lets admins use /admin from sessions that were
+	// started with a second factor. Admins' API keys carry no second factor,
+	// so they cannot reach /admin while this is on.
+	RequireAdmin2FA bool `env:"REQUIRE_ADMIN_2FA" envDefault:"false"`
+
+	// Single sign-on through an OpenID Connect provider is enabled when
+	// OIDCIssuer is set. OIDCRedirectURL defaults to the API's
+	// /auth/oidc/callback under AppBaseURL and must be registered with the
+	// provider. OIDCClientSecret may be empty for public clients.
+	OIDCIssuer       string   `env:"OIDC_ISSUER"`
+	OIDCClientID     string   `env:"OIDC_CLIENT_ID"`
+	OIDCClientSecret string   `env:"OIDC_CLIENT_SECRET"`
+	OIDCRedirectURL  string   `env:"OIDC_REDIRECT_URL"`
+	OIDCScopes       []string `env:"OIDC_SCOPES" envSeparator:" " envDefault:"openid email profile"`
 }
 
 func Load() (*Config, error) {
@@ -15,5 +80,11 @@ func Load() (*Config, error) {
 	if err := env.Parse(cfg); err != nil {
 		return nil, err
 	}
+	if cfg.OIDCIssuer != "" && cfg.OIDCClientID == "" {
+		return nil, errors.New("OIDC_CLIENT_ID is required when OIDC_ISSUER is set")
+	}
+	if cfg.OIDCRedirectURL == "" {
+		cfg.OIDCRedirectURL = strings.TrimRight(cfg.AppBaseURL, "/") + "/auth/oidc/callback"
+	}
 	return cfg, nil
 }
@@ -0,0 +1,112 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type APIKeyRepository struct {
+	DB *pgxpool.Pool
+}
+
+// APIKeyIdentity is who a presented API key acts as.
+type APIKeyIdentity struct {
+	KeyID      string
+	UserID     string
+	Role       string
+	Scopes     []string
+	SecretHash string
+	LastUsedAt *time.Time
+}
+
+const apiKeyColumns = `id, user_id, name, prefix, scopes, created_by, expires_at, last_used_at, revoked_at, created_at`
+
+func scanAPIKey(row pgx.Row, key *APIKey) error {
+	return row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.Scopes, &key.CreatedBy,
+		&key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt, &key.CreatedAt)
+}
+
+func (r *APIKeyRepository) Create(ctx context.Context, key *APIKey, secretHash string) error {
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, secret_hash, scopes, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+	err := r.DB.QueryRow(ctx, query, key.UserID, key.Name, key.Prefix, secretHash, key.Scopes, key.CreatedBy, key.ExpiresAt).
+		Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Code {
+			case "23503":
+				return ErrNotFound
+			case "23505":
+				return ErrDuplicate
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// ListByUser returns the user's keys, including revoked and expired ones,
+// newest first.
+func (r *APIKeyRepository) ListByUser(ctx context.Context, userID string) ([]APIKey, error) {
+	rows, err := r.DB.Query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		var key APIKey
+		if err := scanAPIKey(rows, &key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (r *APIKeyRepository) Revoke(ctx context.Context, userID, id string) error {
+	query := `UPDATE api_keys SET revoked_at = COALESCE(revoked_at, NOW()) WHERE id = $1 AND user_id = $2`
+	tag, err := r.DB.Exec(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetIdentity returns the usable key with the given prefix. Revoked and
+// expired keys, and keys of disabled users, return ErrNotFound.
+func (r *APIKeyRepository) GetIdentity(ctx context.Context, prefix string) (*APIKeyIdentity, error) {
+	query := `
+		SELECT k.id, k.user_id, u.role, k.scopes, k.secret_hash, k.last_used_at
+		FROM api_keys k JOIN users u ON u.id = k.user_id
+		WHERE k.prefix = $1 AND k.revoked_at IS NULL AND (k.expires_at IS NULL OR k.expires_at > NOW())
+		  AND u.disabled_at IS NULL
+	`
+	var id APIKeyIdentity
+	err := r.DB.QueryRow(ctx, query, prefix).Scan(&id.KeyID, &id.UserID, &id.Role, &id.Scopes, &id.SecretHash, &id.LastUsedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &id, nil
+}
+
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, id string) error {
+	_, err := r.DB.Exec(ctx, `UPDATE api_keys SET last_used_at = NOW() WHERE id = $1`, id)
+	return err
+}
@@ -17,20 +17,30 @@ type UserBooking struct {
 	BookingID   string    `json:"booking_id"`
 	EventID     string    `json:"event_id"`
 	EventName   string    `json:"event_name"`
+	TierID      *string   `json:"tier_id"`
+	TierName    *string   `json:"tier_name"`
+	Seats       []string  `json:"seats,omitempty"`
 	Quantity    int       `json:"quantity"`
+	Status      string    `json:"status"`
 	BookingTime time.Time `json:"booking_time"`
 }
 
 type WaitlistUser struct {
 	UserID   string
 	Email    string
+	TierID   *string
 	Quantity int
+	// BookingID is set when the user was booked directly; OfferID is set
+	// when the tickets were offered instead.
+	BookingID      string
+	OfferID        string
+	OfferExpiresAt time.Time
 }
 
 func (r *BookingRepository) GetEventForUpdate(ctx context.Context, eventID string) (*EventForUpdate, error) {
 	var e EventForUpdate
-	query := `SELECT id, capacity, booked_tickets, version FROM events WHERE id = $1`
-	err := r.DB.QueryRow(ctx, query, eventID).Scan(&e.ID, &e.Capacity, &e.BookedTickets, &e.Version)
+	query := `SELECT id, capacity, booked_tickets, held_tickets, status, version FROM events WHERE id = $1`
+	err := r.DB.QueryRow(ctx, query, eventID).Scan(&e.ID, &e.Capacity, &e.BookedTickets, &e.HeldTickets, &e.Status, &e.Version)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -40,10 +50,24 @@ func (r *BookingRepository) GetEventForUpdate(ctx context.Context, eventID strin
 	return &e, nil
 }
 
+// HasTiers reports whether the event sells its tickets by tier.
+func (r *BookingRepository) HasTiers(ctx context.Context, eventID string) (bool, error) {
+	var exists bool
+	err := r.DB.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM ticket_tiers WHERE event_id = $1)`, eventID).Scan(&exists)
+	return exists, err
+}
+
+// GetTierForUpdate reads a tier of the event, including the version
+// CreateBooking checks.
+func (r *BookingRepository) GetTierForUpdate(ctx context.Context, eventID, tierID string) (*TicketTier, error) {
+	return getTier(ctx, r.DB, eventID, tierID)
+}
+
 func (r *BookingRepository) GetByUserID(ctx context.Context, userID string) ([]UserBooking, error) {
 	query := `
-		SELECT b.id, e.id, e.name, b.quantity, b.created_at
+		SELECT b.id, e.id, e.name, b.tier_id, t.name, ` + bookingSeatsColumn + `, b.quantity, b.status, b.created_at
 		FROM bookings b JOIN events e ON b.event_id = e.id
+		LEFT JOIN ticket_tiers t ON t.id = b.tier_id
 		WHERE b.user_id = $1 ORDER BY b.created_at DESC
 	`
 	rows, err := r.DB.Query(ctx, query, userID)
@@ -55,7 +79,7 @@ func (r *BookingRepository) GetByUserID(ctx context.Context, userID string) ([]U
 	var bookings []UserBooking
 	for rows.Next() {
 		var booking UserBooking
-		if err := rows.Scan(&booking.BookingID, &booking.EventID, &booking.EventName, &booking.Quantity, &booking.BookingTime); err != nil {
+		if err := rows.Scan(&booking.BookingID, &booking.EventID, &booking.EventName, &booking.TierID, &booking.TierName, &booking.Seats, &booking.Quantity, &booking.Status, &booking.BookingTime); err != nil {
 			return nil, err
 		}
 		bookings = append(bookings, booking)
@@ -63,14 +87,19 @@ func (r *BookingRepository) GetByUserID(ctx context.Context, userID string) ([]U
 	return bookings, nil
 }
 
-func (r *BookingRepository) HasWaitlist(ctx context.Context, eventID string) (bool, error) {
+// HasWaitlist reports whether anyone is waiting for the tier, or for the
+// event when tierID is nil.
+func (r *BookingRepository) HasWaitlist(ctx context.Context, eventID string, tierID *string) (bool, error) {
 	var exists bool
-	query := `SELECT EXISTS(SELECT 1 FROM waitlist_entries WHERE event_id = $1)`
-	err := r.DB.QueryRow(ctx, query, eventID).Scan(&exists)
+	query := `SELECT EXISTS(SELECT 1 FROM waitlist_entries WHERE event_id = $1 AND tier_id IS NOT DISTINCT FROM $2)`
+	err := r.DB.QueryRow(ctx, query, eventID, tierID).Scan(&exists)
 	return exists, err
 }
 
-func (r *BookingRepository) CreateBooking(ctx context.Context, event *EventForUpdate, userID string, quantity int) error {
+// CreateBooking books tickets if neither the event nor, for a tiered
+// booking, the tier has changed since they were read, returning ErrConflict
+// otherwise. tier is nil for events without tiers.
+func (r *BookingRepository) CreateBooking(ctx context.Context, event *EventForUpdate, tier *TicketTier, userID string, quantity int) error {
 	tx, err := r.DB.Begin(ctx)
 	if err != nil {
 		return err
@@ -89,60 +118,218 @@ func (r *BookingRepository) CreateBooking(ctx context.Context, event *EventForUp
 		return ErrConflict
 	}
 
-	insertBookingQuery := `INSERT INTO bookings (user_id, event_id, quantity) VALUES ($1, $2, $3)`
-	_, err = tx.Exec(ctx, insertBookingQuery, userID, event.ID, quantity)
+	var tierID *string
+	if tier != nil {
+		updateTierQuery := `
+			UPDATE ticket_tiers SET sold = sold + $3, version = version + 1
+			WHERE id = $1 AND version = $2
+		`
+		tag, err := tx.Exec(ctx, updateTierQuery, tier.ID, tier.Version, quantity)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrConflict
+		}
+		tierID = &tier.ID
+	}
+
+	var bookingID string
+	insertBookingQuery := `INSERT INTO bookings (user_id, event_id, tier_id, quantity) VALUES ($1, $2, $3, $4) RETURNING id`
+	err = tx.QueryRow(ctx, insertBookingQuery, userID, event.ID, tierID, quantity).Scan(&bookingID)
 	if err != nil {
 		return err
 	}
 
+	fields := map[string]any{"booking_id": bookingID, "quantity": quantity}
+	if err := EnqueueNotification(ctx, tx, NotificationBookingCreated, userID, event.ID, fields); err != nil {
+		return err
+	}
+	if err := EmitWebhook(ctx, tx, WebhookBookingCreated, bookingPayload(bookingID, event.ID, tierID, userID, quantity)); err != nil {
+		return err
+	}
+
 	return tx.Commit(ctx)
 }
 
-func (r *BookingRepository) AddToWaitlist(ctx context.Context, eventID, userID string, quantity int) error {
-	// This is the corrected query
+// HasSeatMap reports whether the event has reserved seating.
+func (r *BookingRepository) HasSeatMap(ctx context.Context, eventID string) (bool, error) {
+	var exists bool
+	err := r.DB.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM event_seats WHERE event_id = $1)`, eventID).Scan(&exists)
+	return exists, err
+}
+
+// ListSeats returns the event's seats, see SeatRepository.ListEventSeats.
+func (r *BookingRepository) ListSeats(ctx context.Context, eventID string) ([]EventSeat, error) {
+	return listEventSeats(ctx, r.DB, eventID)
+}
+
+// ListTiers returns the event's ticket tiers.
+func (r *BookingRepository) ListTiers(ctx context.Context, eventID string) ([]TicketTier, error) {
+	return listTiers(ctx, r.DB, eventID)
+}
+
+// BookSeats books specific seats of an event with reserved seating. The
+// seats are locked in a fixed order, so concurrent attempts on the same
+// seat wait for each other instead of deadlocking, and the booking is made
+// only if every seat is still free; otherwise it returns ErrConflict. It
+// returns ErrNotFound if a seat is not part of the event, or of the tier
+// for tiered events.
+func (r *BookingRepository) BookSeats(ctx context.Context, eventID string, tierID *string, userID string, seatIDs []string) (*SeatBooking, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	lockQuery := `SELECT ` + eventSeatColumns + eventSeatJoins + `
+		WHERE es.event_id = $1 AND es.seat_id = ANY($2::uuid[]) AND es.tier_id IS NOT DISTINCT FROM $3
+		ORDER BY es.seat_id
+		FOR UPDATE OF es
+	`
+	seats, err := queryEventSeats(ctx, tx, lockQuery, eventID, seatIDs, tierID)
+	if err != nil {
+		return nil, err
+	}
+	if len(seats) != len(seatIDs) {
+		return nil, ErrNotFound
+	}
+	for _, seat := range seats {
+		if seat.Status != SeatAvailable {
+			return nil, ErrConflict
+		}
+	}
+	quantity := len(seats)
+
+	updateEventQuery := `
+		UPDATE events SET booked_tickets = booked_tickets + $2, version = version + 1
+		WHERE id = $1 AND status = 'scheduled' AND booked_tickets + held_tickets + $2 <= capacity
+	`
+	tag, err := tx.Exec(ctx, updateEventQuery, eventID, quantity)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrConflict
+	}
+	if err := adjustTier(ctx, tx, tierID, quantity, 0); err != nil {
+		return nil, tierWriteError(err)
+	}
+
+	booking := SeatBooking{Seats: seats}
+	insertBookingQuery := `INSERT INTO bookings (user_id, event_id, tier_id, quantity) VALUES ($1, $2, $3, $4) RETURNING id`
+	if err := tx.QueryRow(ctx, insertBookingQuery, userID, eventID, tierID, quantity).Scan(&booking.BookingID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE event_seats SET booking_id = $3 WHERE event_id = $1 AND seat_id = ANY($2::uuid[])`, eventID, seatIDs, booking.BookingID); err != nil {
+		return nil, err
+	}
+	for i := range booking.Seats {
+		booking.Seats[i].Status = SeatBooked
+	}
+
+	fields := map[string]any{"booking_id": booking.BookingID, "quantity": quantity}
+	if err := EnqueueNotification(ctx, tx, NotificationBookingCreated, userID, eventID, fields); err != nil {
+		return nil, err
+	}
+	if err := EmitWebhook(ctx, tx, WebhookBookingCreated, bookingPayload(booking.BookingID, eventID, tierID, userID, quantity)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
+
+// AddToWaitlist queues the user for the event, or for one of its tiers.
+// Asking again updates the existing entry with the same rule as
+// WaitlistRepository.UpdateEntryQuantity; switching tier also counts as
+// joining again. Only a new entry is announced as waitlist.joined.
+func (r *BookingRepository) AddToWaitlist(ctx context.Context, eventID string, tierID *string, userID string, quantity int) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var entryID string
+	var inserted bool
 	query := `
-        INSERT INTO waitlist_entries (event_id, user_id, quantity) VALUES ($1, $2, $3)
-        ON CONFLICT (user_id, event_id) DO NOTHING
+        INSERT INTO waitlist_entries (event_id, tier_id, user_id, quantity) VALUES ($1, $2, $3, $4)
+        ON CONFLICT (user_id, event_id) DO UPDATE SET
+            quantity = EXCLUDED.quantity,
+            tier_id = EXCLUDED.tier_id,
+            created_at = CASE
+                WHEN EXCLUDED.quantity > waitlist_entries.quantity OR EXCLUDED.tier_id IS DISTINCT FROM waitlist_entries.tier_id THEN NOW()
+                ELSE waitlist_entries.created_at
+            END
+        RETURNING id, xmax = 0
     `
-	_, err := r.DB.Exec(ctx, query, eventID, userID, quantity)
-	return err
+	if err := tx.QueryRow(ctx, query, eventID, tierID, userID, quantity).Scan(&entryID, &inserted); err != nil {
+		return err
+	}
+	// Updating an existing entry is not joining; xmax is zero only for a
+	// freshly inserted row.
+	if !inserted {
+		return tx.Commit(ctx)
+	}
+
+	payload := map[string]any{"entry_id": entryID, "event_id": eventID, "tier_id": tierID, "user_id": userID, "quantity": quantity}
+	if err := EmitWebhook(ctx, tx, WebhookWaitlistJoined, payload); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
 }
 
-func (r *BookingRepository) UpdateBookingForCancellation(ctx context.Context, tx pgx.Tx, bookingID, userID string, quantityToCancel int) (string, int, error) {
+func (r *BookingRepository) UpdateBookingForCancellation(ctx context.Context, tx pgx.Tx, bookingID, userID string, quantityToCancel int) (string, *string, int, error) {
 	var eventID string
+	var tierID *string
 	var finalQuantity int
 	query := `
         UPDATE bookings SET quantity = quantity - $3
-        WHERE id = $1 AND user_id = $2 AND quantity >= $3
-        RETURNING event_id, quantity
+        WHERE id = $1 AND user_id = $2 AND quantity >= $3 AND status = 'confirmed'
+        RETURNING event_id, tier_id, quantity
     `
-	err := tx.QueryRow(ctx, query, bookingID, userID, quantityToCancel).Scan(&eventID, &finalQuantity)
+	err := tx.QueryRow(ctx, query, bookingID, userID, quantityToCancel).Scan(&eventID, &tierID, &finalQuantity)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return "", 0, errors.New("booking not found, or you are trying to cancel too many tickets")
+			return "", nil, 0, errors.New("booking not found, or you are trying to cancel too many tickets")
 		}
-		return "", 0, err
+		return "", nil, 0, err
+	}
+	if err := releaseSeats(ctx, tx, bookingID, quantityToCancel); err != nil {
+		return "", nil, 0, err
 	}
 	if finalQuantity == 0 {
+		if err := releaseRegistrations(ctx, tx, bookingID); err != nil {
+			return "", nil, 0, err
+		}
 		if _, err := tx.Exec(ctx, "DELETE FROM bookings WHERE id = $1", bookingID); err != nil {
-			return "", 0, err
+			return "", nil, 0, err
 		}
 	}
-	return eventID, quantityToCancel, nil
+	return eventID, tierID, quantityToCancel, nil
 }
 
+// FindAndRemoveMatchingWaitlistEntry takes the oldest entry that fits in
+// availableTickets and, for an entry waiting on a tier, in what is left of
+// that tier.
 func (r *BookingRepository) FindAndRemoveMatchingWaitlistEntry(ctx context.Context, tx pgx.Tx, eventID string, availableTickets int) (*WaitlistUser, error) {
 	var user WaitlistUser
 	query := `
         WITH next_in_line AS (
-            SELECT id, user_id, quantity FROM waitlist_entries
-            WHERE event_id = $1 AND quantity <= $2
-            ORDER BY created_at ASC LIMIT 1 FOR UPDATE SKIP LOCKED
+            SELECT w.id FROM waitlist_entries w
+            LEFT JOIN ticket_tiers t ON t.id = w.tier_id
+            WHERE w.event_id = $1 AND w.quantity <= $2
+              AND (w.tier_id IS NULL OR w.quantity <= t.quantity - t.sold - t.held)
+            ORDER BY w.created_at ASC LIMIT 1 FOR UPDATE OF w SKIP LOCKED
         )
         DELETE FROM waitlist_entries WHERE id = (SELECT id FROM next_in_line)
-        RETURNING user_id, quantity
+        RETURNING user_id, tier_id, quantity
     `
-	err := tx.QueryRow(ctx, query, eventID, availableTickets).Scan(&user.UserID, &user.Quantity)
+	err := tx.QueryRow(ctx, query, eventID, availableTickets).Scan(&user.UserID, &user.TierID, &user.Quantity)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
@@ -156,7 +343,152 @@ func (r *BookingRepository) FindAndRemoveMatchingWaitlistEntry(ctx context.Conte
 	return &user, nil
 }
 
-func (r *BookingRepository) DecrementEventTickets(ctx context.Context, tx pgx.Tx, eventID string, quantity int) error {
+func (r *BookingRepository) DecrementEventTickets(ctx context.Context, tx pgx.Tx, eventID string, tierID *string, quantity int) error {
 	_, err := tx.Exec(ctx, "UPDATE events SET booked_tickets = booked_tickets - $2, version = version + 1 WHERE id = $1", eventID, quantity)
-	return err
+	if err != nil {
+		return err
+	}
+	return adjustTier(ctx, tx, tierID, -quantity, 0)
+}
+
+// PromoteFromWaitlist hands any free capacity on the event to waitlisted users
+// in queue order. Depending on the event's waitlist policy they are either
+// booked directly or sent a time-limited offer that holds the tickets. It must
+// run inside the caller's transaction after the event's counters reflect the
+// freed tickets. Nobody is promoted unless the event is scheduled, since it
+// would not take the booking either.
+func (r *BookingRepository) PromoteFromWaitlist(ctx context.Context, tx pgx.Tx, eventID string) ([]WaitlistUser, error) {
+	var available, offerMinutes int
+	var policy, status string
+	query := `
+		SELECT capacity - booked_tickets - held_tickets, waitlist_policy, waitlist_offer_minutes, status
+		FROM events WHERE id = $1 FOR UPDATE
+	`
+	if err := tx.QueryRow(ctx, query, eventID).Scan(&available, &policy, &offerMinutes, &status); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if status != EventStatusScheduled {
+		return nil, nil
+	}
+
+	var promoted []WaitlistUser
+	booked, held := 0, 0
+	for available > 0 {
+		waitlister, err := r.FindAndRemoveMatchingWaitlistEntry(ctx, tx, eventID, available)
+		if err != nil {
+			return nil, err
+		}
+		if waitlister == nil {
+			break
+		}
+
+		if policy == WaitlistPolicyOffer {
+			insertOfferQuery := `
+				INSERT INTO waitlist_offers (event_id, tier_id, user_id, quantity, expires_at)
+				VALUES ($1, $2, $3, $4, NOW() + make_interval(mins => $5))
+				RETURNING id, expires_at
+			`
+			err := tx.QueryRow(ctx, insertOfferQuery, eventID, waitlister.TierID, waitlister.UserID, waitlister.Quantity, offerMinutes).
+				Scan(&waitlister.OfferID, &waitlister.OfferExpiresAt)
+			if err != nil {
+				return nil, err
+			}
+			if err := adjustTier(ctx, tx, waitlister.TierID, 0, waitlister.Quantity); err != nil {
+				return nil, err
+			}
+			fields := map[string]any{"offer_id": waitlister.OfferID, "quantity": waitlister.Quantity, "expires_at": waitlister.OfferExpiresAt}
+			if err := EnqueueNotification(ctx, tx, NotificationWaitlistOffered, waitlister.UserID, eventID, fields); err != nil {
+				return nil, err
+			}
+			payload := map[string]any{
+				"event_id": eventID, "tier_id": waitlister.TierID, "user_id": waitlister.UserID, "quantity": waitlister.Quantity,
+				"policy": policy, "offer_id": waitlister.OfferID, "offer_expires_at": waitlister.OfferExpiresAt,
+			}
+			if err := EmitWebhook(ctx, tx, WebhookWaitlistPromoted, payload); err != nil {
+				return nil, err
+			}
+			held += waitlister.Quantity
+		} else {
+			insertQuery := `INSERT INTO bookings (user_id, event_id, tier_id, quantity) VALUES ($1, $2, $3, $4) RETURNING id`
+			if err := tx.QueryRow(ctx, insertQuery, waitlister.UserID, eventID, waitlister.TierID, waitlister.Quantity).Scan(&waitlister.BookingID); err != nil {
+				return nil, err
+			}
+			if err := adjustTier(ctx, tx, waitlister.TierID, waitlister.Quantity, 0); err != nil {
+				return nil, err
+			}
+			fields := map[string]any{"booking_id": waitlister.BookingID, "quantity": waitlister.Quantity}
+			if err := EnqueueNotification(ctx, tx, NotificationWaitlistPromoted, waitlister.UserID, eventID, fields); err != nil {
+				return nil, err
+			}
+			payload := map[string]any{
+				"event_id": eventID, "tier_id": waitlister.TierID, "user_id": waitlister.UserID, "quantity": waitlister.Quantity,
+				"policy": policy, "booking_id": waitlister.BookingID,
+			}
+			if err := EmitWebhook(ctx, tx, WebhookWaitlistPromoted, payload); err != nil {
+				return nil, err
+			}
+			booked += waitlister.Quantity
+		}
+		available -= waitlister.Quantity
+		promoted = append(promoted, *waitlister)
+	}
+
+	if booked > 0 || held > 0 {
+		updateQuery := `
+			UPDATE events SET booked_tickets = booked_tickets + $2, held_tickets = held_tickets + $3, version = version + 1
+			WHERE id = $1
+		`
+		if _, err := tx.Exec(ctx, updateQuery, eventID, booked, held); err != nil {
+			return nil, err
+		}
+	}
+	return promoted, nil
+}
+
+// EventBookingCursor is the keyset position of the last booking on a page.
+type EventBookingCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"i"`
+}
+
+// ListEventBookings returns the event's bookings with the attendee's contact
+// details, newest first.
+func (r *BookingRepository) ListEventBookings(ctx context.Context, eventID string, after *EventBookingCursor, limit int) ([]EventBooking, error) {
+	query := `
+		SELECT b.id, b.user_id, u.name, u.email, b.tier_id, t.name, ` + bookingSeatsColumn + `, b.quantity, b.status, b.created_at
+		FROM bookings b JOIN users u ON u.id = b.user_id
+		LEFT JOIN ticket_tiers t ON t.id = b.tier_id
+		WHERE b.event_id = $1
+		  AND ($2::timestamptz IS NULL OR (b.created_at, b.id) < ($2, $3::uuid))
+		ORDER BY b.created_at DESC, b.id DESC
+		LIMIT $4
+	`
+	var afterTime *time.Time
+	var afterID *string
+	if after != nil {
+		afterTime, afterID = &after.CreatedAt, &after.ID
+	}
+	rows, err := r.DB.Query(ctx, query, eventID, afterTime, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bookings := []EventBooking{}
+	for rows.Next() {
+		var b EventBooking
+		if err := rows.Scan(&b.ID, &b.UserID, &b.Name, &b.Email, &b.TierID, &b.TierName, &b.Seats, &b.Quantity, &b.Status, &b.CreatedAt); err != nil {
+			return nil, err
+		}
+		bookings = append(bookings, b)
+	}
+	return bookings, rows.Err()
+}
+
+// bookingPayload is the webhook body for booking.created and booking.cancelled.
+func bookingPayload(bookingID, eventID string, tierID *string, userID string, quantity int) map[string]any {
+	return map[string]any{"booking_id": bookingID, "event_id": eventID, "tier_id": tierID, "user_id": userID, "quantity": quantity}
 }
@@ -0,0 +1,86 @@
+package data
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type EventMemberRepository struct {
+	DB *pgxpool.Pool
+}
+
+// EventMemberRole returns the user's role on the event, or "" when they are
+// not a member.
+func (r *EventMemberRepository) EventMemberRole(ctx context.Context, eventID, userID string) (string, error) {
+	var role string
+	query := `SELECT role FROM event_members WHERE event_id = $1 AND user_id = $2`
+	err := r.DB.QueryRow(ctx, query, eventID, userID).Scan(&role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+	return role, nil
+}
+
+func (r *EventMemberRepository) List(ctx context.Context, eventID string) ([]EventMember, error) {
+	query := `
+		SELECT m.event_id, m.user_id, u.name, u.email, m.role, m.added_by, m.created_at
+		FROM event_members m JOIN users u ON u.id = m.user_id
+		WHERE m.event_id = $1
+		ORDER BY m.created_at
+	`
+	rows, err := r.DB.Query(ctx, query, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []EventMember{}
+	for rows.Next() {
+		var m EventMember
+		if err := rows.Scan(&m.EventID, &m.UserID, &m.Name, &m.Email, &m.Role, &m.AddedBy, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// Put adds the user with the given email to the event, or changes their role
+// if they are already a member. It returns ErrNotFound if no such user or
+// event exists.
+func (r *EventMemberRepository) Put(ctx context.Context, eventID, email, role, addedBy string) (*EventMember, error) {
+	query := `
+		INSERT INTO event_members (event_id, user_id, role, added_by)
+		SELECT e.id, u.id, $3, $4 FROM events e, users u
+		WHERE e.id = $1 AND lower(u.email) = lower($2)
+		ON CONFLICT (event_id, user_id) DO UPDATE SET role = EXCLUDED.role
+		RETURNING event_id, user_id, role, added_by, created_at
+	`
+	var m EventMember
+	err := r.DB.QueryRow(ctx, query, eventID, email, role, addedBy).Scan(&m.EventID, &m.UserID, &m.Role, &m.AddedBy, &m.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	err = r.DB.QueryRow(ctx, `SELECT name, email FROM users WHERE id = $1`, m.UserID).Scan(&m.Name, &m.Email)
+	return &m, err
+}
+
+func (r *EventMemberRepository) Remove(ctx context.Context, eventID, userID string) error {
+	tag, err := r.DB.Exec(ctx, `DELETE FROM event_members WHERE event_id = $1 AND user_id = $2`, eventID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
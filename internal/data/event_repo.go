@@ -66,6 +66,9 @@ package data
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -75,16 +78,104 @@ type EventRepository struct {
 	DB *pgxpool.Pool
 }
 
-func (r *EventRepository) GetAll(ctx context.Context) ([]Event, error) {
-	query := `SELECT id, name, venue, start_time, capacity, booked_tickets FROM events ORDER BY start_time ASC`
+const (
+	EventSortStartAsc  = "start_time"
+	EventSortStartDesc = "-start_time"
+	EventSortNameAsc   = "name"
+	EventSortNameDesc  = "-name"
+)
+
+// EventFilter narrows and orders an event listing. After, when set, must have
+// been produced by a listing with the same Sort.
+type EventFilter struct {
+	Search        string
+	Venue         string
+	VenueID       string
+	SeriesID      string
+	From          *time.Time
+	To            *time.Time
+	OnlyAvailable bool
+	Sort          string
+	Limit         int
+	After         *EventCursor
+}
+
+// EventCursor is the keyset position of the last event on a page.
+type EventCursor struct {
+	Sort      string    `json:"s"`
+	StartTime time.Time `json:"t,omitempty"`
+	Name      string    `json:"n,omitempty"`
+	ID        string    `json:"i"`
+}
+
+func (r *EventRepository) List(ctx context.Context, filter EventFilter) ([]Event, error) {
+	var conditions []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Search != "" {
+		pattern := arg("%" + escapeLike(filter.Search) + "%")
+		conditions = append(conditions, fmt.Sprintf("(name ILIKE %s OR venue ILIKE %s)", pattern, pattern))
+	}
+	if filter.Venue != "" {
+		conditions = append(conditions, fmt.Sprintf("LOWER(venue) = LOWER(%s)", arg(filter.Venue)))
+	}
+	if filter.VenueID != "" {
+		conditions = append(conditions, fmt.Sprintf("venue_id = %s", arg(filter.VenueID)))
+	}
+	if filter.SeriesID != "" {
+		conditions = append(conditions, fmt.Sprintf("series_id = %s", arg(filter.SeriesID)))
+	}
+	if filter.From != nil {
+		conditions = append(conditions, fmt.Sprintf("start_time >= %s", arg(*filter.From)))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, fmt.Sprintf("start_time < %s", arg(*filter.To)))
+	}
+	if filter.OnlyAvailable {
+		conditions = append(conditions, "booked_tickets + held_tickets < capacity AND status = 'scheduled'")
+	}
+
+	var orderBy string
+	switch filter.Sort {
+	case EventSortStartDesc:
+		orderBy = "start_time DESC, id DESC"
+		if filter.After != nil {
+			conditions = append(conditions, fmt.Sprintf("(start_time, id) < (%s, %s)", arg(filter.After.StartTime), arg(filter.After.ID)))
+		}
+	case EventSortNameAsc:
+		orderBy = "name ASC, id ASC"
+		if filter.After != nil {
+			conditions = append(conditions, fmt.Sprintf("(name, id) > (%s, %s)", arg(filter.After.Name), arg(filter.After.ID)))
+		}
+	case EventSortNameDesc:
+		orderBy = "name DESC, id DESC"
+		if filter.After != nil {
+			conditions = append(conditions, fmt.Sprintf("(name, id) < (%s, %s)", arg(filter.After.Name), arg(filter.After.ID)))
+		}
+	default:
+		orderBy = "start_time ASC, id ASC"
+		if filter.After != nil {
+			conditions = append(conditions, fmt.Sprintf("(start_time, id) > (%s, %s)", arg(filter.After.StartTime), arg(filter.After.ID)))
+		}
+	}
 
-	rows, err := r.DB.Query(ctx, query)
+	query := `SELECT id, name, venue, start_time, capacity, booked_tickets, held_tickets, status, waitlist_policy, waitlist_offer_minutes, version, venue_id, series_id FROM events`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY " + orderBy + " LIMIT " + arg(filter.Limit)
+
+	rows, err := r.DB.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var events []Event
+	events := []Event{}
 	for rows.Next() {
 		var event Event
 		err := rows.Scan(
@@ -94,6 +185,13 @@ func (r *EventRepository) GetAll(ctx context.Context) ([]Event, error) {
 			&event.StartTime,
 			&event.Capacity,
 			&event.BookedTickets,
+			&event.HeldTickets,
+			&event.Status,
+			&event.WaitlistPolicy,
+			&event.OfferMinutes,
+			&event.Version,
+			&event.VenueID,
+			&event.SeriesID,
 		)
 		if err != nil {
 			return nil, err
@@ -101,11 +199,19 @@ func (r *EventRepository) GetAll(ctx context.Context) ([]Event, error) {
 		events = append(events, event)
 	}
 
-	return events, nil
+	return events, rows.Err()
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
 }
 
 func (r *EventRepository) GetByID(ctx context.Context, id string) (*Event, error) {
-	query := `SELECT id, name, venue, start_time, capacity, booked_tickets FROM events WHERE id = $1`
+	query := `
+		SELECT id, name, venue, start_time, capacity, booked_tickets, held_tickets, status, waitlist_policy, waitlist_offer_minutes, version,
+			venue_id, EXISTS(SELECT 1 FROM event_seats WHERE event_id = events.id), series_id
+		FROM events WHERE id = $1
+	`
 	var event Event
 	err := r.DB.QueryRow(ctx, query, id).Scan(
 		&event.ID,
@@ -114,7 +220,100 @@ func (r *EventRepository) GetByID(ctx context.Context, id string) (*Event, error
 		&event.StartTime,
 		&event.Capacity,
 		&event.BookedTickets,
+		&event.HeldTickets,
+		&event.Status,
+		&event.WaitlistPolicy,
+		&event.OfferMinutes,
+		&event.Version,
+		&event.VenueID,
+		&event.ReservedSeating,
+		&event.SeriesID,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if event.VenueID != nil {
+		event.VenueDetails, err = getVenue(ctx, r.DB, *event.VenueID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	event.Tiers, err = listTiers(ctx, r.DB, id)
+	if err != nil {
+		return nil, err
+	}
+	annotateTiers(&event, time.Now())
+	return &event, nil
+}
+
+// Create inserts the event and makes ownerID its owner, so organizers can
+// manage the events they create.
+func (r *EventRepository) Create(ctx context.Context, event *Event, ownerID string) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO events (name, venue, start_time, capacity, waitlist_policy, waitlist_offer_minutes, venue_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at, version, booked_tickets, held_tickets, status
+	`
+	args := []interface{}{event.Name, event.Venue, event.StartTime, event.Capacity, event.WaitlistPolicy, event.OfferMinutes, event.VenueID}
+	err = tx.QueryRow(ctx, query, args...).Scan(&event.ID, &event.CreatedAt, &event.UpdatedAt, &event.Version, &event.BookedTickets, &event.HeldTickets, &event.Status)
+	if err != nil {
+		return err
+	}
+
+	memberQuery := `INSERT INTO event_members (event_id, user_id, role, added_by) VALUES ($1, $2, 'owner', $2)`
+	if _, err := tx.Exec(ctx, memberQuery, event.ID, ownerID); err != nil {
+		return err
+	}
+
+	if err := EmitWebhook(ctx, tx, WebhookEventCreated, event); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// eventForUpdateColumns are the columns an event is edited from, read by
+// scanEventForUpdate.
+const eventForUpdateColumns = `
+	id, name, venue, start_time, capacity, booked_tickets, held_tickets, status, waitlist_policy, waitlist_offer_minutes, version, created_at, updated_at,
+	venue_id, EXISTS(SELECT 1 FROM event_seats WHERE event_id = events.id), series_id
+`
+
+func scanEventForUpdate(row pgx.Row, event *Event) error {
+	return row.Scan(
+		&event.ID,
+		&event.Name,
+		&event.Venue,
+		&event.StartTime,
+		&event.Capacity,
+		&event.BookedTickets,
+		&event.HeldTickets,
+		&event.Status,
+		&event.WaitlistPolicy,
+		&event.OfferMinutes,
+		&event.Version,
+		&event.CreatedAt,
+		&event.UpdatedAt,
+		&event.VenueID,
+		&event.ReservedSeating,
+		&event.SeriesID,
 	)
+}
+
+func (r *EventRepository) GetForUpdate(ctx context.Context, tx pgx.Tx, id string) (*Event, error) {
+	query := `SELECT ` + eventForUpdateColumns + ` FROM events WHERE id = $1 FOR UPDATE`
+	var event Event
+	err := scanEventForUpdate(tx.QueryRow(ctx, query, id), &event)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -124,12 +323,211 @@ func (r *EventRepository) GetByID(ctx context.Context, id string) (*Event, error
 	return &event, nil
 }
 
-func (r *EventRepository) Create(ctx context.Context, event *Event) error {
+func (r *EventRepository) Update(ctx context.Context, tx pgx.Tx, event *Event) error {
+	query := `
+		UPDATE events SET name = $3, venue = $4, start_time = $5, capacity = $6, status = $7,
+			waitlist_policy = $8, waitlist_offer_minutes = $9, venue_id = $10, version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND version = $2
+		RETURNING version, updated_at
+	`
+	args := []interface{}{
+		event.ID, event.Version, event.Name, event.Venue, event.StartTime, event.Capacity, event.Status,
+		event.WaitlistPolicy, event.OfferMinutes, event.VenueID,
+	}
+	err := tx.QueryRow(ctx, query, args...).Scan(&event.Version, &event.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrConflict
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *EventRepository) Delete(ctx context.Context, id string, version int) error {
+	tag, err := r.DB.Exec(ctx, `DELETE FROM events WHERE id = $1 AND version = $2`, id, version)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+
+	var exists bool
+	if err := r.DB.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM events WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	return ErrConflict
+}
+
+func (r *EventRepository) HasBookings(ctx context.Context, id string) (bool, error) {
+	var exists bool
+	err := r.DB.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM bookings WHERE event_id = $1)`, id).Scan(&exists)
+	return exists, err
+}
+
+// Cancel marks the event and all of its confirmed bookings as cancelled,
+// empties its waitlist, withdraws pending offers and holds, frees its seats,
+// and records every affected user against the returned cancellation so
+// notifications and refunds can be driven from it. Each cancelled booking is
+// announced to webhook subscribers on its own.
+func (r *EventRepository) Cancel(ctx context.Context, tx pgx.Tx, eventID, cancelledBy, reason string) (*EventCancellation, error) {
+	c := EventCancellation{EventID: eventID, CancelledBy: cancelledBy, Reason: reason}
+	query := `
+		INSERT INTO event_cancellations (event_id, cancelled_by, reason)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+	if err := tx.QueryRow(ctx, query, eventID, cancelledBy, reason).Scan(&c.ID, &c.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	recordQuery := `
+		INSERT INTO event_cancellation_recipients (cancellation_id, user_id, booking_id, source, quantity)
+		SELECT $1, user_id, id, 'booking', quantity FROM bookings WHERE event_id = $2 AND status = 'confirmed'
+		UNION ALL
+		SELECT $1, user_id, NULL, 'waitlist', quantity FROM waitlist_entries WHERE event_id = $2
+		UNION ALL
+		SELECT $1, user_id, NULL, 'waitlist', quantity FROM waitlist_offers WHERE event_id = $2 AND status = 'pending'
+	`
+	if _, err := tx.Exec(ctx, recordQuery, c.ID, eventID); err != nil {
+		return nil, err
+	}
+
+	cancelled, err := cancelConfirmedBookings(ctx, tx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	for _, payload := range cancelled {
+		if err := EmitWebhook(ctx, tx, WebhookBookingCancelled, payload); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM waitlist_entries WHERE event_id = $1`, eventID); err != nil {
+		return nil, err
+	}
+	expireOffersQuery := `
+		UPDATE waitlist_offers SET status = 'expired', responded_at = NOW()
+		WHERE event_id = $1 AND status = 'pending'
+	`
+	if _, err := tx.Exec(ctx, expireOffersQuery, eventID); err != nil {
+		return nil, err
+	}
+	if err := releaseEventHolds(ctx, tx, eventID); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE event_seats SET booking_id = NULL WHERE event_id = $1`, eventID); err != nil {
+		return nil, err
+	}
+	resetTiersQuery := `UPDATE ticket_tiers SET sold = 0, held = 0, version = version + 1, updated_at = NOW() WHERE event_id = $1`
+	if _, err := tx.Exec(ctx, resetTiersQuery, eventID); err != nil {
+		return nil, err
+	}
+
+	cancelEventQuery := `
+		UPDATE events SET status = 'cancelled', booked_tickets = 0, held_tickets = 0, version = version + 1, updated_at = NOW()
+		WHERE id = $1
+	`
+	if _, err := tx.Exec(ctx, cancelEventQuery, eventID); err != nil {
+		return nil, err
+	}
+
+	notifyQuery := `
+		INSERT INTO notification_outbox (kind, user_id, payload)
+		SELECT $2, r.user_id, jsonb_build_object(
+			'event_id', e.id, 'event_name', e.name, 'event_start', e.start_time,
+			'quantity', r.quantity, 'source', r.source, 'reason', c.reason)
+		FROM event_cancellation_recipients r
+		JOIN event_cancellations c ON c.id = r.cancellation_id
+		JOIN events e ON e.id = c.event_id
+		WHERE r.cancellation_id = $1
+	`
+	if _, err := tx.Exec(ctx, notifyQuery, c.ID, NotificationEventCancelled); err != nil {
+		return nil, err
+	}
+
+	recipients, err := r.getCancellationRecipients(ctx, tx, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	c.Recipients = recipients
+	return &c, nil
+}
+
+// cancelConfirmedBookings cancels every confirmed booking of the event and
+// returns their webhook payloads. The rows are read in full before any
+// webhook is queued, since the transaction can only run one query at a time.
+func cancelConfirmedBookings(ctx context.Context, tx pgx.Tx, eventID string) ([]map[string]any, error) {
 	query := `
-		INSERT INTO events (name, venue, start_time, capacity)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, created_at, updated_at, version, booked_tickets
+		UPDATE bookings SET status = 'cancelled', cancelled_at = NOW(), updated_at = NOW()
+		WHERE event_id = $1 AND status = 'confirmed'
+		RETURNING id, tier_id, user_id, quantity
 	`
-	args := []interface{}{event.Name, event.Venue, event.StartTime, event.Capacity}
-	return r.DB.QueryRow(ctx, query, args...).Scan(&event.ID, &event.CreatedAt, &event.UpdatedAt, &event.Version, &event.BookedTickets)
+	rows, err := tx.Query(ctx, query, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payloads []map[string]any
+	for rows.Next() {
+		var bookingID, userID string
+		var tierID *string
+		var quantity int
+		if err := rows.Scan(&bookingID, &tierID, &userID, &quantity); err != nil {
+			return nil, err
+		}
+		payloads = append(payloads, bookingPayload(bookingID, eventID, tierID, userID, quantity))
+	}
+	return payloads, rows.Err()
+}
+
+func (r *EventRepository) GetCancellation(ctx context.Context, eventID string) (*EventCancellation, error) {
+	var c EventCancellation
+	var cancelledBy *string
+	query := `SELECT id, event_id, cancelled_by, reason, created_at FROM event_cancellations WHERE event_id = $1`
+	err := r.DB.QueryRow(ctx, query, eventID).Scan(&c.ID, &c.EventID, &cancelledBy, &c.Reason, &c.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if cancelledBy != nil {
+		c.CancelledBy = *cancelledBy
+	}
+
+	recipients, err := r.getCancellationRecipients(ctx, r.DB, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	c.Recipients = recipients
+	return &c, nil
+}
+
+func (r *EventRepository) getCancellationRecipients(ctx context.Context, q querier, cancellationID string) ([]CancellationRecipient, error) {
+	query := `
+		SELECT r.user_id, u.email, r.booking_id, r.source, r.quantity
+		FROM event_cancellation_recipients r JOIN users u ON u.id = r.user_id
+		WHERE r.cancellation_id = $1 ORDER BY r.created_at, r.source
+	`
+	rows, err := q.Query(ctx, query, cancellationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recipients := []CancellationRecipient{}
+	for rows.Next() {
+		var recipient CancellationRecipient
+		if err := rows.Scan(&recipient.UserID, &recipient.Email, &recipient.BookingID, &recipient.Source, &recipient.Quantity); err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, recipient)
+	}
+	return recipients, rows.Err()
 }
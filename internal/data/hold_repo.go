@@ -0,0 +1,208 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type HoldRepository struct {
+	DB *pgxpool.Pool
+}
+
+const holdColumns = `id, event_id, tier_id, user_id, quantity, status, expires_at, booking_id, created_at`
+
+func scanHold(row pgx.Row, hold *Hold) error {
+	return row.Scan(&hold.ID, &hold.EventID, &hold.TierID, &hold.UserID, &hold.Quantity, &hold.Status, &hold.ExpiresAt, &hold.BookingID, &hold.CreatedAt)
+}
+
+// Create holds tickets under the same version checks as
+// BookingRepository.CreateBooking. tier is nil for events without tiers.
+func (r *HoldRepository) Create(ctx context.Context, event *EventForUpdate, tier *TicketTier, userID string, quantity int, ttl time.Duration) (*Hold, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	updateEventQuery := `
+		UPDATE events SET held_tickets = held_tickets + $3, version = version + 1
+		WHERE id = $1 AND version = $2
+	`
+	tag, err := tx.Exec(ctx, updateEventQuery, event.ID, event.Version, quantity)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrConflict
+	}
+
+	hold := Hold{EventID: event.ID, UserID: userID, Quantity: quantity}
+	if tier != nil {
+		updateTierQuery := `
+			UPDATE ticket_tiers SET held = held + $3, version = version + 1
+			WHERE id = $1 AND version = $2
+		`
+		tag, err := tx.Exec(ctx, updateTierQuery, tier.ID, tier.Version, quantity)
+		if err != nil {
+			return nil, err
+		}
+		if tag.RowsAffected() == 0 {
+			return nil, ErrConflict
+		}
+		hold.TierID = &tier.ID
+	}
+
+	insertHoldQuery := `
+		INSERT INTO ticket_holds (event_id, tier_id, user_id, quantity, expires_at)
+		VALUES ($1, $2, $3, $4, NOW() + $5::interval)
+		RETURNING id, status, expires_at, created_at
+	`
+	err = tx.QueryRow(ctx, insertHoldQuery, event.ID, hold.TierID, userID, quantity, ttl).Scan(&hold.ID, &hold.Status, &hold.ExpiresAt, &hold.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hold, tx.Commit(ctx)
+}
+
+func (r *HoldRepository) GetForUpdate(ctx context.Context, tx pgx.Tx, holdID, userID string) (*Hold, error) {
+	var hold Hold
+	query := `SELECT ` + holdColumns + ` FROM ticket_holds WHERE id = $1 AND user_id = $2 FOR UPDATE`
+	if err := scanHold(tx.QueryRow(ctx, query, holdID, userID), &hold); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// LockEventStatus locks the event the hold is for and returns its status.
+// It takes the event lock before the hold's, the same order as event
+// cancellation, so the two cannot deadlock.
+func (r *HoldRepository) LockEventStatus(ctx context.Context, tx pgx.Tx, holdID, userID string) (string, error) {
+	query := `
+		SELECT e.status FROM events e JOIN ticket_holds h ON h.event_id = e.id
+		WHERE h.id = $1 AND h.user_id = $2
+		FOR UPDATE OF e
+	`
+	var status string
+	if err := tx.QueryRow(ctx, query, holdID, userID).Scan(&status); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	return status, nil
+}
+
+// Confirm converts an active hold into a booking, moving its tickets from the
+// event's held counter to the booked counter.
+func (r *HoldRepository) Confirm(ctx context.Context, tx pgx.Tx, hold *Hold) error {
+	var bookingID string
+	insertBookingQuery := `INSERT INTO bookings (user_id, event_id, tier_id, quantity) VALUES ($1, $2, $3, $4) RETURNING id`
+	if err := tx.QueryRow(ctx, insertBookingQuery, hold.UserID, hold.EventID, hold.TierID, hold.Quantity).Scan(&bookingID); err != nil {
+		return err
+	}
+
+	updateEventQuery := `
+		UPDATE events SET held_tickets = held_tickets - $2, booked_tickets = booked_tickets + $2, version = version + 1
+		WHERE id = $1
+	`
+	if _, err := tx.Exec(ctx, updateEventQuery, hold.EventID, hold.Quantity); err != nil {
+		return err
+	}
+	if err := adjustTier(ctx, tx, hold.TierID, hold.Quantity, -hold.Quantity); err != nil {
+		return err
+	}
+
+	fields := map[string]any{"booking_id": bookingID, "quantity": hold.Quantity}
+	if err := EnqueueNotification(ctx, tx, NotificationBookingCreated, hold.UserID, hold.EventID, fields); err != nil {
+		return err
+	}
+	if err := EmitWebhook(ctx, tx, WebhookBookingCreated, bookingPayload(bookingID, hold.EventID, hold.TierID, hold.UserID, hold.Quantity)); err != nil {
+		return err
+	}
+
+	updateHoldQuery := `UPDATE ticket_holds SET status = 'confirmed', booking_id = $2, updated_at = NOW() WHERE id = $1`
+	if _, err := tx.Exec(ctx, updateHoldQuery, hold.ID, bookingID); err != nil {
+		return err
+	}
+	hold.Status = HoldStatusConfirmed
+	hold.BookingID = &bookingID
+	return nil
+}
+
+// Release ends an active hold with the given status and returns its tickets
+// to the event's free pool.
+func (r *HoldRepository) Release(ctx context.Context, tx pgx.Tx, hold *Hold, status string) error {
+	updateHoldQuery := `UPDATE ticket_holds SET status = $2, updated_at = NOW() WHERE id = $1`
+	if _, err := tx.Exec(ctx, updateHoldQuery, hold.ID, status); err != nil {
+		return err
+	}
+
+	updateEventQuery := `UPDATE events SET held_tickets = held_tickets - $2, version = version + 1 WHERE id = $1`
+	if _, err := tx.Exec(ctx, updateEventQuery, hold.EventID, hold.Quantity); err != nil {
+		return err
+	}
+	if err := adjustTier(ctx, tx, hold.TierID, 0, -hold.Quantity); err != nil {
+		return err
+	}
+	hold.Status = status
+	return nil
+}
+
+// ClaimExpired locks up to limit active holds whose expiry has passed. Rows
+// locked by a concurrent sweeper are skipped.
+func (r *HoldRepository) ClaimExpired(ctx context.Context, tx pgx.Tx, limit int) ([]Hold, error) {
+	query := `
+		SELECT ` + holdColumns + ` FROM ticket_holds WHERE status = 'active' AND expires_at <= NOW()
+		ORDER BY expires_at LIMIT $1 FOR UPDATE SKIP LOCKED
+	`
+	return queryHolds(ctx, tx, query, limit)
+}
+
+// GetByUserID returns all of the user's holds, newest first.
+func (r *HoldRepository) GetByUserID(ctx context.Context, userID string) ([]Hold, error) {
+	query := `SELECT ` + holdColumns + ` FROM ticket_holds WHERE user_id = $1 ORDER BY created_at DESC`
+	return queryHolds(ctx, r.DB, query, userID)
+}
+
+// LockActiveByUserID locks the user's active holds so they can be released.
+func (r *HoldRepository) LockActiveByUserID(ctx context.Context, tx pgx.Tx, userID string) ([]Hold, error) {
+	query := `SELECT ` + holdColumns + ` FROM ticket_holds WHERE user_id = $1 AND status = 'active' FOR UPDATE`
+	return queryHolds(ctx, tx, query, userID)
+}
+
+func queryHolds(ctx context.Context, q querier, query string, args ...any) ([]Hold, error) {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holds := []Hold{}
+	for rows.Next() {
+		var hold Hold
+		if err := scanHold(rows, &hold); err != nil {
+			return nil, err
+		}
+		holds = append(holds, hold)
+	}
+	return holds, rows.Err()
+}
+
+// releaseEventHolds ends every active hold of an event that is being
+// cancelled. The event's and tiers' held counters are reset by the caller.
+func releaseEventHolds(ctx context.Context, tx pgx.Tx, eventID string) error {
+	query := `
+		UPDATE ticket_holds SET status = 'released', updated_at = NOW()
+		WHERE event_id = $1 AND status = 'active'
+	`
+	_, err := tx.Exec(ctx, query, eventID)
+	return err
+}
@@ -0,0 +1,88 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type IdempotencyRepository struct {
+	DB *pgxpool.Pool
+}
+
+type IdempotencyRecord struct {
+	UserID              string
+	Key                 string
+	RequestMethod       string
+	RequestPath         string
+	RequestHash         string
+	ResponseStatus      *int
+	ResponseContentType string
+	ResponseBody        []byte
+}
+
+// Completed reports whether the original request has finished and its
+// response can be replayed.
+func (r *IdempotencyRecord) Completed() bool {
+	return r.ResponseStatus != nil
+}
+
+// Reserve claims the key for a new request. If the key already exists the
+// stored record is returned with created set to false.
+func (r *IdempotencyRepository) Reserve(ctx context.Context, record *IdempotencyRecord) (bool, error) {
+	insertQuery := `
+		INSERT INTO idempotency_keys (user_id, key, request_method, request_path, request_hash)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, key) DO NOTHING
+	`
+	tag, err := r.DB.Exec(ctx, insertQuery, record.UserID, record.Key, record.RequestMethod, record.RequestPath, record.RequestHash)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 1 {
+		return true, nil
+	}
+
+	selectQuery := `
+		SELECT request_method, request_path, request_hash, response_status, COALESCE(response_content_type, ''), response_body
+		FROM idempotency_keys WHERE user_id = $1 AND key = $2
+	`
+	err = r.DB.QueryRow(ctx, selectQuery, record.UserID, record.Key).Scan(
+		&record.RequestMethod, &record.RequestPath, &record.RequestHash, &record.ResponseStatus, &record.ResponseContentType, &record.ResponseBody,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// The key was released between our insert and select; let the caller retry.
+			return false, ErrConflict
+		}
+		return false, err
+	}
+	return false, nil
+}
+
+func (r *IdempotencyRepository) Complete(ctx context.Context, userID, key string, status int, contentType string, body []byte) error {
+	query := `
+		UPDATE idempotency_keys
+		SET response_status = $3, response_content_type = $4, response_body = $5, completed_at = NOW()
+		WHERE user_id = $1 AND key = $2
+	`
+	_, err := r.DB.Exec(ctx, query, userID, key, status, contentType, body)
+	return err
+}
+
+// Release forgets a key whose request failed so the client can retry it.
+func (r *IdempotencyRepository) Release(ctx context.Context, userID, key string) error {
+	_, err := r.DB.Exec(ctx, `DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2`, userID, key)
+	return err
+}
+
+func (r *IdempotencyRepository) DeleteOlderThan(ctx context.Context, cutoff time.Time) (int64, error) {
+	tag, err := r.DB.Exec(ctx, `DELETE FROM idempotency_keys WHERE created_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
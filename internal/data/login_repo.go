@@ -0,0 +1,134 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	LoginScopeEmail = "email"
+	LoginScopeIP    = "ip"
+	// LoginScopeReset counts password reset requests per email address.
+	LoginScopeReset = "reset"
+)
+
+type LoginFailure struct {
+	Scope        string     `json:"scope"`
+	Key          string     `json:"key"`
+	Failures     int        `json:"failures"`
+	LastFailedAt time.Time  `json:"last_failed_at"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty"`
+}
+
+type LoginAttemptRepository struct {
+	DB *pgxpool.Pool
+}
+
+func (r *LoginAttemptRepository) Get(ctx context.Context, scope, key string) (*LoginFailure, error) {
+	query := `SELECT scope, key, failures, last_failed_at, locked_until FROM login_failures WHERE scope = $1 AND key = $2`
+	var f LoginFailure
+	err := r.DB.QueryRow(ctx, query, scope, key).Scan(&f.Scope, &f.Key, &f.Failures, &f.LastFailedAt, &f.LockedUntil)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &f, nil
+}
+
+// CountAttempt stores failures and lockedUntil for a new attempt, provided
+// the counter still holds seen, nil meaning there was none. It reports false
+// if another attempt changed the counter first.
+func (r *LoginAttemptRepository) CountAttempt(ctx context.Context, scope, key string, seen *LoginFailure, failures int, lockedUntil *time.Time) (bool, error) {
+	if seen == nil {
+		query := `
+			INSERT INTO login_failures (scope, key, failures, last_failed_at, locked_until) VALUES ($1, $2, $3, NOW(), $4)
+			ON CONFLICT (scope, key) DO NOTHING
+		`
+		tag, err := r.DB.Exec(ctx, query, scope, key, failures, lockedUntil)
+		if err != nil {
+			return false, err
+		}
+		return tag.RowsAffected() > 0, nil
+	}
+
+	query := `
+		UPDATE login_failures SET failures = $3, last_failed_at = NOW(), locked_until = $4
+		WHERE scope = $1 AND key = $2 AND failures = $5 AND last_failed_at = $6
+		RETURNING failures
+	`
+	var stored int
+	err := r.DB.QueryRow(ctx, query, scope, key, failures, lockedUntil, seen.Failures, seen.LastFailedAt).Scan(&stored)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Forgive takes back one counted attempt after it turned out to be a
+// successful login, lifting the lockout it triggered if it reached limit.
+func (r *LoginAttemptRepository) Forgive(ctx context.Context, scope, key string, limit int) error {
+	query := `
+		UPDATE login_failures SET
+			failures = failures - 1,
+			locked_until = CASE WHEN failures - 1 < $3 THEN NULL ELSE locked_until END
+		WHERE scope = $1 AND key = $2 AND failures > 0
+	`
+	_, err := r.DB.Exec(ctx, query, scope, key, limit)
+	return err
+}
+
+// Clear forgets the failures for a key, lifting any lockout. It reports
+// whether there was anything to clear.
+func (r *LoginAttemptRepository) Clear(ctx context.Context, scope, key string) (bool, error) {
+	tag, err := r.DB.Exec(ctx, `DELETE FROM login_failures WHERE scope = $1 AND key = $2`, scope, key)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *LoginAttemptRepository) ListLocked(ctx context.Context) ([]LoginFailure, error) {
+	query := `
+		SELECT scope, key, failures, last_failed_at, locked_until FROM login_failures
+		WHERE locked_until > NOW()
+		ORDER BY locked_until DESC
+	`
+	rows, err := r.DB.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	locked := []LoginFailure{}
+	for rows.Next() {
+		var f LoginFailure
+		if err := rows.Scan(&f.Scope, &f.Key, &f.Failures, &f.LastFailedAt, &f.LockedUntil); err != nil {
+			return nil, err
+		}
+		locked = append(locked, f)
+	}
+	return locked, rows.Err()
+}
+
+// DeleteStale removes counters that are neither locked nor recent enough to
+// count towards a lockout.
+func (r *LoginAttemptRepository) DeleteStale(ctx context.Context, cutoff time.Time) (int64, error) {
+	query := `
+		DELETE FROM login_failures
+		WHERE last_failed_at < $1 AND (locked_until IS NULL OR locked_until < NOW())
+	`
+	tag, err := r.DB.Exec(ctx, query, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
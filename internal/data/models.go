@@ -2,6 +2,42 @@ package data
 
 import "time"
 
+const (
+	EventStatusScheduled = "scheduled"
+	EventStatusCancelled = "cancelled"
+	EventStatusPostponed = "postponed"
+	EventStatusCompleted = "completed"
+)
+
+const (
+	BookingStatusConfirmed = "confirmed"
+	BookingStatusCancelled = "cancelled"
+)
+
+const (
+	WaitlistPolicyAutoBook = "auto_book"
+	WaitlistPolicyOffer    = "offer"
+)
+
+const (
+	OfferStatusPending  = "pending"
+	OfferStatusAccepted = "accepted"
+	OfferStatusDeclined = "declined"
+	OfferStatusExpired  = "expired"
+)
+
+const (
+	HoldStatusActive    = "active"
+	HoldStatusConfirmed = "confirmed"
+	HoldStatusReleased  = "released"
+	HoldStatusExpired   = "expired"
+)
+
+const (
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeEmailVerification = "email_verification"
+)
+
 type User struct {
 	ID           string    `json:"id"`
 	Name         string    `json:"name"`
@@ -10,6 +46,11 @@ type User struct {
 	Role         string    `json:"role"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
+
+	EmailVerifiedAt       *time.Time `json:"email_verified_at"`
+	TwoFactorEnabled      bool       `json:"two_factor_enabled"`
+	DisabledAt            *time.Time `json:"disabled_at"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
 }
 
 type Event struct {
@@ -19,9 +60,219 @@ type Event struct {
 	StartTime     time.Time `json:"start_time"`
 	Capacity      int       `json:"capacity"`
 	BookedTickets int       `json:"booked_tickets"`
-	Version       int       `json:"-"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	HeldTickets   int       `json:"held_tickets"`
+	Status        string    `json:"status"`
+	// WaitlistPolicy decides whether freed tickets are booked for the next
+	// waitlisted user straight away or offered to them for OfferMinutes.
+	WaitlistPolicy string    `json:"waitlist_policy"`
+	OfferMinutes   int       `json:"waitlist_offer_minutes"`
+	Version        int       `json:"version"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	// VenueID links the venue Venue names. VenueDetails and ReservedSeating
+	// are only loaded for a single event; an event with reserved seating
+	// sells the seats of its venue's seat map.
+	VenueID         *string `json:"venue_id"`
+	VenueDetails    *Venue  `json:"venue_details,omitempty"`
+	ReservedSeating bool    `json:"reserved_seating"`
+	// SeriesID is set on occurrences generated from an event series.
+	SeriesID *string `json:"series_id,omitempty"`
+	// Tiers is only loaded for a single event. An event without tiers sells
+	// from one untyped pool of Capacity tickets.
+	Tiers []TicketTier `json:"tiers,omitempty"`
+}
+
+// EventSeries is the template recurring events are generated from. Its
+// RRule repeats StartsAt's local time in Timezone; Exclusions are local
+// dates skipped. Occurrences is only loaded for the series view.
+type EventSeries struct {
+	ID               string    `json:"id"`
+	Name             string    `json:"name"`
+	Venue            string    `json:"venue"`
+	VenueID          *string   `json:"venue_id"`
+	Capacity         int       `json:"capacity"`
+	WaitlistPolicy   string    `json:"waitlist_policy"`
+	OfferMinutes     int       `json:"waitlist_offer_minutes"`
+	Timezone         string    `json:"timezone"`
+	StartsAt         time.Time `json:"starts_at"`
+	RRule            string    `json:"rrule"`
+	Exclusions       []string  `json:"exclusions"`
+	GeneratedThrough string    `json:"generated_through"`
+	OwnerID          *string   `json:"owner_id"`
+	Version          int       `json:"version"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+	Occurrences      []Event   `json:"occurrences,omitempty"`
+}
+
+// EventSession is a part of an event, such as a breakout, with its own
+// room and capacity. Users holding a booking for the event register for
+// sessions one place each.
+type EventSession struct {
+	ID         string    `json:"id"`
+	EventID    string    `json:"event_id"`
+	Title      string    `json:"title"`
+	Room       string    `json:"room"`
+	StartsAt   time.Time `json:"starts_at"`
+	EndsAt     time.Time `json:"ends_at"`
+	Capacity   int       `json:"capacity"`
+	Registered int       `json:"registered"`
+	Available  int       `json:"available"`
+	Version    int       `json:"version"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	// EventStatus is the status of the session's event, read for
+	// registration.
+	EventStatus string `json:"-"`
+}
+
+// SessionRegistration is a user's place in a session, shown in their
+// agenda.
+type SessionRegistration struct {
+	ID        string    `json:"id"`
+	SessionID string    `json:"session_id"`
+	EventID   string    `json:"event_id"`
+	EventName string    `json:"event_name"`
+	Title     string    `json:"title"`
+	Room      string    `json:"room"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TicketTier is a type of ticket for an event with its own price and
+// inventory. Available and OnSale are only set when the tier is shown with
+// its event; Available also accounts for the event's remaining capacity.
+type TicketTier struct {
+	ID          string     `json:"id"`
+	EventID     string     `json:"event_id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	PriceCents  int64      `json:"price_cents"`
+	Currency    string     `json:"currency"`
+	Quantity    int        `json:"quantity"`
+	Sold        int        `json:"sold"`
+	Held        int        `json:"held"`
+	Available   int        `json:"available"`
+	OnSale      bool       `json:"on_sale"`
+	SalesStart  *time.Time `json:"sales_start"`
+	SalesEnd    *time.Time `json:"sales_end"`
+	Position    int        `json:"position"`
+	Version     int        `json:"version"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Remaining is the number of the tier's tickets neither sold nor held.
+func (t *TicketTier) Remaining() int {
+	return t.Quantity - t.Sold - t.Held
+}
+
+// SellingAt reports whether now falls inside the tier's sale window.
+func (t *TicketTier) SellingAt(now time.Time) bool {
+	return (t.SalesStart == nil || !now.Before(*t.SalesStart)) && (t.SalesEnd == nil || now.Before(*t.SalesEnd))
+}
+
+// Venue is a place events are held. Sections is its seat map, only loaded
+// when the venue is fetched on its own; EventCount is only set in listings.
+type Venue struct {
+	ID              string         `json:"id"`
+	Name            string         `json:"name"`
+	Address         string         `json:"address"`
+	Timezone        string         `json:"timezone"`
+	DefaultCapacity *int           `json:"default_capacity"`
+	Latitude        *float64       `json:"latitude"`
+	Longitude       *float64       `json:"longitude"`
+	EventCount      *int           `json:"event_count,omitempty"`
+	Sections        []VenueSection `json:"sections,omitempty"`
+	Version         int            `json:"version"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
+// VenueSection is a block of seats. Sections with a lower Rank are the
+// better seats.
+type VenueSection struct {
+	ID   string    `json:"id"`
+	Name string    `json:"name"`
+	Rank int       `json:"rank"`
+	Rows []SeatRow `json:"rows"`
+}
+
+// SeatRow is a row of a section, front rows first.
+type SeatRow struct {
+	Label string `json:"label"`
+	Seats []Seat `json:"seats"`
+}
+
+type Seat struct {
+	ID     string `json:"id"`
+	Number int    `json:"number"`
+}
+
+const (
+	SeatAvailable = "available"
+	SeatBooked    = "booked"
+)
+
+// EventSeat is a seat of an event with reserved seating. TierID is the tier
+// the seat is sold in, for events with tiers.
+type EventSeat struct {
+	SeatID      string  `json:"seat_id"`
+	SectionID   string  `json:"section_id"`
+	Section     string  `json:"section"`
+	SectionRank int     `json:"section_rank"`
+	Row         string  `json:"row"`
+	RowPosition int     `json:"row_position"`
+	Number      int     `json:"number"`
+	TierID      *string `json:"tier_id"`
+	Status      string  `json:"status"`
+}
+
+// SeatBooking is a booking of specific seats.
+type SeatBooking struct {
+	BookingID string      `json:"booking_id"`
+	Seats     []EventSeat `json:"seats"`
+}
+
+// APIKey is a credential an integration uses instead of a login session.
+// The secret itself is only returned once, when the key is created.
+type APIKey struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  *string    `json:"created_by"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// EventMember is a user with event-scoped access, see authz.MemberAllows.
+type EventMember struct {
+	EventID   string    `json:"event_id"`
+	UserID    string    `json:"user_id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	AddedBy   *string   `json:"added_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// EventBooking is a booking as seen by the event's organizers.
+type EventBooking struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	TierID    *string   `json:"tier_id"`
+	TierName  *string   `json:"tier_name"`
+	Seats     []string  `json:"seats,omitempty"`
+	Quantity  int       `json:"quantity"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type Booking struct {
@@ -35,5 +286,117 @@ type EventForUpdate struct {
 	ID            string
 	Capacity      int
 	BookedTickets int
+	HeldTickets   int
+	Status        string
 	Version       int
 }
+
+// Available is the number of tickets neither booked nor held.
+func (e *EventForUpdate) Available() int {
+	return e.Capacity - e.BookedTickets - e.HeldTickets
+}
+
+type EventCancellation struct {
+	ID          string                  `json:"id"`
+	EventID     string                  `json:"event_id"`
+	CancelledBy string                  `json:"cancelled_by"`
+	Reason      string                  `json:"reason"`
+	CreatedAt   time.Time               `json:"created_at"`
+	Recipients  []CancellationRecipient `json:"recipients"`
+}
+
+type CancellationRecipient struct {
+	UserID    string  `json:"user_id"`
+	Email     string  `json:"email"`
+	BookingID *string `json:"booking_id,omitempty"`
+	Source    string  `json:"source"`
+	Quantity  int     `json:"quantity"`
+}
+
+type Hold struct {
+	ID        string    `json:"id"`
+	EventID   string    `json:"event_id"`
+	TierID    *string   `json:"tier_id"`
+	UserID    string    `json:"user_id"`
+	Quantity  int       `json:"quantity"`
+	Status    string    `json:"status"`
+	ExpiresAt time.Time `json:"expires_at"`
+	BookingID *string   `json:"booking_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WaitlistOffer struct {
+	ID          string     `json:"id"`
+	EventID     string     `json:"event_id"`
+	EventName   string     `json:"event_name,omitempty"`
+	TierID      *string    `json:"tier_id"`
+	UserID      string     `json:"user_id"`
+	Quantity    int        `json:"quantity"`
+	Status      string     `json:"status"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	BookingID   *string    `json:"booking_id,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+}
+
+// WaitlistEntry is a user's place in an event's waitlist. Position is
+// 1-based; TicketsAhead is the total quantity requested by entries in front.
+type WaitlistEntry struct {
+	ID           string    `json:"id"`
+	EventID      string    `json:"event_id"`
+	EventName    string    `json:"event_name"`
+	TierID       *string   `json:"tier_id"`
+	Quantity     int       `json:"quantity"`
+	Position     int       `json:"position"`
+	TicketsAhead int       `json:"tickets_ahead"`
+	JoinedAt     time.Time `json:"joined_at"`
+}
+
+const (
+	WebhookBookingCreated   = "booking.created"
+	WebhookBookingCancelled = "booking.cancelled"
+	WebhookWaitlistJoined   = "waitlist.joined"
+	WebhookWaitlistPromoted = "waitlist.promoted"
+	WebhookEventCreated     = "event.created"
+)
+
+// WebhookEventTypes lists every event type a subscription may ask for.
+var WebhookEventTypes = []string{
+	WebhookBookingCreated,
+	WebhookBookingCancelled,
+	WebhookWaitlistJoined,
+	WebhookWaitlistPromoted,
+	WebhookEventCreated,
+}
+
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusDead      = "dead"
+)
+
+// WebhookSubscription is a partner endpoint. Secret is only populated when
+// the subscription is created; afterwards it is never returned.
+type WebhookSubscription struct {
+	ID         string    `json:"id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type WebhookDelivery struct {
+	ID             string         `json:"id"`
+	SubscriptionID string         `json:"subscription_id"`
+	EventType      string         `json:"event_type"`
+	Payload        map[string]any `json:"payload"`
+	Status         string         `json:"status"`
+	Attempts       int            `json:"attempts"`
+	NextAttemptAt  time.Time      `json:"next_attempt_at"`
+	LastStatusCode *int           `json:"last_status_code,omitempty"`
+	LastError      *string        `json:"last_error,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	DeliveredAt    *time.Time     `json:"delivered_at,omitempty"`
+}
@@ -0,0 +1,104 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type OIDCRepository struct {
+	DB *pgxpool.Pool
+}
+
+// OIDCLogin is an SSO login waiting for the provider to send the user back.
+type OIDCLogin struct {
+	ID           string
+	Nonce        string
+	CodeVerifier string
+}
+
+func (r *OIDCRepository) CreateLogin(ctx context.Context, stateHash, nonce, codeVerifier string, expiresAt time.Time) error {
+	query := `INSERT INTO oidc_logins (state_hash, nonce, code_verifier, expires_at) VALUES ($1, $2, $3, $4)`
+	_, err := r.DB.Exec(ctx, query, stateHash, nonce, codeVerifier, expiresAt)
+	return err
+}
+
+// ClaimState spends a login's state so the provider's response can only be
+// processed once. Unknown, expired and already used states return
+// ErrNotFound.
+func (r *OIDCRepository) ClaimState(ctx context.Context, stateHash string) (*OIDCLogin, error) {
+	query := `
+		UPDATE oidc_logins SET state_used_at = NOW()
+		WHERE state_hash = $1 AND state_used_at IS NULL AND expires_at > NOW()
+		RETURNING id, nonce, code_verifier
+	`
+	var login OIDCLogin
+	err := r.DB.QueryRow(ctx, query, stateHash).Scan(&login.ID, &login.Nonce, &login.CodeVerifier)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &login, nil
+}
+
+// SetHandoff records who signed in and the token the browser exchanges for
+// a session.
+func (r *OIDCRepository) SetHandoff(ctx context.Context, id, userID, handoffHash string, expiresAt time.Time) error {
+	query := `UPDATE oidc_logins SET user_id = $2, handoff_hash = $3, expires_at = $4 WHERE id = $1`
+	_, err := r.DB.Exec(ctx, query, id, userID, handoffHash, expiresAt)
+	return err
+}
+
+// ConsumeHandoff deletes the login and returns its user. Unknown and expired
+// handoff tokens return ErrNotFound.
+func (r *OIDCRepository) ConsumeHandoff(ctx context.Context, handoffHash string) (string, error) {
+	query := `DELETE FROM oidc_logins WHERE handoff_hash = $1 AND expires_at > NOW() RETURNING user_id`
+	var userID string
+	err := r.DB.QueryRow(ctx, query, handoffHash).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	return userID, err
+}
+
+// DeleteExpired removes logins that were abandoned or never completed.
+func (r *OIDCRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	tag, err := r.DB.Exec(ctx, `DELETE FROM oidc_logins WHERE expires_at < NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// GetIdentityUserID returns the account linked to a provider identity.
+func (r *OIDCRepository) GetIdentityUserID(ctx context.Context, issuer, subject string) (string, error) {
+	var userID string
+	err := r.DB.QueryRow(ctx, `SELECT user_id FROM user_identities WHERE issuer = $1 AND subject = $2`, issuer, subject).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	return userID, err
+}
+
+// LinkIdentity attaches a provider identity to an account. It returns
+// ErrDuplicate if the identity is already linked, which happens when two
+// first logins for the same identity race.
+func (r *OIDCRepository) LinkIdentity(ctx context.Context, tx pgx.Tx, userID, issuer, subject, email string) error {
+	query := `
+		INSERT INTO user_identities (user_id, issuer, subject, email) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (issuer, subject) DO NOTHING
+	`
+	tag, err := tx.Exec(ctx, query, userID, issuer, subject, email)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrDuplicate
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	NotificationBookingCreated   = "booking.created"
+	NotificationBookingCancelled = "booking.cancelled"
+	NotificationWaitlistPromoted = "waitlist.promoted"
+	NotificationWaitlistOffered  = "waitlist.offered"
+	NotificationEventCancelled   = "event.cancelled"
+)
+
+type OutboxRepository struct {
+	DB *pgxpool.Pool
+}
+
+// OutboxMessage is a claimed notification together with its recipient.
+type OutboxMessage struct {
+	ID       string
+	Kind     string
+	Payload  map[string]any
+	Attempts int
+	Email    string
+	Name     string
+}
+
+// EnqueueNotification records a notification for userID inside tx. The
+// payload is extended with the event's id, name and start time so the message
+// can be rendered without further lookups.
+func EnqueueNotification(ctx context.Context, tx pgx.Tx, kind, userID, eventID string, fields map[string]any) error {
+	payload, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO notification_outbox (kind, user_id, payload)
+		SELECT $1, $2, $3::jsonb || jsonb_build_object('event_id', e.id, 'event_name', e.name, 'event_start', e.start_time)
+		FROM events e WHERE e.id = $4
+	`
+	_, err = tx.Exec(ctx, query, kind, userID, payload, eventID)
+	return err
+}
+
+// ClaimPending leases up to limit due messages for lease. A message whose
+// lease runs out without being marked is picked up again by a later claim.
+func (r *OutboxRepository) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]OutboxMessage, error) {
+	query := `
+		WITH claimed AS (
+			UPDATE notification_outbox SET attempts = attempts + 1, next_attempt_at = NOW() + $2::interval
+			WHERE id IN (
+				SELECT id FROM notification_outbox
+				WHERE status = 'pending' AND next_attempt_at <= NOW()
+				ORDER BY next_attempt_at LIMIT $1 FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, kind, user_id, payload, attempts
+		)
+		SELECT c.id, c.kind, c.payload, c.attempts, u.email, u.name
+		FROM claimed c JOIN users u ON u.id = c.user_id
+	`
+	rows, err := r.DB.Query(ctx, query, limit, lease)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []OutboxMessage
+	for rows.Next() {
+		var msg OutboxMessage
+		if err := rows.Scan(&msg.ID, &msg.Kind, &msg.Payload, &msg.Attempts, &msg.Email, &msg.Name); err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
+func (r *OutboxRepository) MarkSent(ctx context.Context, id string) error {
+	query := `UPDATE notification_outbox SET status = 'sent', sent_at = NOW(), last_error = NULL WHERE id = $1`
+	_, err := r.DB.Exec(ctx, query, id)
+	return err
+}
+
+// MarkFailed records a failed attempt. The message is retried at nextAttempt
+// unless final is set, in which case it is parked as failed.
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id string, sendErr error, nextAttempt time.Time, final bool) error {
+	status := "pending"
+	if final {
+		status = "failed"
+	}
+	query := `UPDATE notification_outbox SET status = $2, last_error = $3, next_attempt_at = $4 WHERE id = $1`
+	_, err := r.DB.Exec(ctx, query, id, status, sendErr.Error(), nextAttempt)
+	return err
+}
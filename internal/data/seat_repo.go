@@ -0,0 +1,152 @@
+package data
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SeatRepository struct {
+	DB *pgxpool.Pool
+}
+
+const eventSeatColumns = `
+	es.seat_id, sec.id, sec.name, sec.rank, s.row_label, s.row_position, s.number, es.tier_id,
+	CASE WHEN es.booking_id IS NULL THEN 'available' ELSE 'booked' END
+`
+
+const eventSeatJoins = `
+	FROM event_seats es
+	JOIN venue_seats s ON s.id = es.seat_id
+	JOIN venue_sections sec ON sec.id = s.section_id
+`
+
+// bookingSeatsColumn lists a booking's seats, like "Stalls C12", in a query
+// over bookings b.
+const bookingSeatsColumn = `
+	ARRAY(
+		SELECT sec.name || ' ' || s.row_label || s.number` + eventSeatJoins + `
+		WHERE es.booking_id = b.id ORDER BY sec.rank, sec.name, s.row_position, s.number
+	)
+`
+
+func scanEventSeat(row pgx.Row, seat *EventSeat) error {
+	return row.Scan(
+		&seat.SeatID, &seat.SectionID, &seat.Section, &seat.SectionRank, &seat.Row, &seat.RowPosition, &seat.Number, &seat.TierID,
+		&seat.Status,
+	)
+}
+
+func queryEventSeats(ctx context.Context, q querier, query string, args ...any) ([]EventSeat, error) {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seats := []EventSeat{}
+	for rows.Next() {
+		var seat EventSeat
+		if err := scanEventSeat(rows, &seat); err != nil {
+			return nil, err
+		}
+		seats = append(seats, seat)
+	}
+	return seats, rows.Err()
+}
+
+// ListEventSeats returns every seat of the event, best sections and front
+// rows first. It is empty for events without reserved seating.
+func (r *SeatRepository) ListEventSeats(ctx context.Context, eventID string) ([]EventSeat, error) {
+	return listEventSeats(ctx, r.DB, eventID)
+}
+
+func listEventSeats(ctx context.Context, q querier, eventID string) ([]EventSeat, error) {
+	query := `SELECT ` + eventSeatColumns + eventSeatJoins + `
+		WHERE es.event_id = $1
+		ORDER BY sec.rank, sec.name, s.row_position, s.number
+	`
+	return queryEventSeats(ctx, q, query, eventID)
+}
+
+// AttachSeatMap gives the event reserved seating from the venue's seat map,
+// replacing any seat map it had, and sets its capacity to the number of
+// seats. The event takes the venue's name. sectionTiers maps section IDs to
+// the tier their seats are sold in. It returns ErrConflict once tickets have
+// been sold, held or waitlisted.
+func (r *SeatRepository) AttachSeatMap(ctx context.Context, eventID, venueID string, sectionTiers map[string]string) (int, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var taken int
+	var waitlisted bool
+	lockQuery := `
+		SELECT booked_tickets + held_tickets, EXISTS(SELECT 1 FROM waitlist_entries WHERE event_id = $1)
+		FROM events WHERE id = $1 FOR UPDATE
+	`
+	if err := tx.QueryRow(ctx, lockQuery, eventID).Scan(&taken, &waitlisted); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+	if taken > 0 || waitlisted {
+		return 0, ErrConflict
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM event_seats WHERE event_id = $1`, eventID); err != nil {
+		return 0, err
+	}
+
+	sections := make([]string, 0, len(sectionTiers))
+	tiers := make([]string, 0, len(sectionTiers))
+	for section, tier := range sectionTiers {
+		sections = append(sections, section)
+		tiers = append(tiers, tier)
+	}
+	insertQuery := `
+		INSERT INTO event_seats (event_id, seat_id, tier_id)
+		SELECT $1, s.id, m.tier_id
+		FROM venue_seats s
+		JOIN venue_sections sec ON sec.id = s.section_id
+		LEFT JOIN unnest($3::uuid[], $4::uuid[]) AS m(section_id, tier_id) ON m.section_id = sec.id
+		WHERE sec.venue_id = $2
+	`
+	tag, err := tx.Exec(ctx, insertQuery, eventID, venueID, sections, tiers)
+	if err != nil {
+		return 0, err
+	}
+	seats := int(tag.RowsAffected())
+
+	updateQuery := `
+		UPDATE events SET venue_id = $2, venue = (SELECT name FROM venues WHERE id = $2), capacity = $3,
+			version = version + 1, updated_at = NOW()
+		WHERE id = $1
+	`
+	if _, err := tx.Exec(ctx, updateQuery, eventID, venueID, seats); err != nil {
+		return 0, err
+	}
+
+	return seats, tx.Commit(ctx)
+}
+
+// releaseSeats frees up to quantity of the booking's seats, starting from
+// the back of its block.
+func releaseSeats(ctx context.Context, tx pgx.Tx, bookingID string, quantity int) error {
+	query := `
+		UPDATE event_seats SET booking_id = NULL
+		WHERE (event_id, seat_id) IN (
+			SELECT es.event_id, es.seat_id` + eventSeatJoins + `
+			WHERE es.booking_id = $1
+			ORDER BY sec.rank DESC, sec.name DESC, s.row_position DESC, s.number DESC
+			LIMIT $2
+		)
+	`
+	_, err := tx.Exec(ctx, query, bookingID, quantity)
+	return err
+}
@@ -0,0 +1,195 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SeriesRepository struct {
+	DB *pgxpool.Pool
+}
+
+const seriesColumns = `
+	id, name, venue, venue_id, capacity, waitlist_policy, waitlist_offer_minutes, timezone, starts_at, rrule,
+	ARRAY(SELECT to_char(d, 'YYYY-MM-DD') FROM unnest(exclusions) AS d ORDER BY d), to_char(generated_through, 'YYYY-MM-DD'),
+	owner_id, version, created_at, updated_at
+`
+
+func scanSeries(row pgx.Row, series *EventSeries) error {
+	return row.Scan(
+		&series.ID, &series.Name, &series.Venue, &series.VenueID, &series.Capacity, &series.WaitlistPolicy, &series.OfferMinutes,
+		&series.Timezone, &series.StartsAt, &series.RRule, &series.Exclusions, &series.GeneratedThrough,
+		&series.OwnerID, &series.Version, &series.CreatedAt, &series.UpdatedAt,
+	)
+}
+
+// Create inserts the series. Its occurrences are generated separately, in
+// the same transaction.
+func (r *SeriesRepository) Create(ctx context.Context, tx pgx.Tx, series *EventSeries) error {
+	query := `
+		INSERT INTO event_series (name, venue, venue_id, capacity, waitlist_policy, waitlist_offer_minutes, timezone, starts_at, rrule,
+			exclusions, generated_through, owner_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10::date[], $11::date, $12)
+		RETURNING id, version, created_at, updated_at
+	`
+	args := []any{
+		series.Name, series.Venue, series.VenueID, series.Capacity, series.WaitlistPolicy, series.OfferMinutes, series.Timezone,
+		series.StartsAt, series.RRule, series.Exclusions, series.GeneratedThrough, series.OwnerID,
+	}
+	return tx.QueryRow(ctx, query, args...).Scan(&series.ID, &series.Version, &series.CreatedAt, &series.UpdatedAt)
+}
+
+func (r *SeriesRepository) Get(ctx context.Context, id string) (*EventSeries, error) {
+	return getSeries(ctx, r.DB, `SELECT `+seriesColumns+` FROM event_series WHERE id = $1`, id)
+}
+
+func (r *SeriesRepository) GetForUpdate(ctx context.Context, tx pgx.Tx, id string) (*EventSeries, error) {
+	return getSeries(ctx, tx, `SELECT `+seriesColumns+` FROM event_series WHERE id = $1 FOR UPDATE`, id)
+}
+
+func getSeries(ctx context.Context, q querier, query, id string) (*EventSeries, error) {
+	var series EventSeries
+	if err := scanSeries(q.QueryRow(ctx, query, id), &series); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &series, nil
+}
+
+// Update saves the series template and how far it has been generated. The
+// caller holds the row lock, so a version mismatch is a stale client.
+func (r *SeriesRepository) Update(ctx context.Context, tx pgx.Tx, series *EventSeries) error {
+	query := `
+		UPDATE event_series SET name = $3, venue = $4, venue_id = $5, capacity = $6, waitlist_policy = $7,
+			waitlist_offer_minutes = $8, starts_at = $9, generated_through = $10::date, version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND version = $2
+		RETURNING version, updated_at
+	`
+	args := []any{
+		series.ID, series.Version, series.Name, series.Venue, series.VenueID, series.Capacity, series.WaitlistPolicy,
+		series.OfferMinutes, series.StartsAt, series.GeneratedThrough,
+	}
+	if err := tx.QueryRow(ctx, query, args...).Scan(&series.Version, &series.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrConflict
+		}
+		return err
+	}
+	return nil
+}
+
+// SetGeneratedThrough records the last local date occurrences have been
+// generated for. It does not change the version, since clients did not
+// change anything.
+func (r *SeriesRepository) SetGeneratedThrough(ctx context.Context, tx pgx.Tx, id, date string) error {
+	_, err := tx.Exec(ctx, `UPDATE event_series SET generated_through = $2::date WHERE id = $1`, id, date)
+	return err
+}
+
+// ListDue returns the series not yet generated through the date horizon
+// falls on in their own time zone.
+func (r *SeriesRepository) ListDue(ctx context.Context, horizon time.Time) ([]string, error) {
+	query := `SELECT id FROM event_series WHERE generated_through < ($1::timestamptz AT TIME ZONE timezone)::date ORDER BY generated_through`
+	rows, err := r.DB.Query(ctx, query, horizon)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// InsertOccurrence creates the series' occurrence for the local date from
+// its template, owned by the series owner. It returns nil if the date
+// already has one.
+func (r *SeriesRepository) InsertOccurrence(ctx context.Context, tx pgx.Tx, series *EventSeries, date string, start time.Time) (*Event, error) {
+	event := &Event{
+		Name:           series.Name,
+		Venue:          series.Venue,
+		VenueID:        series.VenueID,
+		StartTime:      start,
+		Capacity:       series.Capacity,
+		WaitlistPolicy: series.WaitlistPolicy,
+		OfferMinutes:   series.OfferMinutes,
+		SeriesID:       &series.ID,
+	}
+	query := `
+		INSERT INTO events (name, venue, venue_id, start_time, capacity, waitlist_policy, waitlist_offer_minutes, series_id, series_date)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9::date)
+		ON CONFLICT (series_id, series_date) DO NOTHING
+		RETURNING id, created_at, updated_at, version, booked_tickets, held_tickets, status
+	`
+	args := []any{
+		event.Name, event.Venue, event.VenueID, event.StartTime, event.Capacity, event.WaitlistPolicy, event.OfferMinutes,
+		series.ID, date,
+	}
+	err := tx.QueryRow(ctx, query, args...).Scan(&event.ID, &event.CreatedAt, &event.UpdatedAt, &event.Version, &event.BookedTickets, &event.HeldTickets, &event.Status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if series.OwnerID != nil {
+		memberQuery := `INSERT INTO event_members (event_id, user_id, role, added_by) VALUES ($1, $2, 'owner', $2)`
+		if _, err := tx.Exec(ctx, memberQuery, event.ID, *series.OwnerID); err != nil {
+			return nil, err
+		}
+	}
+	if err := EmitWebhook(ctx, tx, WebhookEventCreated, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// ListOccurrences returns the series' events in date order, those starting
+// before from left out when from is set.
+func (r *SeriesRepository) ListOccurrences(ctx context.Context, seriesID string, from *time.Time) ([]Event, error) {
+	query := `SELECT ` + eventForUpdateColumns + ` FROM events WHERE series_id = $1 AND ($2::timestamptz IS NULL OR start_time >= $2) ORDER BY series_date`
+	return queryOccurrences(ctx, r.DB, query, seriesID, from)
+}
+
+// ListFollowingForUpdate locks the series' events from the given one on,
+// in date order, leaving out cancelled ones.
+func (r *SeriesRepository) ListFollowingForUpdate(ctx context.Context, tx pgx.Tx, seriesID, eventID string) ([]Event, error) {
+	query := `
+		SELECT ` + eventForUpdateColumns + ` FROM events
+		WHERE series_id = $1 AND series_date >= (SELECT series_date FROM events WHERE id = $2) AND status <> 'cancelled'
+		ORDER BY series_date
+		FOR UPDATE
+	`
+	return queryOccurrences(ctx, tx, query, seriesID, eventID)
+}
+
+func queryOccurrences(ctx context.Context, q querier, query string, args ...any) ([]Event, error) {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		var event Event
+		if err := scanEventForUpdate(rows, &event); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
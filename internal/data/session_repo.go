@@ -0,0 +1,344 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SessionRepository struct {
+	DB *pgxpool.Pool
+}
+
+// SessionOverlapError is returned when the user is already registered for
+// a session that runs at the same time as the one they register for.
+type SessionOverlapError struct {
+	Session EventSession
+}
+
+func (e *SessionOverlapError) Error() string {
+	return "already registered for " + e.Session.Title + " at the same time"
+}
+
+const sessionColumns = `
+	s.id, s.event_id, s.title, s.room, s.starts_at, s.ends_at, s.capacity, s.registered, s.version, s.created_at, s.updated_at
+`
+
+func scanSession(row pgx.Row, session *EventSession, extra ...any) error {
+	dest := []any{
+		&session.ID, &session.EventID, &session.Title, &session.Room, &session.StartsAt, &session.EndsAt,
+		&session.Capacity, &session.Registered, &session.Version, &session.CreatedAt, &session.UpdatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	session.Available = session.Capacity - session.Registered
+	return nil
+}
+
+// ListByEvent returns the event's sessions in the order they run.
+func (r *SessionRepository) ListByEvent(ctx context.Context, eventID string) ([]EventSession, error) {
+	query := `SELECT ` + sessionColumns + ` FROM event_sessions s WHERE s.event_id = $1 ORDER BY s.starts_at, s.room, s.title`
+	rows, err := r.DB.Query(ctx, query, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []EventSession{}
+	for rows.Next() {
+		var session EventSession
+		if err := scanSession(rows, &session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// Get reads a session of the event, including its version and the status
+// of the event.
+func (r *SessionRepository) Get(ctx context.Context, eventID, id string) (*EventSession, error) {
+	var session EventSession
+	query := `SELECT ` + sessionColumns + `, e.status FROM event_sessions s JOIN events e ON e.id = s.event_id WHERE s.id = $1 AND s.event_id = $2`
+	if err := scanSession(r.DB.QueryRow(ctx, query, id, eventID), &session, &session.EventStatus); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Create inserts the session. It returns ErrNotFound if the event does not
+// exist.
+func (r *SessionRepository) Create(ctx context.Context, session *EventSession) error {
+	query := `
+		INSERT INTO event_sessions (event_id, title, room, starts_at, ends_at, capacity)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, registered, version, created_at, updated_at
+	`
+	args := []any{session.EventID, session.Title, session.Room, session.StartsAt, session.EndsAt, session.Capacity}
+	err := r.DB.QueryRow(ctx, query, args...).Scan(&session.ID, &session.Registered, &session.Version, &session.CreatedAt, &session.UpdatedAt)
+	if err != nil {
+		return sessionWriteError(err)
+	}
+	session.Available = session.Capacity - session.Registered
+	return nil
+}
+
+// Update saves the session if its version has not changed since it was
+// read, returning ErrConflict otherwise. Registrations change the version,
+// so a capacity checked against the read registrations still holds. With
+// checkOverlaps, the registered users are locked as in Register and a
+// *SessionOverlapError is returned if the new times clash with another
+// session one of them is registered for.
+func (r *SessionRepository) Update(ctx context.Context, session *EventSession, checkOverlaps bool) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if checkOverlaps {
+		lockQuery := `
+			SELECT 1 FROM users
+			WHERE id IN (SELECT user_id FROM session_registrations WHERE session_id = $1)
+			ORDER BY id FOR UPDATE
+		`
+		if _, err := tx.Exec(ctx, lockQuery, session.ID); err != nil {
+			return err
+		}
+
+		var overlap EventSession
+		overlapQuery := `
+			SELECT ` + sessionColumns + ` FROM session_registrations mine
+			JOIN session_registrations sr ON sr.user_id = mine.user_id AND sr.session_id <> mine.session_id
+			JOIN event_sessions s ON s.id = sr.session_id
+			WHERE mine.session_id = $1 AND s.starts_at < $3 AND s.ends_at > $2
+			ORDER BY s.starts_at LIMIT 1
+		`
+		err := scanSession(tx.QueryRow(ctx, overlapQuery, session.ID, session.StartsAt, session.EndsAt), &overlap)
+		if err == nil {
+			return &SessionOverlapError{Session: overlap}
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+	}
+
+	query := `
+		UPDATE event_sessions SET title = $3, room = $4, starts_at = $5, ends_at = $6, capacity = $7,
+			version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND version = $2
+		RETURNING version, updated_at
+	`
+	args := []any{session.ID, session.Version, session.Title, session.Room, session.StartsAt, session.EndsAt, session.Capacity}
+	if err := tx.QueryRow(ctx, query, args...).Scan(&session.Version, &session.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrConflict
+		}
+		return sessionWriteError(err)
+	}
+	return tx.Commit(ctx)
+}
+
+// Delete removes a session nobody is registered for. It returns
+// ErrConflict otherwise.
+func (r *SessionRepository) Delete(ctx context.Context, eventID, id string) error {
+	tag, err := r.DB.Exec(ctx, `DELETE FROM event_sessions WHERE id = $1 AND event_id = $2`, id, eventID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return ErrConflict
+		}
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func sessionWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23503":
+			return ErrNotFound
+		case "23514":
+			return ErrConflict
+		}
+	}
+	return err
+}
+
+// Register gives the user a place in the session, as read at its version.
+// Like CreateBooking it takes the place only if the version is unchanged,
+// returning ErrConflict otherwise. The user's row is locked first so their
+// registrations are made one at a time and the overlap check holds. It
+// returns ErrNotFound if the user holds no confirmed booking for the
+// event, ErrDuplicate if they are already registered and a
+// *SessionOverlapError if another of their sessions runs at the same time.
+func (r *SessionRepository) Register(ctx context.Context, session *EventSession, userID string) (*SessionRegistration, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		return nil, err
+	}
+
+	var bookingID string
+	bookingQuery := `
+		SELECT id FROM bookings WHERE user_id = $1 AND event_id = $2 AND status = 'confirmed'
+		ORDER BY created_at LIMIT 1
+	`
+	if err := tx.QueryRow(ctx, bookingQuery, userID, session.EventID).Scan(&bookingID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	var overlap EventSession
+	overlapQuery := `
+		SELECT ` + sessionColumns + ` FROM session_registrations sr
+		JOIN event_sessions s ON s.id = sr.session_id
+		WHERE sr.user_id = $1 AND s.id <> $2 AND s.starts_at < $4 AND s.ends_at > $3
+		ORDER BY s.starts_at LIMIT 1
+	`
+	err = scanSession(tx.QueryRow(ctx, overlapQuery, userID, session.ID, session.StartsAt, session.EndsAt), &overlap)
+	if err == nil {
+		return nil, &SessionOverlapError{Session: overlap}
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	updateQuery := `
+		UPDATE event_sessions SET registered = registered + 1, version = version + 1
+		WHERE id = $1 AND version = $2
+	`
+	tag, err := tx.Exec(ctx, updateQuery, session.ID, session.Version)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrConflict
+	}
+
+	registration := &SessionRegistration{
+		SessionID: session.ID,
+		EventID:   session.EventID,
+		Title:     session.Title,
+		Room:      session.Room,
+		StartsAt:  session.StartsAt,
+		EndsAt:    session.EndsAt,
+	}
+	insertQuery := `
+		INSERT INTO session_registrations (session_id, user_id, booking_id) VALUES ($1, $2, $3)
+		RETURNING id, created_at, (SELECT name FROM events WHERE id = $4)
+	`
+	err = tx.QueryRow(ctx, insertQuery, session.ID, userID, bookingID, session.EventID).
+		Scan(&registration.ID, &registration.CreatedAt, &registration.EventName)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrDuplicate
+		}
+		return nil, err
+	}
+	return registration, tx.Commit(ctx)
+}
+
+// Unregister gives up the user's place in the session.
+func (r *SessionRepository) Unregister(ctx context.Context, eventID, sessionID, userID string) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	deleteQuery := `
+		DELETE FROM session_registrations sr USING event_sessions s
+		WHERE sr.session_id = s.id AND s.id = $1 AND s.event_id = $2 AND sr.user_id = $3
+	`
+	tag, err := tx.Exec(ctx, deleteQuery, sessionID, eventID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	updateQuery := `UPDATE event_sessions SET registered = registered - 1, version = version + 1, updated_at = NOW() WHERE id = $1`
+	if _, err := tx.Exec(ctx, updateQuery, sessionID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ListUserAgenda returns the sessions the user is registered for that end
+// after from, in the order they run. Sessions of cancelled events are left
+// out.
+func (r *SessionRepository) ListUserAgenda(ctx context.Context, userID string, from time.Time) ([]SessionRegistration, error) {
+	query := `
+		SELECT sr.id, s.id, s.event_id, e.name, s.title, s.room, s.starts_at, s.ends_at, sr.created_at
+		FROM session_registrations sr
+		JOIN event_sessions s ON s.id = sr.session_id
+		JOIN events e ON e.id = s.event_id
+		WHERE sr.user_id = $1 AND s.ends_at > $2 AND e.status <> 'cancelled'
+		ORDER BY s.starts_at, s.title
+	`
+	rows, err := r.DB.Query(ctx, query, userID, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	agenda := []SessionRegistration{}
+	for rows.Next() {
+		var reg SessionRegistration
+		err := rows.Scan(&reg.ID, &reg.SessionID, &reg.EventID, &reg.EventName, &reg.Title, &reg.Room, &reg.StartsAt, &reg.EndsAt, &reg.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		agenda = append(agenda, reg)
+	}
+	return agenda, rows.Err()
+}
+
+// releaseRegistrations runs when a booking is cancelled in full. Its
+// session registrations move to another confirmed booking the user holds
+// for the event, or are given up when there is none.
+func releaseRegistrations(ctx context.Context, tx pgx.Tx, bookingID string) error {
+	moveQuery := `
+		UPDATE session_registrations SET booking_id = other.id
+		FROM bookings b
+		JOIN LATERAL (
+			SELECT o.id FROM bookings o
+			WHERE o.user_id = b.user_id AND o.event_id = b.event_id AND o.id <> b.id AND o.status = 'confirmed'
+			ORDER BY o.created_at LIMIT 1
+		) other ON true
+		WHERE b.id = $1 AND session_registrations.booking_id = $1
+	`
+	if _, err := tx.Exec(ctx, moveQuery, bookingID); err != nil {
+		return err
+	}
+	releaseQuery := `
+		WITH released AS (
+			DELETE FROM session_registrations WHERE booking_id = $1 RETURNING session_id
+		)
+		UPDATE event_sessions s SET registered = registered - r.n, version = version + 1, updated_at = NOW()
+		FROM (SELECT session_id, COUNT(*) AS n FROM released GROUP BY session_id) r
+		WHERE s.id = r.session_id
+	`
+	_, err := tx.Exec(ctx, releaseQuery, bookingID)
+	return err
+}
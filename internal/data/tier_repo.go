@@ -0,0 +1,153 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TierRepository struct {
+	DB *pgxpool.Pool
+}
+
+const tierColumns = `
+	id, event_id, name, description, price_cents, currency, quantity, sold, held,
+	sales_start, sales_end, position, version, created_at, updated_at
+`
+
+func scanTier(row pgx.Row, tier *TicketTier) error {
+	return row.Scan(
+		&tier.ID, &tier.EventID, &tier.Name, &tier.Description, &tier.PriceCents, &tier.Currency, &tier.Quantity, &tier.Sold, &tier.Held,
+		&tier.SalesStart, &tier.SalesEnd, &tier.Position, &tier.Version, &tier.CreatedAt, &tier.UpdatedAt,
+	)
+}
+
+func (r *TierRepository) ListByEvent(ctx context.Context, eventID string) ([]TicketTier, error) {
+	return listTiers(ctx, r.DB, eventID)
+}
+
+func listTiers(ctx context.Context, q querier, eventID string) ([]TicketTier, error) {
+	rows, err := q.Query(ctx, `SELECT `+tierColumns+` FROM ticket_tiers WHERE event_id = $1 ORDER BY position, price_cents, name`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tiers := []TicketTier{}
+	for rows.Next() {
+		var tier TicketTier
+		if err := scanTier(rows, &tier); err != nil {
+			return nil, err
+		}
+		tiers = append(tiers, tier)
+	}
+	return tiers, rows.Err()
+}
+
+func (r *TierRepository) Get(ctx context.Context, eventID, id string) (*TicketTier, error) {
+	return getTier(ctx, r.DB, eventID, id)
+}
+
+func getTier(ctx context.Context, q querier, eventID, id string) (*TicketTier, error) {
+	var tier TicketTier
+	err := scanTier(q.QueryRow(ctx, `SELECT `+tierColumns+` FROM ticket_tiers WHERE id = $1 AND event_id = $2`, id, eventID), &tier)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &tier, nil
+}
+
+// Create adds a tier to an event. It returns ErrNotFound if the event does
+// not exist and ErrDuplicate if the event already has a tier with the name.
+func (r *TierRepository) Create(ctx context.Context, tier *TicketTier) error {
+	query := `
+		INSERT INTO ticket_tiers (event_id, name, description, price_cents, currency, quantity, sales_start, sales_end, position)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, sold, held, version, created_at, updated_at
+	`
+	args := []any{tier.EventID, tier.Name, tier.Description, tier.PriceCents, tier.Currency, tier.Quantity, tier.SalesStart, tier.SalesEnd, tier.Position}
+	err := r.DB.QueryRow(ctx, query, args...).Scan(&tier.ID, &tier.Sold, &tier.Held, &tier.Version, &tier.CreatedAt, &tier.UpdatedAt)
+	return tierWriteError(err)
+}
+
+// Update saves the tier's editable fields if its version has not changed
+// since it was read. Lowering the quantity below what is already sold or
+// held returns ErrConflict, as does a stale version.
+func (r *TierRepository) Update(ctx context.Context, tier *TicketTier) error {
+	query := `
+		UPDATE ticket_tiers SET name = $4, description = $5, price_cents = $6, currency = $7, quantity = $8,
+			sales_start = $9, sales_end = $10, position = $11, version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND event_id = $2 AND version = $3
+		RETURNING sold, held, version, updated_at
+	`
+	args := []any{
+		tier.ID, tier.EventID, tier.Version, tier.Name, tier.Description, tier.PriceCents, tier.Currency, tier.Quantity,
+		tier.SalesStart, tier.SalesEnd, tier.Position,
+	}
+	err := r.DB.QueryRow(ctx, query, args...).Scan(&tier.Sold, &tier.Held, &tier.Version, &tier.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrConflict
+	}
+	return tierWriteError(err)
+}
+
+// Delete removes a tier nobody has bought or holds. It returns ErrConflict
+// when bookings, holds or offers refer to it.
+func (r *TierRepository) Delete(ctx context.Context, eventID, id string) error {
+	tag, err := r.DB.Exec(ctx, `DELETE FROM ticket_tiers WHERE id = $1 AND event_id = $2`, id, eventID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return ErrConflict
+		}
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func tierWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23503":
+			return ErrNotFound
+		case "23505":
+			return ErrDuplicate
+		case "23514":
+			return ErrConflict
+		}
+	}
+	return err
+}
+
+// adjustTier moves a tier's sold and held counters alongside the event's.
+// It does nothing for tickets without a tier.
+func adjustTier(ctx context.Context, tx pgx.Tx, tierID *string, soldDelta, heldDelta int) error {
+	if tierID == nil {
+		return nil
+	}
+	query := `UPDATE ticket_tiers SET sold = sold + $2, held = held + $3, version = version + 1 WHERE id = $1`
+	_, err := tx.Exec(ctx, query, *tierID, soldDelta, heldDelta)
+	return err
+}
+
+// annotateTiers sets each tier's Available and OnSale for display with its
+// event.
+func annotateTiers(event *Event, now time.Time) {
+	eventAvailable := event.Capacity - event.BookedTickets - event.HeldTickets
+	for i := range event.Tiers {
+		tier := &event.Tiers[i]
+		tier.Available = max(0, min(tier.Remaining(), eventAvailable))
+		tier.OnSale = event.Status == EventStatusScheduled && tier.SellingAt(now)
+	}
+}
@@ -0,0 +1,165 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	RevokedLogout    = "logout"
+	RevokedLogoutAll = "logout_all"
+	RevokedReuse     = "reuse"
+	RevokedPassword  = "password_reset"
+	RevokedDisabled  = "account_disabled"
+	RevokedRole      = "role_changed"
+)
+
+type TokenRepository struct {
+	DB *pgxpool.Pool
+}
+
+// RefreshToken is a stored refresh token joined with the state of its family
+// and the owning user's current role.
+type RefreshToken struct {
+	ID              string
+	FamilyID        string
+	UserID          string
+	Role            string
+	ExpiresAt       time.Time
+	UsedAt          *time.Time
+	FamilyRevokedAt *time.Time
+	SSO             bool
+	MFA             bool
+}
+
+// CreateFamily starts a new login session for userID. sso records whether the
+// user signed in through the identity provider, and mfa whether the login
+// passed a second factor.
+func (r *TokenRepository) CreateFamily(ctx context.Context, tx pgx.Tx, userID string, sso, mfa bool) (string, error) {
+	var familyID string
+	query := `INSERT INTO token_families (user_id, sso, mfa) VALUES ($1, $2, $3) RETURNING id`
+	err := tx.QueryRow(ctx, query, userID, sso, mfa).Scan(&familyID)
+	return familyID, err
+}
+
+func (r *TokenRepository) CreateRefreshToken(ctx context.Context, tx pgx.Tx, familyID, tokenHash string, expiresAt time.Time) error {
+	query := `INSERT INTO refresh_tokens (family_id, token_hash, expires_at) VALUES ($1, $2, $3)`
+	_, err := tx.Exec(ctx, query, familyID, tokenHash, expiresAt)
+	return err
+}
+
+// GetRefreshTokenForUpdate looks a token up by its hash and locks it so two
+// concurrent refreshes with the same token cannot both rotate it.
+func (r *TokenRepository) GetRefreshTokenForUpdate(ctx context.Context, tx pgx.Tx, tokenHash string) (*RefreshToken, error) {
+	query := `
+		SELECT rt.id, rt.family_id, f.user_id, u.role, rt.expires_at, rt.used_at, f.revoked_at, f.sso, f.mfa
+		FROM refresh_tokens rt
+		JOIN token_families f ON f.id = rt.family_id
+		JOIN users u ON u.id = f.user_id
+		WHERE rt.token_hash = $1
+		FOR UPDATE OF rt
+	`
+	var token RefreshToken
+	err := tx.QueryRow(ctx, query, tokenHash).Scan(&token.ID, &token.FamilyID, &token.UserID, &token.Role, &token.ExpiresAt, &token.UsedAt, &token.FamilyRevokedAt, &token.SSO, &token.MFA)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *TokenRepository) MarkRefreshTokenUsed(ctx context.Context, tx pgx.Tx, id string) error {
+	_, err := tx.Exec(ctx, `UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1`, id)
+	return err
+}
+
+func (r *TokenRepository) RevokeFamily(ctx context.Context, tx pgx.Tx, familyID, reason string) error {
+	query := `UPDATE token_families SET revoked_at = NOW(), revoked_reason = $2 WHERE id = $1 AND revoked_at IS NULL`
+	_, err := tx.Exec(ctx, query, familyID, reason)
+	return err
+}
+
+// RevokeFamilyByToken ends the session the refresh token belongs to. Unknown
+// tokens are ignored so logout never reveals whether a token existed.
+func (r *TokenRepository) RevokeFamilyByToken(ctx context.Context, tokenHash, reason string) error {
+	query := `
+		UPDATE token_families SET revoked_at = NOW(), revoked_reason = $2
+		WHERE id = (SELECT family_id FROM refresh_tokens WHERE token_hash = $1) AND revoked_at IS NULL
+	`
+	_, err := r.DB.Exec(ctx, query, tokenHash, reason)
+	return err
+}
+
+// RevokeUserFamilies ends every session of userID and returns how many were
+// still active.
+func (r *TokenRepository) RevokeUserFamilies(ctx context.Context, tx pgx.Tx, userID, reason string) (int64, error) {
+	query := `UPDATE token_families SET revoked_at = NOW(), revoked_reason = $2 WHERE user_id = $1 AND revoked_at IS NULL`
+	tag, err := tx.Exec(ctx, query, userID, reason)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// RevokeOtherFamilies ends every session of userID except keepFamilyID.
+func (r *TokenRepository) RevokeOtherFamilies(ctx context.Context, userID, keepFamilyID, reason string) (int64, error) {
+	query := `
+		UPDATE token_families SET revoked_at = NOW(), revoked_reason = $3
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+	`
+	tag, err := r.DB.Exec(ctx, query, userID, keepFamilyID, reason)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// IsSessionRevoked reports whether access tokens for the session must be
+// rejected. A session that no longer exists, or whose user has been
+// disabled, counts as revoked.
+func (r *TokenRepository) IsSessionRevoked(ctx context.Context, familyID string) (bool, error) {
+	var revoked bool
+	query := `
+		SELECT f.revoked_at IS NOT NULL OR u.disabled_at IS NOT NULL
+		FROM token_families f JOIN users u ON u.id = f.user_id
+		WHERE f.id = $1
+	`
+	err := r.DB.QueryRow(ctx, query, familyID).Scan(&revoked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return true, nil
+	}
+	return revoked, err
+}
+
+// IsSSOSession reports whether familyID is a live session of userID that
+// was started by signing in through the identity provider.
+func (r *TokenRepository) IsSSOSession(ctx context.Context, familyID, userID string) (bool, error) {
+	var sso bool
+	query := `SELECT sso FROM token_families WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+	err := r.DB.QueryRow(ctx, query, familyID, userID).Scan(&sso)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return sso, err
+}
+
+// DeleteExpired removes sessions whose newest refresh token expired before
+// cutoff, along with their tokens.
+func (r *TokenRepository) DeleteExpired(ctx context.Context, cutoff time.Time) (int64, error) {
+	query := `
+		DELETE FROM token_families f
+		WHERE NOT EXISTS (SELECT 1 FROM refresh_tokens rt WHERE rt.family_id = f.id AND rt.expires_at >= $1)
+		  AND f.created_at < $1
+	`
+	tag, err := r.DB.Exec(ctx, query, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,166 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TwoFactorRepository struct {
+	DB *pgxpool.Pool
+}
+
+// TOTPState is a user's second-factor enrolment. Secret is empty when the
+// user has never started enrolment.
+type TOTPState struct {
+	Email     string
+	Secret    string
+	EnabledAt *time.Time
+}
+
+// LoginChallenge is a pending second-factor step joined with its user.
+type LoginChallenge struct {
+	ID        string
+	UserID    string
+	Email     string
+	Role      string
+	ExpiresAt time.Time
+	Attempts  int
+	UsedAt    *time.Time
+	SSO       bool
+}
+
+func (r *TwoFactorRepository) GetTOTP(ctx context.Context, userID string) (*TOTPState, error) {
+	var state TOTPState
+	var secret *string
+	query := `SELECT email, totp_secret, totp_enabled_at FROM users WHERE id = $1`
+	err := r.DB.QueryRow(ctx, query, userID).Scan(&state.Email, &secret, &state.EnabledAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if secret != nil {
+		state.Secret = *secret
+	}
+	return &state, nil
+}
+
+// SetPendingSecret starts (or restarts) enrolment. It returns ErrConflict if
+// two-factor is already enabled.
+func (r *TwoFactorRepository) SetPendingSecret(ctx context.Context, userID, secret string) error {
+	query := `UPDATE users SET totp_secret = $2, updated_at = NOW() WHERE id = $1 AND totp_enabled_at IS NULL`
+	tag, err := r.DB.Exec(ctx, query, userID, secret)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+// Enable confirms enrolment, recording step as already used.
+func (r *TwoFactorRepository) Enable(ctx context.Context, tx pgx.Tx, userID string, step int64) error {
+	query := `
+		UPDATE users SET totp_enabled_at = NOW(), totp_last_step = $2, updated_at = NOW()
+		WHERE id = $1 AND totp_enabled_at IS NULL AND totp_secret IS NOT NULL
+	`
+	tag, err := tx.Exec(ctx, query, userID, step)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+// UseStep records step as the latest accepted code. It reports false if a
+// code from this or a later step was already accepted.
+func (r *TwoFactorRepository) UseStep(ctx context.Context, tx pgx.Tx, userID string, step int64) (bool, error) {
+	query := `
+		UPDATE users SET totp_last_step = $2
+		WHERE id = $1 AND (totp_last_step IS NULL OR totp_last_step < $2)
+	`
+	tag, err := tx.Exec(ctx, query, userID, step)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ReplaceRecoveryCodes discards any existing codes and stores the new hashes.
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID string, hashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	query := `INSERT INTO recovery_codes (user_id, code_hash) SELECT $1, unnest($2::text[])`
+	_, err := tx.Exec(ctx, query, userID, hashes)
+	return err
+}
+
+// UseRecoveryCode spends a recovery code and reports whether it was valid.
+func (r *TwoFactorRepository) UseRecoveryCode(ctx context.Context, tx pgx.Tx, userID, hash string) (bool, error) {
+	query := `UPDATE recovery_codes SET used_at = NOW() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+	tag, err := tx.Exec(ctx, query, userID, hash)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *TwoFactorRepository) RemainingRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	var remaining int
+	err := r.DB.QueryRow(ctx, `SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL`, userID).Scan(&remaining)
+	return remaining, err
+}
+
+// CreateChallenge stores a pending second-factor step. sso records that
+// the first factor was a sign-in at the identity provider.
+func (r *TwoFactorRepository) CreateChallenge(ctx context.Context, userID, tokenHash string, sso bool, expiresAt time.Time) error {
+	query := `INSERT INTO login_challenges (user_id, token_hash, sso, expires_at) VALUES ($1, $2, $3, $4)`
+	_, err := r.DB.Exec(ctx, query, userID, tokenHash, sso, expiresAt)
+	return err
+}
+
+func (r *TwoFactorRepository) GetChallengeForUpdate(ctx context.Context, tx pgx.Tx, tokenHash string) (*LoginChallenge, error) {
+	query := `
+		SELECT c.id, c.user_id, u.email, u.role, c.expires_at, c.attempts, c.used_at, c.sso
+		FROM login_challenges c JOIN users u ON u.id = c.user_id
+		WHERE c.token_hash = $1 AND u.disabled_at IS NULL
+		FOR UPDATE OF c
+	`
+	var c LoginChallenge
+	err := tx.QueryRow(ctx, query, tokenHash).Scan(&c.ID, &c.UserID, &c.Email, &c.Role, &c.ExpiresAt, &c.Attempts, &c.UsedAt, &c.SSO)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *TwoFactorRepository) FailChallenge(ctx context.Context, tx pgx.Tx, id string) error {
+	_, err := tx.Exec(ctx, `UPDATE login_challenges SET attempts = attempts + 1 WHERE id = $1`, id)
+	return err
+}
+
+func (r *TwoFactorRepository) ConsumeChallenge(ctx context.Context, tx pgx.Tx, id string) error {
+	_, err := tx.Exec(ctx, `UPDATE login_challenges SET used_at = NOW() WHERE id = $1`, id)
+	return err
+}
+
+// DeleteExpiredChallenges removes challenges that can no longer be used.
+func (r *TwoFactorRepository) DeleteExpiredChallenges(ctx context.Context) (int64, error) {
+	tag, err := r.DB.Exec(ctx, `DELETE FROM login_challenges WHERE expires_at < NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -3,8 +3,12 @@ package data
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -21,11 +25,52 @@ func (r *UserRepository) Create(ctx context.Context, user *User) error {
 	return r.DB.QueryRow(ctx, query, user.Name, user.Email, user.PasswordHash, user.Role).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
 }
 
+// CreateVerified inserts a user whose email address is already known to be
+// theirs, such as one vouched for by a single sign-on provider.
+func (r *UserRepository) CreateVerified(ctx context.Context, tx pgx.Tx, user *User) error {
+	query := `
+		INSERT INTO users (name, email, password_hash, role, email_verified_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		RETURNING id, email_verified_at, created_at, updated_at
+	`
+	err := tx.QueryRow(ctx, query, user.Name, user.Email, user.PasswordHash, user.Role).
+		Scan(&user.ID, &user.EmailVerifiedAt, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrDuplicate
+		}
+		return err
+	}
+	return nil
+}
+
+const userColumns = `
+	id, name, email, password_hash, role, email_verified_at, totp_enabled_at IS NOT NULL,
+	disabled_at, password_reset_required, created_at, updated_at
+`
+
+func scanUser(row pgx.Row, user *User) error {
+	return row.Scan(
+		&user.ID, &user.Name, &user.Email, &user.PasswordHash, &user.Role, &user.EmailVerifiedAt, &user.TwoFactorEnabled,
+		&user.DisabledAt, &user.PasswordResetRequired, &user.CreatedAt, &user.UpdatedAt,
+	)
+}
+
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
-	query := `SELECT id, name, email, password_hash, role FROM users WHERE email = $1`
 	var user User
-	err := r.DB.QueryRow(ctx, query, email).Scan(&user.ID, &user.Name, &user.Email, &user.PasswordHash, &user.Role)
-	if err != nil {
+	if err := scanUser(r.DB.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE email = $1`, email), &user); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id string) (*User, error) {
+	var user User
+	if err := scanUser(r.DB.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, id), &user); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
 		}
@@ -33,3 +78,232 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*User, e
 	}
 	return &user, nil
 }
+
+type UserFilter struct {
+	// Search matches part of the name or email.
+	Search string
+	Role   string
+	Limit  int
+	After  *UserCursor
+}
+
+type UserCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"i"`
+}
+
+// List returns users newest first.
+func (r *UserRepository) List(ctx context.Context, filter UserFilter) ([]User, error) {
+	var conditions []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Search != "" {
+		pattern := arg("%" + escapeLike(filter.Search) + "%")
+		conditions = append(conditions, fmt.Sprintf("(name ILIKE %s OR email ILIKE %s)", pattern, pattern))
+	}
+	if filter.Role != "" {
+		conditions = append(conditions, fmt.Sprintf("role::text = %s", arg(filter.Role)))
+	}
+	if filter.After != nil {
+		conditions = append(conditions, fmt.Sprintf("(created_at, id) < (%s, %s)", arg(filter.After.CreatedAt), arg(filter.After.ID)))
+	}
+
+	query := `SELECT ` + userColumns + ` FROM users`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT " + arg(filter.Limit)
+
+	rows, err := r.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		var user User
+		if err := scanUser(rows, &user); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+func (r *UserRepository) SetRole(ctx context.Context, tx pgx.Tx, id, role string) error {
+	tag, err := tx.Exec(ctx, `UPDATE users SET role = $2, updated_at = NOW() WHERE id = $1`, id, role)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetDisabled disables or re-enables the account. Disabling an already
+// disabled account keeps the original timestamp.
+func (r *UserRepository) SetDisabled(ctx context.Context, tx pgx.Tx, id string, disabled bool) error {
+	query := `
+		UPDATE users SET
+			disabled_at = CASE WHEN $2 THEN COALESCE(disabled_at, NOW()) END,
+			updated_at = NOW()
+		WHERE id = $1
+	`
+	tag, err := tx.Exec(ctx, query, id, disabled)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *UserRepository) RequirePasswordReset(ctx context.Context, tx pgx.Tx, id string) error {
+	tag, err := tx.Exec(ctx, `UPDATE users SET password_reset_required = TRUE, updated_at = NOW() WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *UserRepository) IsEmailVerified(ctx context.Context, id string) (bool, error) {
+	var verified bool
+	err := r.DB.QueryRow(ctx, `SELECT email_verified_at IS NOT NULL FROM users WHERE id = $1`, id).Scan(&verified)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, ErrNotFound
+	}
+	return verified, err
+}
+
+// CreateToken stores a new single-use token and invalidates any earlier
+// unused token with the same purpose, so only the latest email works.
+func (r *UserRepository) CreateToken(ctx context.Context, userID, purpose, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	invalidateQuery := `UPDATE user_tokens SET used_at = NOW() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`
+	if _, err := tx.Exec(ctx, invalidateQuery, userID, purpose); err != nil {
+		return err
+	}
+	insertQuery := `INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at) VALUES ($1, $2, $3, $4)`
+	if _, err := tx.Exec(ctx, insertQuery, userID, purpose, tokenHash, expiresAt); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ConsumeToken spends a valid token and returns its user. Unknown, expired
+// and already used tokens all return ErrNotFound.
+func (r *UserRepository) ConsumeToken(ctx context.Context, tx pgx.Tx, purpose, tokenHash string) (string, error) {
+	query := `
+		UPDATE user_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id
+	`
+	var userID string
+	err := tx.QueryRow(ctx, query, tokenHash, purpose).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	return userID, err
+}
+
+func (r *UserRepository) SetPassword(ctx context.Context, tx pgx.Tx, userID, passwordHash string) error {
+	query := `UPDATE users SET password_hash = $2, password_reset_required = FALSE, updated_at = NOW() WHERE id = $1`
+	_, err := tx.Exec(ctx, query, userID, passwordHash)
+	return err
+}
+
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, tx pgx.Tx, userID string) error {
+	query := `UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW() WHERE id = $1`
+	_, err := tx.Exec(ctx, query, userID)
+	return err
+}
+
+// UpdateProfile changes the user's name and email. A new email address must
+// be verified again. It returns ErrDuplicate if the email is taken.
+func (r *UserRepository) UpdateProfile(ctx context.Context, user *User) error {
+	query := `
+		UPDATE users SET
+			name = $2,
+			email_verified_at = CASE WHEN email = $3 THEN email_verified_at END,
+			email = $3,
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING email_verified_at, updated_at
+	`
+	err := r.DB.QueryRow(ctx, query, user.ID, user.Name, user.Email).Scan(&user.EmailVerifiedAt, &user.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrDuplicate
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Anonymize erases the user's personal data and everything that only exists
+// for them, and blocks the account from being used again. Bookings are kept,
+// attached to the anonymized user, so event counters stay correct; the
+// caller must release holds and offers first.
+func (r *UserRepository) Anonymize(ctx context.Context, tx pgx.Tx, userID string) error {
+	var email string
+	if err := tx.QueryRow(ctx, `SELECT email FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, userID).Scan(&email); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	statements := []string{
+		`DELETE FROM waitlist_entries WHERE user_id = $1`,
+		`DELETE FROM token_families WHERE user_id = $1`,
+		`DELETE FROM user_tokens WHERE user_id = $1`,
+		`DELETE FROM recovery_codes WHERE user_id = $1`,
+		`DELETE FROM login_challenges WHERE user_id = $1`,
+		`DELETE FROM event_members WHERE user_id = $1`,
+		`DELETE FROM notification_outbox WHERE user_id = $1`,
+		`DELETE FROM idempotency_keys WHERE user_id = $1`,
+		`DELETE FROM api_keys WHERE user_id = $1`,
+		`DELETE FROM user_identities WHERE user_id = $1`,
+		`DELETE FROM oidc_logins WHERE user_id = $1`,
+		`UPDATE users SET
+			name = 'Deleted user',
+			email = 'deleted-' || id || '@invalid',
+			password_hash = '',
+			email_verified_at = NULL,
+			totp_secret = NULL,
+			totp_enabled_at = NULL,
+			totp_last_step = NULL,
+			password_reset_required = FALSE,
+			disabled_at = COALESCE(disabled_at, NOW()),
+			deleted_at = NOW(),
+			updated_at = NOW()
+		WHERE id = $1`,
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(ctx, stmt, userID); err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.Exec(ctx, `DELETE FROM login_failures WHERE scope = $1 AND key = LOWER($2)`, LoginScopeEmail, email)
+	return err
+}
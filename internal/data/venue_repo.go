@@ -0,0 +1,300 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type VenueRepository struct {
+	DB *pgxpool.Pool
+}
+
+const venueColumns = `
+	v.id, v.name, v.address, v.timezone, v.default_capacity, v.latitude, v.longitude, v.version, v.created_at, v.updated_at
+`
+
+func scanVenue(row pgx.Row, venue *Venue, extra ...any) error {
+	dest := []any{
+		&venue.ID, &venue.Name, &venue.Address, &venue.Timezone, &venue.DefaultCapacity, &venue.Latitude, &venue.Longitude,
+		&venue.Version, &venue.CreatedAt, &venue.UpdatedAt,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// VenueFilter narrows a venue listing, which is ordered by name.
+type VenueFilter struct {
+	Search string
+	After  *VenueCursor
+	Limit  int
+}
+
+// VenueCursor is the keyset position of the last venue on a page.
+type VenueCursor struct {
+	Name string `json:"n"`
+	ID   string `json:"i"`
+}
+
+// Create inserts the venue with its seat map. Seat IDs are filled in on
+// the given venue. It returns ErrDuplicate if another venue has the same
+// name, ignoring case, punctuation and spacing, if two sections share a
+// name or if a row repeats a seat number.
+func (r *VenueRepository) Create(ctx context.Context, venue *Venue) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO venues (name, address, timezone, default_capacity, latitude, longitude)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, version, created_at, updated_at
+	`
+	args := []any{venue.Name, venue.Address, venue.Timezone, venue.DefaultCapacity, venue.Latitude, venue.Longitude}
+	err = tx.QueryRow(ctx, query, args...).Scan(&venue.ID, &venue.Version, &venue.CreatedAt, &venue.UpdatedAt)
+	if err != nil {
+		return venueWriteError(err)
+	}
+
+	for i := range venue.Sections {
+		section := &venue.Sections[i]
+		sectionQuery := `INSERT INTO venue_sections (venue_id, name, rank) VALUES ($1, $2, $3) RETURNING id`
+		if err := tx.QueryRow(ctx, sectionQuery, venue.ID, section.Name, section.Rank).Scan(&section.ID); err != nil {
+			return venueWriteError(err)
+		}
+		for position := range section.Rows {
+			if err := insertSeatRow(ctx, tx, section.ID, position, &section.Rows[position]); err != nil {
+				return venueWriteError(err)
+			}
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func insertSeatRow(ctx context.Context, tx pgx.Tx, sectionID string, position int, row *SeatRow) error {
+	numbers := make([]int, len(row.Seats))
+	for i, seat := range row.Seats {
+		numbers[i] = seat.Number
+	}
+	query := `
+		INSERT INTO venue_seats (section_id, row_label, row_position, number)
+		SELECT $1, $2, $3, n FROM unnest($4::int[]) WITH ORDINALITY AS s(n, i) ORDER BY i
+		RETURNING id, number
+	`
+	rows, err := tx.Query(ctx, query, sectionID, row.Label, position, numbers)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	ids := make(map[int]string, len(numbers))
+	for rows.Next() {
+		var id string
+		var number int
+		if err := rows.Scan(&id, &number); err != nil {
+			return err
+		}
+		ids[number] = id
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for i := range row.Seats {
+		row.Seats[i].ID = ids[row.Seats[i].Number]
+	}
+	return nil
+}
+
+func venueWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrDuplicate
+	}
+	return err
+}
+
+// Get returns the venue with its seat map and how many events it has.
+func (r *VenueRepository) Get(ctx context.Context, id string) (*Venue, error) {
+	venue, err := getVenue(ctx, r.DB, id)
+	if err != nil {
+		return nil, err
+	}
+	venue.Sections, err = r.seatMap(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return venue, nil
+}
+
+// GetDetails returns the venue without its seat map.
+func (r *VenueRepository) GetDetails(ctx context.Context, id string) (*Venue, error) {
+	return getVenue(ctx, r.DB, id)
+}
+
+func getVenue(ctx context.Context, q querier, id string) (*Venue, error) {
+	var venue Venue
+	var events int
+	query := `SELECT ` + venueColumns + `, (SELECT COUNT(*) FROM events WHERE venue_id = v.id) FROM venues v WHERE v.id = $1`
+	if err := scanVenue(q.QueryRow(ctx, query, id), &venue, &events); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	venue.EventCount = &events
+	return &venue, nil
+}
+
+// FindByName returns the venue whose name matches, ignoring case,
+// punctuation and spacing, or ErrNotFound.
+func (r *VenueRepository) FindByName(ctx context.Context, name string) (*Venue, error) {
+	var venue Venue
+	query := `SELECT ` + venueColumns + ` FROM venues v WHERE venue_key(v.name) = venue_key($1) ORDER BY v.created_at, v.id LIMIT 1`
+	if err := scanVenue(r.DB.QueryRow(ctx, query, name), &venue); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &venue, nil
+}
+
+func (r *VenueRepository) List(ctx context.Context, filter VenueFilter) ([]Venue, error) {
+	var conditions []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Search != "" {
+		pattern := arg("%" + escapeLike(filter.Search) + "%")
+		conditions = append(conditions, fmt.Sprintf("(v.name ILIKE %s OR v.address ILIKE %s)", pattern, pattern))
+	}
+	if filter.After != nil {
+		conditions = append(conditions, fmt.Sprintf("(v.name, v.id) > (%s, %s)", arg(filter.After.Name), arg(filter.After.ID)))
+	}
+
+	query := `SELECT ` + venueColumns + `, (SELECT COUNT(*) FROM events WHERE venue_id = v.id) FROM venues v`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY v.name, v.id LIMIT " + arg(filter.Limit)
+
+	rows, err := r.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	venues := []Venue{}
+	for rows.Next() {
+		var venue Venue
+		var events int
+		if err := scanVenue(rows, &venue, &events); err != nil {
+			return nil, err
+		}
+		venue.EventCount = &events
+		venues = append(venues, venue)
+	}
+	return venues, rows.Err()
+}
+
+// Update saves the venue's details if its version has not changed since it
+// was read, returning ErrConflict otherwise. Events at the venue take its
+// new name and a new version. It returns ErrDuplicate if another venue has
+// the same name.
+func (r *VenueRepository) Update(ctx context.Context, venue *Venue) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE venues SET name = $3, address = $4, timezone = $5, default_capacity = $6, latitude = $7, longitude = $8,
+			version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND version = $2
+		RETURNING version, updated_at
+	`
+	args := []any{venue.ID, venue.Version, venue.Name, venue.Address, venue.Timezone, venue.DefaultCapacity, venue.Latitude, venue.Longitude}
+	if err := tx.QueryRow(ctx, query, args...).Scan(&venue.Version, &venue.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrConflict
+		}
+		return venueWriteError(err)
+	}
+	// The rename is an edit of each event, so clients holding an event's
+	// old version must re-read it.
+	renameQuery := `
+		UPDATE events SET venue = $2, version = version + 1, updated_at = NOW()
+		WHERE venue_id = $1 AND venue <> $2
+	`
+	if _, err := tx.Exec(ctx, renameQuery, venue.ID, venue.Name); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Delete removes a venue no event refers to, with its seat map. It returns
+// ErrConflict while events are held there.
+func (r *VenueRepository) Delete(ctx context.Context, id string) error {
+	tag, err := r.DB.Exec(ctx, `DELETE FROM venues WHERE id = $1`, id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return ErrConflict
+		}
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *VenueRepository) seatMap(ctx context.Context, venueID string) ([]VenueSection, error) {
+	query := `
+		SELECT sec.id, sec.name, sec.rank, s.id, s.row_label, s.number
+		FROM venue_sections sec
+		LEFT JOIN venue_seats s ON s.section_id = sec.id
+		WHERE sec.venue_id = $1
+		ORDER BY sec.rank, sec.name, s.row_position, s.number
+	`
+	rows, err := r.DB.Query(ctx, query, venueID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sections := []VenueSection{}
+	for rows.Next() {
+		var section VenueSection
+		var seatID, rowLabel *string
+		var number *int
+		if err := rows.Scan(&section.ID, &section.Name, &section.Rank, &seatID, &rowLabel, &number); err != nil {
+			return nil, err
+		}
+		if n := len(sections); n == 0 || sections[n-1].ID != section.ID {
+			section.Rows = []SeatRow{}
+			sections = append(sections, section)
+		}
+		if seatID == nil {
+			continue
+		}
+		current := &sections[len(sections)-1]
+		if n := len(current.Rows); n == 0 || current.Rows[n-1].Label != *rowLabel {
+			current.Rows = append(current.Rows, SeatRow{Label: *rowLabel})
+		}
+		row := &current.Rows[len(current.Rows)-1]
+		row.Seats = append(row.Seats, Seat{ID: *seatID, Number: *number})
+	}
+	return sections, rows.Err()
+}
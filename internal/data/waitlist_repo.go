@@ -0,0 +1,294 @@
+package data
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WaitlistRepository struct {
+	DB *pgxpool.Pool
+}
+
+const offerColumns = `id, event_id, tier_id, user_id, quantity, status, expires_at, booking_id, created_at, responded_at`
+
+func scanOffer(row pgx.Row, offer *WaitlistOffer) error {
+	return row.Scan(
+		&offer.ID, &offer.EventID, &offer.TierID, &offer.UserID, &offer.Quantity, &offer.Status,
+		&offer.ExpiresAt, &offer.BookingID, &offer.CreatedAt, &offer.RespondedAt,
+	)
+}
+
+func (r *WaitlistRepository) GetOffersByUserID(ctx context.Context, userID string) ([]WaitlistOffer, error) {
+	query := `
+		SELECT o.id, o.event_id, e.name, o.tier_id, o.user_id, o.quantity, o.status, o.expires_at, o.booking_id, o.created_at, o.responded_at
+		FROM waitlist_offers o JOIN events e ON e.id = o.event_id
+		WHERE o.user_id = $1 AND o.status = 'pending' AND o.expires_at > NOW()
+		ORDER BY o.expires_at
+	`
+	rows, err := r.DB.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	offers := []WaitlistOffer{}
+	for rows.Next() {
+		var offer WaitlistOffer
+		err := rows.Scan(
+			&offer.ID, &offer.EventID, &offer.EventName, &offer.TierID, &offer.UserID, &offer.Quantity, &offer.Status,
+			&offer.ExpiresAt, &offer.BookingID, &offer.CreatedAt, &offer.RespondedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		offers = append(offers, offer)
+	}
+	return offers, rows.Err()
+}
+
+func (r *WaitlistRepository) GetOfferForUpdate(ctx context.Context, tx pgx.Tx, offerID, userID string) (*WaitlistOffer, error) {
+	var offer WaitlistOffer
+	query := `SELECT ` + offerColumns + ` FROM waitlist_offers WHERE id = $1 AND user_id = $2 FOR UPDATE`
+	if err := scanOffer(tx.QueryRow(ctx, query, offerID, userID), &offer); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &offer, nil
+}
+
+// LockEventStatus locks the event the offer is for and returns its status.
+// Like HoldRepository.LockEventStatus it takes the event lock before the
+// offer's, the same order as event cancellation.
+func (r *WaitlistRepository) LockEventStatus(ctx context.Context, tx pgx.Tx, offerID, userID string) (string, error) {
+	query := `
+		SELECT e.status FROM events e JOIN waitlist_offers o ON o.event_id = e.id
+		WHERE o.id = $1 AND o.user_id = $2
+		FOR UPDATE OF e
+	`
+	var status string
+	if err := tx.QueryRow(ctx, query, offerID, userID).Scan(&status); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	return status, nil
+}
+
+// AcceptOffer books the offered tickets, moving them from the event's held
+// counter to the booked counter.
+func (r *WaitlistRepository) AcceptOffer(ctx context.Context, tx pgx.Tx, offer *WaitlistOffer) error {
+	var bookingID string
+	insertBookingQuery := `INSERT INTO bookings (user_id, event_id, tier_id, quantity) VALUES ($1, $2, $3, $4) RETURNING id`
+	if err := tx.QueryRow(ctx, insertBookingQuery, offer.UserID, offer.EventID, offer.TierID, offer.Quantity).Scan(&bookingID); err != nil {
+		return err
+	}
+
+	updateEventQuery := `
+		UPDATE events SET held_tickets = held_tickets - $2, booked_tickets = booked_tickets + $2, version = version + 1
+		WHERE id = $1
+	`
+	if _, err := tx.Exec(ctx, updateEventQuery, offer.EventID, offer.Quantity); err != nil {
+		return err
+	}
+	if err := adjustTier(ctx, tx, offer.TierID, offer.Quantity, -offer.Quantity); err != nil {
+		return err
+	}
+
+	fields := map[string]any{"booking_id": bookingID, "quantity": offer.Quantity}
+	if err := EnqueueNotification(ctx, tx, NotificationBookingCreated, offer.UserID, offer.EventID, fields); err != nil {
+		return err
+	}
+	if err := EmitWebhook(ctx, tx, WebhookBookingCreated, bookingPayload(bookingID, offer.EventID, offer.TierID, offer.UserID, offer.Quantity)); err != nil {
+		return err
+	}
+
+	updateOfferQuery := `
+		UPDATE waitlist_offers SET status = 'accepted', booking_id = $2, responded_at = NOW()
+		WHERE id = $1
+		RETURNING responded_at
+	`
+	if err := tx.QueryRow(ctx, updateOfferQuery, offer.ID, bookingID).Scan(&offer.RespondedAt); err != nil {
+		return err
+	}
+	offer.Status = OfferStatusAccepted
+	offer.BookingID = &bookingID
+	return nil
+}
+
+// CloseOffer ends a pending offer without a booking and returns its tickets
+// to the event's free pool.
+func (r *WaitlistRepository) CloseOffer(ctx context.Context, tx pgx.Tx, offer *WaitlistOffer, status string) error {
+	updateOfferQuery := `UPDATE waitlist_offers SET status = $2, responded_at = NOW() WHERE id = $1 RETURNING responded_at`
+	if err := tx.QueryRow(ctx, updateOfferQuery, offer.ID, status).Scan(&offer.RespondedAt); err != nil {
+		return err
+	}
+
+	updateEventQuery := `UPDATE events SET held_tickets = held_tickets - $2, version = version + 1 WHERE id = $1`
+	if _, err := tx.Exec(ctx, updateEventQuery, offer.EventID, offer.Quantity); err != nil {
+		return err
+	}
+	if err := adjustTier(ctx, tx, offer.TierID, 0, -offer.Quantity); err != nil {
+		return err
+	}
+	offer.Status = status
+	return nil
+}
+
+// ClaimExpiredOffers locks up to limit pending offers whose window has passed.
+// The events are locked before the offers, the same order as event
+// cancellation. Events and offers locked by someone else are skipped and
+// left for a later sweep.
+func (r *WaitlistRepository) ClaimExpiredOffers(ctx context.Context, tx pgx.Tx, limit int) ([]WaitlistOffer, error) {
+	eventsQuery := `
+		SELECT id FROM events
+		WHERE id IN (
+			SELECT event_id FROM waitlist_offers
+			WHERE status = 'pending' AND expires_at <= NOW()
+			ORDER BY expires_at LIMIT $1
+		)
+		ORDER BY id FOR UPDATE SKIP LOCKED
+	`
+	eventIDs, err := lockEventIDs(ctx, tx, eventsQuery, limit)
+	if err != nil || len(eventIDs) == 0 {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + offerColumns + ` FROM waitlist_offers
+		WHERE event_id = ANY($2) AND status = 'pending' AND expires_at <= NOW()
+		ORDER BY expires_at LIMIT $1 FOR UPDATE SKIP LOCKED
+	`
+	return queryOffers(ctx, tx, query, limit, eventIDs)
+}
+
+// GetOfferHistory returns every offer the user has received, newest first.
+func (r *WaitlistRepository) GetOfferHistory(ctx context.Context, userID string) ([]WaitlistOffer, error) {
+	query := `SELECT ` + offerColumns + ` FROM waitlist_offers WHERE user_id = $1 ORDER BY created_at DESC`
+	return queryOffers(ctx, r.DB, query, userID)
+}
+
+// LockPendingOffersByUserID locks the user's pending offers so they can be
+// closed.
+func (r *WaitlistRepository) LockPendingOffersByUserID(ctx context.Context, tx pgx.Tx, userID string) ([]WaitlistOffer, error) {
+	query := `SELECT ` + offerColumns + ` FROM waitlist_offers WHERE user_id = $1 AND status = 'pending' FOR UPDATE`
+	return queryOffers(ctx, tx, query, userID)
+}
+
+func lockEventIDs(ctx context.Context, tx pgx.Tx, query string, args ...any) ([]string, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func queryOffers(ctx context.Context, q querier, query string, args ...any) ([]WaitlistOffer, error) {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	offers := []WaitlistOffer{}
+	for rows.Next() {
+		var offer WaitlistOffer
+		if err := scanOffer(rows, &offer); err != nil {
+			return nil, err
+		}
+		offers = append(offers, offer)
+	}
+	return offers, rows.Err()
+}
+
+const entryQuery = `
+	SELECT w.id, w.event_id, e.name, w.tier_id, w.quantity, q.position, q.tickets_ahead, w.created_at
+	FROM waitlist_entries w
+	JOIN events e ON e.id = w.event_id
+	JOIN LATERAL (
+		SELECT COUNT(*) + 1 AS position, COALESCE(SUM(a.quantity), 0) AS tickets_ahead
+		FROM waitlist_entries a
+		WHERE a.event_id = w.event_id AND (a.created_at, a.id) < (w.created_at, w.id)
+	) q ON true
+`
+
+func (r *WaitlistRepository) GetEntriesByUserID(ctx context.Context, userID string) ([]WaitlistEntry, error) {
+	rows, err := r.DB.Query(ctx, entryQuery+` WHERE w.user_id = $1 ORDER BY w.created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []WaitlistEntry{}
+	for rows.Next() {
+		var entry WaitlistEntry
+		err := rows.Scan(&entry.ID, &entry.EventID, &entry.EventName, &entry.TierID, &entry.Quantity, &entry.Position, &entry.TicketsAhead, &entry.JoinedAt)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (r *WaitlistRepository) GetEntry(ctx context.Context, entryID, userID string) (*WaitlistEntry, error) {
+	var entry WaitlistEntry
+	err := r.DB.QueryRow(ctx, entryQuery+` WHERE w.id = $1 AND w.user_id = $2`, entryID, userID).Scan(
+		&entry.ID, &entry.EventID, &entry.EventName, &entry.TierID, &entry.Quantity, &entry.Position, &entry.TicketsAhead, &entry.JoinedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// UpdateEntryQuantity changes how many tickets the entry asks for. Lowering
+// the quantity keeps the entry's place in the queue; raising it moves the
+// entry to the back, since it would otherwise take tickets from people behind.
+func (r *WaitlistRepository) UpdateEntryQuantity(ctx context.Context, tx pgx.Tx, entryID, userID string, quantity int) (string, error) {
+	var eventID string
+	query := `
+		UPDATE waitlist_entries SET
+			created_at = CASE WHEN $3 > quantity THEN NOW() ELSE created_at END,
+			quantity = $3
+		WHERE id = $1 AND user_id = $2
+		RETURNING event_id
+	`
+	if err := tx.QueryRow(ctx, query, entryID, userID, quantity).Scan(&eventID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	return eventID, nil
+}
+
+func (r *WaitlistRepository) DeleteEntry(ctx context.Context, tx pgx.Tx, entryID, userID string) (string, error) {
+	var eventID string
+	query := `DELETE FROM waitlist_entries WHERE id = $1 AND user_id = $2 RETURNING event_id`
+	if err := tx.QueryRow(ctx, query, entryID, userID).Scan(&eventID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	return eventID, nil
+}
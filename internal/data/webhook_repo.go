@@ -0,0 +1,252 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WebhookRepository struct {
+	DB *pgxpool.Pool
+}
+
+// PendingDelivery is a claimed delivery together with where to send it.
+// Payload is the exact JSON body that gets signed and posted.
+type PendingDelivery struct {
+	ID             string
+	SubscriptionID string
+	EventType      string
+	Payload        []byte
+	Attempts       int
+	URL            string
+	Secret         string
+}
+
+// DeliveryCursor is the keyset position of the last delivery on a page.
+type DeliveryCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"i"`
+}
+
+// EmitWebhook queues a delivery of eventType for every active subscription
+// that wants it. It must run inside the transaction making the change so a
+// rollback discards the deliveries too. Every subscriber receives the same
+// envelope id, which partners can use to de-duplicate retries.
+func EmitWebhook(ctx context.Context, tx pgx.Tx, eventType string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	query := `
+		WITH envelope AS (SELECT gen_random_uuid() AS id, NOW() AS occurred_at)
+		INSERT INTO webhook_deliveries (subscription_id, event_type, payload)
+		SELECT s.id, $1, jsonb_build_object('id', e.id, 'type', $1::text, 'created_at', e.occurred_at, 'data', $2::jsonb)
+		FROM webhook_subscriptions s, envelope e
+		WHERE s.active AND $1 = ANY(s.event_types)
+	`
+	_, err = tx.Exec(ctx, query, eventType, body)
+	return err
+}
+
+const subscriptionColumns = `id, url, event_types, active, created_at, updated_at`
+
+func scanSubscription(row pgx.Row, sub *WebhookSubscription) error {
+	return row.Scan(&sub.ID, &sub.URL, &sub.EventTypes, &sub.Active, &sub.CreatedAt, &sub.UpdatedAt)
+}
+
+func (r *WebhookRepository) CreateSubscription(ctx context.Context, sub *WebhookSubscription) error {
+	query := `
+		INSERT INTO webhook_subscriptions (url, secret, event_types, active)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + subscriptionColumns
+	return scanSubscription(r.DB.QueryRow(ctx, query, sub.URL, sub.Secret, sub.EventTypes, sub.Active), sub)
+}
+
+func (r *WebhookRepository) ListSubscriptions(ctx context.Context) ([]WebhookSubscription, error) {
+	rows, err := r.DB.Query(ctx, `SELECT `+subscriptionColumns+` FROM webhook_subscriptions ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []WebhookSubscription{}
+	for rows.Next() {
+		var sub WebhookSubscription
+		if err := scanSubscription(rows, &sub); err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+func (r *WebhookRepository) GetSubscription(ctx context.Context, id string) (*WebhookSubscription, error) {
+	var sub WebhookSubscription
+	err := scanSubscription(r.DB.QueryRow(ctx, `SELECT `+subscriptionColumns+` FROM webhook_subscriptions WHERE id = $1`, id), &sub)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// UpdateSubscription replaces the URL, event types and active flag. The
+// secret is left untouched.
+func (r *WebhookRepository) UpdateSubscription(ctx context.Context, sub *WebhookSubscription) error {
+	query := `
+		UPDATE webhook_subscriptions SET url = $2, event_types = $3, active = $4, updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + subscriptionColumns
+	err := scanSubscription(r.DB.QueryRow(ctx, query, sub.ID, sub.URL, sub.EventTypes, sub.Active), sub)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (r *WebhookRepository) DeleteSubscription(ctx context.Context, id string) error {
+	tag, err := r.DB.Exec(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListDeliveries returns a subscription's deliveries newest first. status
+// filters by delivery status when non-empty.
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, subscriptionID, status string, after *DeliveryCursor, limit int) ([]WebhookDelivery, error) {
+	query := `
+		SELECT id, subscription_id, event_type, payload, status, attempts, next_attempt_at,
+		       last_status_code, last_error, created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE subscription_id = $1
+		  AND ($2 = '' OR status::text = $2)
+		  AND ($3::timestamptz IS NULL OR (created_at, id) < ($3, $4::uuid))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $5
+	`
+	var afterTime *time.Time
+	var afterID *string
+	if after != nil {
+		afterTime, afterID = &after.CreatedAt, &after.ID
+	}
+	rows, err := r.DB.Query(ctx, query, subscriptionID, status, afterTime, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []WebhookDelivery{}
+	for rows.Next() {
+		var d WebhookDelivery
+		err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// ClaimDeliveries leases up to limit due deliveries for lease, the same way
+// OutboxRepository.ClaimPending does. Deliveries for inactive subscriptions
+// wait until the subscription is re-enabled.
+func (r *WebhookRepository) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]PendingDelivery, error) {
+	query := `
+		WITH claimed AS (
+			UPDATE webhook_deliveries SET attempts = attempts + 1, next_attempt_at = NOW() + $2::interval
+			WHERE id IN (
+				SELECT d.id FROM webhook_deliveries d
+				JOIN webhook_subscriptions s ON s.id = d.subscription_id
+				WHERE d.status = 'pending' AND d.next_attempt_at <= NOW() AND s.active
+				ORDER BY d.next_attempt_at LIMIT $1 FOR UPDATE OF d SKIP LOCKED
+			)
+			RETURNING id, subscription_id, event_type, payload, attempts
+		)
+		SELECT c.id, c.subscription_id, c.event_type, c.payload::text, c.attempts, s.url, s.secret
+		FROM claimed c JOIN webhook_subscriptions s ON s.id = c.subscription_id
+	`
+	rows, err := r.DB.Query(ctx, query, limit, lease)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []PendingDelivery
+	for rows.Next() {
+		var d PendingDelivery
+		var payload string
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventType, &payload, &d.Attempts, &d.URL, &d.Secret); err != nil {
+			return nil, err
+		}
+		d.Payload = []byte(payload)
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *WebhookRepository) MarkDelivered(ctx context.Context, id string, statusCode int) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'delivered', delivered_at = NOW(), last_status_code = $2, last_error = NULL
+		WHERE id = $1
+	`
+	_, err := r.DB.Exec(ctx, query, id, statusCode)
+	return err
+}
+
+// MarkFailed records a failed attempt. statusCode is zero when no response
+// was received. The delivery is retried at nextAttempt unless dead is set.
+func (r *WebhookRepository) MarkFailed(ctx context.Context, id string, statusCode int, sendErr error, nextAttempt time.Time, dead bool) error {
+	status := DeliveryStatusPending
+	if dead {
+		status = DeliveryStatusDead
+	}
+	var code *int
+	if statusCode != 0 {
+		code = &statusCode
+	}
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $2, last_status_code = $3, last_error = $4, next_attempt_at = $5
+		WHERE id = $1
+	`
+	_, err := r.DB.Exec(ctx, query, id, status, code, sendErr.Error(), nextAttempt)
+	return err
+}
+
+// RequeueDelivery moves a dead delivery back to pending with a fresh attempt
+// budget. It returns ErrConflict if the delivery is not dead.
+func (r *WebhookRepository) RequeueDelivery(ctx context.Context, subscriptionID, deliveryID string) error {
+	query := `
+		UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+		WHERE id = $1 AND subscription_id = $2 AND status = 'dead'
+	`
+	tag, err := r.DB.Exec(ctx, query, deliveryID, subscriptionID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+
+	var exists bool
+	err = r.DB.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM webhook_deliveries WHERE id = $1 AND subscription_id = $2)`, deliveryID, subscriptionID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	return ErrConflict
+}
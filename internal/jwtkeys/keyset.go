@@ -0,0 +1,183 @@
+// Package jwtkeys holds the asymmetric keys Evently signs and verifies access
+// tokens with.
+//
+// Keys live in a directory as PEM files named <kid>.pem. A PKCS#8 "PRIVATE
+// KEY" (RSA of at least 2048 bits, or Ed25519) can sign and verify; a PKIX
+// "PUBLIC KEY" can only verify. Exactly one private key signs new tokens.
+//
+// To rotate:
+//
+//  1. Set JWT_SIGNING_KEY_ID to the current kid if it is not set already;
+//     otherwise the new key would sign as soon as it is loaded. Add a new key
+//     with cmd/keygen and restart; it is published in the JWKS but does not
+//     sign yet.
+//  2. Once verifiers have picked up the new JWKS, set JWT_SIGNING_KEY_ID to
+//     the new kid (or unset it to use the newest key) and restart.
+//  3. After ACCESS_TOKEN_TTL has passed, delete the old key file (or replace
+//     it with its public half) and restart.
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+const minRSABits = 2048
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Key is one entry of the key set. Private is nil for verify-only keys.
+type Key struct {
+	ID      string
+	Alg     string
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+func (k *Key) method() jwt.SigningMethod {
+	if k.Alg == AlgRS256 {
+		return jwt.SigningMethodRS256
+	}
+	return jwt.SigningMethodEdDSA
+}
+
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+	ids     []string
+}
+
+// LoadDir reads every *.pem file in dir. signingKID picks the signing key;
+// when empty the private key with the greatest kid is used, which for keys
+// named by cmd/keygen is the newest, so a key signs as soon as it is added
+// unless signingKID pins the current one.
+func LoadDir(dir, signingKID string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	set := &KeySet{keys: make(map[string]*Key)}
+	for _, path := range paths {
+		key, err := loadKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		set.keys[key.ID] = key
+		set.ids = append(set.ids, key.ID)
+	}
+	sort.Strings(set.ids)
+
+	if signingKID == "" {
+		for i := len(set.ids) - 1; i >= 0; i-- {
+			if key := set.keys[set.ids[i]]; key.Private != nil {
+				set.signing = key
+				break
+			}
+		}
+		if set.signing == nil {
+			return nil, fmt.Errorf("no private key found in %s, generate one with cmd/keygen", dir)
+		}
+		return set, nil
+	}
+
+	key, ok := set.keys[signingKID]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q not found in %s", signingKID, dir)
+	}
+	if key.Private == nil {
+		return nil, fmt.Errorf("signing key %q has no private part", signingKID)
+	}
+	set.signing = key
+	return set, nil
+}
+
+func loadKey(path string) (*Key, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	key := &Key{ID: strings.TrimSuffix(filepath.Base(path), ".pem")}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("unsupported private key type")
+		}
+		key.Private = signer
+		key.Public = signer.Public()
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key.Public = parsed
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q, expected PRIVATE KEY or PUBLIC KEY", block.Type)
+	}
+
+	switch pub := key.Public.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA key must be at least %d bits", minRSABits)
+		}
+		key.Alg = AlgRS256
+	case ed25519.PublicKey:
+		key.Alg = AlgEdDSA
+	default:
+		return nil, errors.New("only RSA and Ed25519 keys are supported")
+	}
+	return key, nil
+}
+
+// Sign issues a token signed with the current signing key and tagged with its kid.
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.signing.method(), claims)
+	token.Header["kid"] = s.signing.ID
+	return token.SignedString(s.signing.Private)
+}
+
+// Keyfunc resolves the verification key named by a token's kid header. It
+// rejects tokens whose alg does not match the key, so a token cannot pick a
+// weaker algorithm than the key was issued for.
+func (s *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if token.Method.Alg() != key.Alg {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.Public, nil
+}
+
+// Algorithms lists the signing methods a verifier should accept.
+func (s *KeySet) Algorithms() []string {
+	return []string{AlgRS256, AlgEdDSA}
+}
@@ -0,0 +1,251 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writeKey stores signer in dir as <kid>.pem, keeping only the public half
+// when publicOnly is set, the way cmd/keygen -retire does.
+func writeKey(t *testing.T, dir, kid string, signer crypto.Signer, publicOnly bool) {
+	t.Helper()
+	blockType := "PRIVATE KEY"
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if publicOnly {
+		blockType = "PUBLIC KEY"
+		der, err = x509.MarshalPKIXPublicKey(signer.Public())
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newEd25519(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv
+}
+
+func newRSA(t *testing.T, bits int) *rsa.PrivateKey {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv
+}
+
+func testClaims() jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{Subject: "user-1", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))}
+}
+
+// signingKID signs a token with set and reports the kid it was tagged with.
+func signingKID(t *testing.T, set *KeySet) string {
+	t.Helper()
+	signed, err := set.Sign(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.Parse(signed, set.Keyfunc)
+	if err != nil {
+		t.Fatalf("verifying own token: %v", err)
+	}
+	kid, _ := token.Header["kid"].(string)
+	return kid
+}
+
+func TestLoadDirSigningKey(t *testing.T) {
+	dir := t.TempDir()
+	writeKey(t, dir, "20240101T000000Z", newEd25519(t), false)
+	writeKey(t, dir, "20240201T000000Z", newRSA(t, 2048), false)
+	// The newest file is verify-only, so it must not be picked to sign.
+	writeKey(t, dir, "20240301T000000Z", newEd25519(t), true)
+
+	tests := []struct {
+		name       string
+		signingKID string
+		want       string
+		wantErr    bool
+	}{
+		{name: "newest private key by default", want: "20240201T000000Z"},
+		{name: "pinned kid", signingKID: "20240101T000000Z", want: "20240101T000000Z"},
+		{name: "pinned kid without private half", signingKID: "20240301T000000Z", wantErr: true},
+		{name: "pinned kid not in dir", signingKID: "20990101T000000Z", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := LoadDir(dir, tt.signingKID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadDir() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := signingKID(t, set); got != tt.want {
+				t.Errorf("signed with kid %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadDirRejects(t *testing.T) {
+	t.Run("no private key", func(t *testing.T) {
+		dir := t.TempDir()
+		writeKey(t, dir, "public", newEd25519(t), true)
+		if _, err := LoadDir(dir, ""); err == nil {
+			t.Error("LoadDir() accepted a directory without a private key")
+		}
+	})
+	t.Run("short RSA key", func(t *testing.T) {
+		dir := t.TempDir()
+		writeKey(t, dir, "short", newRSA(t, 1024), false)
+		if _, err := LoadDir(dir, ""); err == nil {
+			t.Error("LoadDir() accepted a 1024-bit RSA key")
+		}
+	})
+}
+
+func TestKeyfuncVerifiesEveryLoadedKey(t *testing.T) {
+	dir := t.TempDir()
+	writeKey(t, dir, "old", newEd25519(t), false)
+	writeKey(t, dir, "new", newRSA(t, 2048), false)
+
+	before, err := LoadDir(dir, "old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	issued, err := before.Sign(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// After the rotation tokens from the old key still verify.
+	after, err := LoadDir(dir, "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwt.Parse(issued, after.Keyfunc); err != nil {
+		t.Errorf("token from the previous signing key rejected: %v", err)
+	}
+	if got := signingKID(t, after); got != "new" {
+		t.Errorf("signed with kid %q, want new", got)
+	}
+}
+
+func TestKeyfuncRejects(t *testing.T) {
+	dir := t.TempDir()
+	edKey := newEd25519(t)
+	writeKey(t, dir, "ed", edKey, false)
+	writeKey(t, dir, "rsa", newRSA(t, 2048), false)
+	set, err := LoadDir(dir, "ed")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(method jwt.SigningMethod, kid string, key any) string {
+		token := jwt.NewWithClaims(method, testClaims())
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	t.Run("unknown kid", func(t *testing.T) {
+		_, err := jwt.Parse(sign(jwt.SigningMethodEdDSA, "stranger", newEd25519(t)), set.Keyfunc)
+		if !errors.Is(err, ErrUnknownKey) {
+			t.Errorf("Parse() error = %v, want ErrUnknownKey", err)
+		}
+	})
+	t.Run("missing kid", func(t *testing.T) {
+		_, err := jwt.Parse(sign(jwt.SigningMethodEdDSA, "", edKey), set.Keyfunc)
+		if !errors.Is(err, ErrUnknownKey) {
+			t.Errorf("Parse() error = %v, want ErrUnknownKey", err)
+		}
+	})
+	t.Run("alg does not match the key", func(t *testing.T) {
+		tests := []struct {
+			method jwt.SigningMethod
+			kid    string
+		}{
+			{jwt.SigningMethodEdDSA, "rsa"},
+			{jwt.SigningMethodRS256, "ed"},
+			{jwt.SigningMethodHS256, "ed"},
+		}
+		for _, tt := range tests {
+			token := &jwt.Token{Method: tt.method, Header: map[string]any{"alg": tt.method.Alg(), "kid": tt.kid}}
+			if key, err := set.Keyfunc(token); err == nil {
+				t.Errorf("Keyfunc() returned %T for an %s token naming %q", key, tt.method.Alg(), tt.kid)
+			}
+		}
+	})
+}
+
+func TestJWKS(t *testing.T) {
+	dir := t.TempDir()
+	edKey := newEd25519(t)
+	rsaKey := newRSA(t, 2048)
+	writeKey(t, dir, "a-ed", edKey, true)
+	writeKey(t, dir, "b-rsa", rsaKey, false)
+	set, err := LoadDir(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := set.JWKS().Keys
+	if len(keys) != 2 {
+		t.Fatalf("JWKS has %d keys, want 2", len(keys))
+	}
+	decode := func(s string) []byte {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			t.Fatalf("decoding %q: %v", s, err)
+		}
+		return b
+	}
+
+	ed := keys[0]
+	if ed.Kid != "a-ed" || ed.Kty != "OKP" || ed.Crv != "Ed25519" || ed.Alg != AlgEdDSA || ed.Use != "sig" {
+		t.Errorf("Ed25519 JWK = %+v", ed)
+	}
+	if !edKey.Public().(ed25519.PublicKey).Equal(ed25519.PublicKey(decode(ed.X))) {
+		t.Error("Ed25519 JWK x does not match the public key")
+	}
+
+	rs := keys[1]
+	if rs.Kid != "b-rsa" || rs.Kty != "RSA" || rs.Alg != AlgRS256 || rs.Use != "sig" || rs.Crv != "" || rs.X != "" {
+		t.Errorf("RSA JWK = %+v", rs)
+	}
+	if new(big.Int).SetBytes(decode(rs.N)).Cmp(rsaKey.N) != 0 {
+		t.Error("RSA JWK n does not match the modulus")
+	}
+	if e := new(big.Int).SetBytes(decode(rs.E)); e.Int64() != int64(rsaKey.E) {
+		t.Errorf("RSA JWK e = %d, want %d", e, rsaKey.E)
+	}
+	if rs.E != "AQAB" {
+		t.Errorf("RSA JWK e = %q, want AQAB", rs.E)
+	}
+}
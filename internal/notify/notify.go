@@ -0,0 +1,16 @@
+// Package notify delivers user-facing messages such as booking emails.
+// Notifier implementations are interchangeable so local development can write
+// messages to a file or the log instead of a real mail server.
+package notify
+
+import "context"
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
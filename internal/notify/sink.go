@@ -0,0 +1,41 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileNotifier appends every message to a local file, for inspecting
+// outgoing mail during development.
+type FileNotifier struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (n *FileNotifier) Send(ctx context.Context, msg Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "--- %s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().Format(time.RFC3339), msg.To, msg.Subject, msg.Body)
+	return err
+}
+
+// LogNotifier writes messages to the application log instead of sending them.
+type LogNotifier struct {
+	Log *slog.Logger
+}
+
+func (n *LogNotifier) Send(ctx context.Context, msg Message) error {
+	n.Log.Info("notification", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
@@ -0,0 +1,89 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+type messageTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+var templates = map[string]messageTemplate{
+	"booking.created": parse(
+		`Your booking for {{.event_name}} is confirmed`,
+		`Hi {{.name}},
+
+Your booking of {{.quantity}} ticket(s) for {{.event_name}} on {{.event_start}} is confirmed.
+Booking reference: {{.booking_id}}`),
+	"booking.cancelled": parse(
+		`Your booking for {{.event_name}} was cancelled`,
+		`Hi {{.name}},
+
+{{.quantity}} ticket(s) on booking {{.booking_id}} for {{.event_name}} have been cancelled.`),
+	"waitlist.promoted": parse(
+		`You're in! Tickets booked for {{.event_name}}`,
+		`Hi {{.name}},
+
+Tickets became available for {{.event_name}} on {{.event_start}} and we booked {{.quantity}} ticket(s) for you from the waitlist.
+Booking reference: {{.booking_id}}`),
+	"waitlist.offered": parse(
+		`Tickets are available for {{.event_name}}`,
+		`Hi {{.name}},
+
+{{.quantity}} ticket(s) for {{.event_name}} on {{.event_start}} are being held for you.
+Accept or decline the offer before {{.expires_at}}, after which it passes to the next person on the waitlist.
+Offer reference: {{.offer_id}}`),
+	"event.cancelled": parse(
+		`{{.event_name}} has been cancelled`,
+		`Hi {{.name}},
+
+Unfortunately {{.event_name}}, scheduled for {{.event_start}}, has been cancelled.{{if eq .source "booking"}}
+Your booking of {{.quantity}} ticket(s) has been cancelled and will be refunded.{{else}}
+You have been removed from the waitlist.{{end}}{{if .reason}}
+
+Reason: {{.reason}}{{end}}`),
+	"auth.verify_email": parse(
+		`Confirm your Evently email address`,
+		`Hi {{.name}},
+
+Please confirm your email address by opening the link below:
+{{.link}}
+
+The link expires in {{.expires_in}}. If you did not create an Evently account you can ignore this email.`),
+	"auth.password_reset": parse(
+		`Reset your Evently password`,
+		`Hi {{.name}},
+
+Someone asked to reset the password for your Evently account. To choose a new password open the link below:
+{{.link}}
+
+The link expires in {{.expires_in}} and can only be used once. If you did not ask for this you can ignore this email.`),
+}
+
+func parse(subject, body string) messageTemplate {
+	return messageTemplate{
+		subject: template.Must(template.New("subject").Parse(subject)),
+		body:    template.Must(template.New("body").Parse(body)),
+	}
+}
+
+// Render builds the message for a notification kind. data holds the
+// notification payload plus the recipient's name.
+func Render(kind, to string, data map[string]any) (Message, error) {
+	tmpl, ok := templates[kind]
+	if !ok {
+		return Message{}, fmt.Errorf("no template for notification kind %q", kind)
+	}
+
+	var subject, body strings.Builder
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return Message{}, err
+	}
+	if err := tmpl.body.Execute(&body, data); err != nil {
+		return Message{}, err
+	}
+	return Message{To: to, Subject: subject.String(), Body: body.String()}, nil
+}
@@ -0,0 +1,211 @@
+// Package recurrence expands RRULE-style recurrence rules into occurrence
+// dates. It understands the part of RFC 5545 that event series need: FREQ
+// of DAILY, WEEKLY or MONTHLY with INTERVAL, BYDAY, BYMONTHDAY, COUNT and
+// UNTIL. Weeks start on Monday.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxCount is the largest COUNT a rule may have.
+const MaxCount = 1000
+
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// Weekday is a BYDAY entry. N picks the Nth such weekday of the month,
+// counting from the end when negative; zero means every one. Only monthly
+// rules may set N.
+type Weekday struct {
+	Day time.Weekday
+	N   int
+}
+
+func (w Weekday) String() string {
+	code := strings.ToUpper(w.Day.String()[:2])
+	if w.N == 0 {
+		return code
+	}
+	return strconv.Itoa(w.N) + code
+}
+
+// Rule is a parsed recurrence rule. Interval is at least 1. Count and
+// Until are zero and nil for rules that do not end; Until is inclusive and
+// only its date is used.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []Weekday
+	ByMonthDay []int
+	Count      int
+	Until      *Date
+}
+
+// Parse reads a rule such as "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10". An
+// "RRULE:" prefix is allowed. Parts it does not support are refused rather
+// than ignored, so a rule never means less than it says.
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 6 && strings.EqualFold(s[:6], "RRULE:") {
+		s = s[6:]
+	}
+	rule := &Rule{Interval: 1}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		key, value = strings.ToUpper(key), strings.ToUpper(value)
+		if !ok || value == "" || seen[key] {
+			return nil, fmt.Errorf("%w: malformed part %q", ErrInvalidRule, part)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "FREQ":
+			rule.Freq = Frequency(value)
+			if rule.Freq != Daily && rule.Freq != Weekly && rule.Freq != Monthly {
+				err = errors.New("FREQ must be DAILY, WEEKLY or MONTHLY")
+			}
+		case "INTERVAL":
+			rule.Interval, err = parseInt(value, 1, 366)
+		case "COUNT":
+			rule.Count, err = parseInt(value, 1, MaxCount)
+		case "UNTIL":
+			rule.Until, err = parseUntil(value)
+		case "BYDAY":
+			rule.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseByMonthDay(value)
+		default:
+			err = fmt.Errorf("%s is not supported", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+	}
+
+	switch {
+	case rule.Freq == "":
+		return nil, fmt.Errorf("%w: FREQ is required", ErrInvalidRule)
+	case rule.Count > 0 && rule.Until != nil:
+		return nil, fmt.Errorf("%w: COUNT and UNTIL cannot both be given", ErrInvalidRule)
+	case rule.ByMonthDay != nil && rule.Freq != Monthly:
+		return nil, fmt.Errorf("%w: BYMONTHDAY is only allowed with FREQ=MONTHLY", ErrInvalidRule)
+	case rule.Freq != Monthly && slices.ContainsFunc(rule.ByDay, func(w Weekday) bool { return w.N != 0 }):
+		return nil, fmt.Errorf("%w: numbered BYDAY entries are only allowed with FREQ=MONTHLY", ErrInvalidRule)
+	}
+	return rule, nil
+}
+
+func parseInt(value string, lo, hi int) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < lo || n > hi {
+		return 0, fmt.Errorf("%q must be a number from %d to %d", value, lo, hi)
+	}
+	return n, nil
+}
+
+// parseUntil accepts a date, 20261231, or a date-time, 20261231T180000Z.
+func parseUntil(value string) (*Date, error) {
+	layout := "20060102"
+	if len(value) > 8 {
+		layout = "20060102T150405"
+		if strings.HasSuffix(value, "Z") {
+			layout += "Z"
+		}
+	}
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return nil, fmt.Errorf("UNTIL %q must be a date like 20261231", value)
+	}
+	d := DateOf(t)
+	return &d, nil
+}
+
+func parseByDay(value string) ([]Weekday, error) {
+	var days []Weekday
+	for _, entry := range strings.Split(value, ",") {
+		if len(entry) < 2 {
+			return nil, fmt.Errorf("BYDAY entry %q is not a weekday", entry)
+		}
+		prefix, code := entry[:len(entry)-2], entry[len(entry)-2:]
+		day, ok := weekdayCodes[code]
+		if !ok {
+			return nil, fmt.Errorf("BYDAY entry %q is not a weekday", entry)
+		}
+		w := Weekday{Day: day}
+		if prefix != "" {
+			n, err := strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, fmt.Errorf("BYDAY entry %q must be numbered from 1 to 5 or -1 to -5", entry)
+			}
+			w.N = n
+		}
+		if slices.Contains(days, w) {
+			return nil, fmt.Errorf("BYDAY repeats %q", entry)
+		}
+		days = append(days, w)
+	}
+	return days, nil
+}
+
+func parseByMonthDay(value string) ([]int, error) {
+	var days []int
+	for _, entry := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(entry)
+		if err != nil || n == 0 || n < -31 || n > 31 {
+			return nil, fmt.Errorf("BYMONTHDAY entry %q must be from 1 to 31 or -1 to -31", entry)
+		}
+		if !slices.Contains(days, n) {
+			days = append(days, n)
+		}
+	}
+	return days, nil
+}
+
+// String returns the rule in canonical form, which Parse reads back.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, len(r.ByDay))
+		for i, w := range r.ByDay {
+			codes[i] = w.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, n := range r.ByMonthDay {
+			days[i] = strconv.Itoa(n)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.compact())
+	}
+	return strings.Join(parts, ";")
+}
@@ -0,0 +1,68 @@
+package recurrence
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseRoundTrip(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"FREQ=DAILY", "FREQ=DAILY"},
+		{"RRULE:freq=weekly;byday=mo,we;count=10", "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10"},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=TU", "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU"},
+		{"FREQ=MONTHLY;INTERVAL=1;BYDAY=-1FR", "FREQ=MONTHLY;BYDAY=-1FR"},
+		{"FREQ=MONTHLY;BYDAY=2TU,4TU", "FREQ=MONTHLY;BYDAY=2TU,4TU"},
+		{"FREQ=MONTHLY;BYMONTHDAY=1,-1,1", "FREQ=MONTHLY;BYMONTHDAY=1,-1"},
+		{"FREQ=DAILY;UNTIL=20261231", "FREQ=DAILY;UNTIL=20261231"},
+		{"FREQ=DAILY;UNTIL=20261231T180000Z", "FREQ=DAILY;UNTIL=20261231"},
+	}
+	for _, tt := range tests {
+		rule, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.in, err)
+			continue
+		}
+		got := rule.String()
+		if got != tt.want {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.in, got, tt.want)
+		}
+		again, err := Parse(got)
+		if err != nil {
+			t.Errorf("Parse(%q) of canonical form error = %v", got, err)
+			continue
+		}
+		if again.String() != got {
+			t.Errorf("canonical form %q reparsed as %q", got, again.String())
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"COUNT=3",
+		"FREQ=YEARLY",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=1001",
+		"FREQ=DAILY;COUNT=3;UNTIL=20261231",
+		"FREQ=DAILY;UNTIL=2026-12-31",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=MONTHLY;BYDAY=6MO",
+		"FREQ=MONTHLY;BYDAY=MO,MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=DAILY;BYHOUR=9",
+		"FREQ=DAILY;COUNT",
+	}
+	for _, in := range tests {
+		if _, err := Parse(in); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("Parse(%q) error = %v, want ErrInvalidRule", in, err)
+		}
+	}
+}
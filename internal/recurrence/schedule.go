@@ -0,0 +1,176 @@
+package recurrence
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Date is a calendar day in the time zone a schedule runs in.
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// DateOf returns the day t falls on in its own location.
+func DateOf(t time.Time) Date {
+	y, m, d := t.Date()
+	return Date{y, m, d}
+}
+
+// ParseDate reads a date like 2026-12-31.
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return Date{}, fmt.Errorf("%q is not a date like 2026-12-31", s)
+	}
+	return DateOf(t), nil
+}
+
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+func (d Date) compact() string {
+	return fmt.Sprintf("%04d%02d%02d", d.Year, d.Month, d.Day)
+}
+
+// Time returns midnight UTC on the date, which is how the database stores
+// a DATE.
+func (d Date) Time() time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, time.UTC)
+}
+
+func (d Date) Before(other Date) bool {
+	return d.Time().Before(other.Time())
+}
+
+func (d Date) AddDays(n int) Date {
+	return DateOf(d.Time().AddDate(0, 0, n))
+}
+
+func (d Date) weekday() time.Weekday {
+	return d.Time().Weekday()
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// Schedule anchors a rule at the start of its first occurrence. Every
+// occurrence starts at Start's wall-clock time in Start's location, so it
+// keeps its local time across daylight saving changes. Dates in Exclude
+// are skipped but still count towards the rule's COUNT.
+type Schedule struct {
+	Start   time.Time
+	Rule    Rule
+	Exclude []Date
+}
+
+// Between returns the starts of the occurrences falling on the dates from
+// through to, inclusive, in order.
+func (s Schedule) Between(from, to Date) []time.Time {
+	loc := s.Start.Location()
+	hour, min, sec := s.Start.Clock()
+	var starts []time.Time
+	s.Rule.each(DateOf(s.Start), to, func(d Date) {
+		if d.Before(from) || slices.Contains(s.Exclude, d) {
+			return
+		}
+		starts = append(starts, time.Date(d.Year, d.Month, d.Day, hour, min, sec, s.Start.Nanosecond(), loc))
+	})
+	return starts
+}
+
+// each calls fn with every date of the rule from start, in order, up to
+// limit, COUNT or UNTIL, whichever comes first.
+func (r *Rule) each(start, limit Date, fn func(Date)) {
+	if r.Until != nil && r.Until.Before(limit) {
+		limit = *r.Until
+	}
+	count := 0
+	for period := 0; ; period++ {
+		first, dates := r.period(start, period)
+		if limit.Before(first) {
+			return
+		}
+		for _, d := range dates {
+			if d.Before(start) {
+				continue
+			}
+			if limit.Before(d) {
+				return
+			}
+			fn(d)
+			count++
+			if count == r.Count {
+				return
+			}
+		}
+	}
+}
+
+// period returns the first day of the nth period of the rule counted from
+// the one holding start, and the dates in it that match the rule, in order.
+func (r *Rule) period(start Date, n int) (Date, []Date) {
+	switch r.Freq {
+	case Daily:
+		d := start.AddDays(n * r.Interval)
+		if len(r.ByDay) > 0 && !slices.ContainsFunc(r.ByDay, func(w Weekday) bool { return w.Day == d.weekday() }) {
+			return d, nil
+		}
+		return d, []Date{d}
+
+	case Weekly:
+		monday := start.AddDays(-((int(start.weekday()) + 6) % 7))
+		first := monday.AddDays(7 * n * r.Interval)
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []Weekday{{Day: start.weekday()}}
+		}
+		var dates []Date
+		for offset := range 7 {
+			d := first.AddDays(offset)
+			if slices.ContainsFunc(days, func(w Weekday) bool { return w.Day == d.weekday() }) {
+				dates = append(dates, d)
+			}
+		}
+		return first, dates
+
+	default:
+		month := time.Date(start.Year, start.Month+time.Month(n*r.Interval), 1, 0, 0, 0, 0, time.UTC)
+		first := DateOf(month)
+		var dates []Date
+		for day := 1; day <= daysIn(first.Year, first.Month); day++ {
+			d := Date{first.Year, first.Month, day}
+			if r.matchesMonthDay(d, start) {
+				dates = append(dates, d)
+			}
+		}
+		return first, dates
+	}
+}
+
+// matchesMonthDay reports whether d is picked by a monthly rule. With
+// neither BYMONTHDAY nor BYDAY the rule repeats on start's day of the
+// month, skipping months too short to have it.
+func (r *Rule) matchesMonthDay(d, start Date) bool {
+	if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+		return d.Day == start.Day
+	}
+	length := daysIn(d.Year, d.Month)
+	if len(r.ByMonthDay) > 0 && !slices.ContainsFunc(r.ByMonthDay, func(n int) bool {
+		return n == d.Day || n < 0 && length+1+n == d.Day
+	}) {
+		return false
+	}
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	fromStart := (d.Day-1)/7 + 1
+	fromEnd := -((length-d.Day)/7 + 1)
+	return slices.ContainsFunc(r.ByDay, func(w Weekday) bool {
+		return w.Day == d.weekday() && (w.N == 0 || w.N == fromStart || w.N == fromEnd)
+	})
+}
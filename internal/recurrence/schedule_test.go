@@ -0,0 +1,147 @@
+package recurrence
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func mustParse(t *testing.T, s string) Rule {
+	t.Helper()
+	rule, err := Parse(s)
+	if err != nil {
+		t.Fatalf("Parse(%q) error = %v", s, err)
+	}
+	return *rule
+}
+
+func dates(starts []time.Time) []string {
+	out := make([]string, len(starts))
+	for i, t := range starts {
+		out[i] = DateOf(t).String()
+	}
+	return out
+}
+
+func TestScheduleBetween(t *testing.T) {
+	start := time.Date(2026, 1, 31, 19, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		start   time.Time
+		rule    string
+		exclude []Date
+		from    Date
+		to      Date
+		want    []string
+	}{
+		{
+			name:  "31st skips short months",
+			start: start,
+			rule:  "FREQ=MONTHLY",
+			from:  Date{2026, 1, 1},
+			to:    Date{2026, 7, 31},
+			want:  []string{"2026-01-31", "2026-03-31", "2026-05-31", "2026-07-31"},
+		},
+		{
+			name:  "last day of every month",
+			start: start,
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1",
+			from:  Date{2026, 1, 1},
+			to:    Date{2026, 4, 30},
+			want:  []string{"2026-01-31", "2026-02-28", "2026-03-31", "2026-04-30"},
+		},
+		{
+			name:  "last Friday",
+			start: time.Date(2026, 1, 1, 18, 0, 0, 0, time.UTC),
+			rule:  "FREQ=MONTHLY;BYDAY=-1FR",
+			from:  Date{2026, 1, 1},
+			to:    Date{2026, 5, 31},
+			want:  []string{"2026-01-30", "2026-02-27", "2026-03-27", "2026-04-24", "2026-05-29"},
+		},
+		{
+			name:  "second and fourth Tuesday",
+			start: time.Date(2026, 3, 1, 18, 0, 0, 0, time.UTC),
+			rule:  "FREQ=MONTHLY;BYDAY=2TU,4TU",
+			from:  Date{2026, 3, 1},
+			to:    Date{2026, 4, 30},
+			want:  []string{"2026-03-10", "2026-03-24", "2026-04-14", "2026-04-28"},
+		},
+		{
+			name:  "fortnightly on two weekdays",
+			start: time.Date(2026, 3, 4, 9, 0, 0, 0, time.UTC),
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE",
+			from:  Date{2026, 3, 1},
+			to:    Date{2026, 3, 31},
+			want:  []string{"2026-03-04", "2026-03-16", "2026-03-18", "2026-03-30"},
+		},
+		{
+			name:  "COUNT reached before the horizon",
+			start: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC),
+			rule:  "FREQ=DAILY;COUNT=3",
+			from:  Date{2026, 3, 1},
+			to:    Date{2026, 12, 31},
+			want:  []string{"2026-03-02", "2026-03-03", "2026-03-04"},
+		},
+		{
+			name:    "excluded dates still count towards COUNT",
+			start:   time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC),
+			rule:    "FREQ=DAILY;COUNT=3",
+			exclude: []Date{{2026, 3, 3}},
+			from:    Date{2026, 3, 1},
+			to:      Date{2026, 12, 31},
+			want:    []string{"2026-03-02", "2026-03-04"},
+		},
+		{
+			name:  "UNTIL is inclusive",
+			start: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC),
+			rule:  "FREQ=WEEKLY;UNTIL=20260316",
+			from:  Date{2026, 3, 1},
+			to:    Date{2026, 12, 31},
+			want:  []string{"2026-03-02", "2026-03-09", "2026-03-16"},
+		},
+		{
+			name:  "from cuts earlier occurrences",
+			start: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC),
+			rule:  "FREQ=DAILY;COUNT=5",
+			from:  Date{2026, 3, 5},
+			to:    Date{2026, 12, 31},
+			want:  []string{"2026-03-05", "2026-03-06"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Schedule{Start: tt.start, Rule: mustParse(t, tt.rule), Exclude: tt.exclude}
+			got := dates(s.Between(tt.from, tt.to))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Between() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleKeepsWallClockAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	// Clocks go forward on 29 March 2026.
+	s := Schedule{Start: time.Date(2026, 3, 27, 19, 30, 0, 0, loc), Rule: mustParse(t, "FREQ=DAILY")}
+	starts := s.Between(Date{2026, 3, 27}, Date{2026, 3, 31})
+	if len(starts) != 5 {
+		t.Fatalf("Between() returned %d occurrences, want 5", len(starts))
+	}
+	for _, start := range starts {
+		if h, m, _ := start.In(loc).Clock(); h != 19 || m != 30 {
+			t.Errorf("occurrence on %s starts at %02d:%02d local, want 19:30", DateOf(start), h, m)
+		}
+	}
+	if got := starts[2].Sub(starts[1]); got != 23*time.Hour {
+		t.Errorf("gap across the change = %s, want 23h", got)
+	}
+	if got := starts[0].UTC().Hour(); got != 19 {
+		t.Errorf("first occurrence is %02d:30 UTC, want 19:30 UTC", got)
+	}
+	if got := starts[4].UTC().Hour(); got != 18 {
+		t.Errorf("last occurrence is %02d:30 UTC, want 18:30 UTC", got)
+	}
+}
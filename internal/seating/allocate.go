@@ -0,0 +1,276 @@
+// Package seating picks the best available seats for a booking that asks
+// for a number of seats rather than specific ones. It works on a snapshot
+// of the seat map and is deterministic: the same seats and request always
+// give the same answer.
+package seating
+
+import (
+	"cmp"
+	"errors"
+	"math"
+	"slices"
+)
+
+// centerWeight is how many rows back one seat away from the middle of the
+// row is worth: a centred block two rows back scores the same as a block
+// in the front row whose middle is four seats off centre.
+const centerWeight = 0.5
+
+var (
+	ErrInvalidQuantity = errors.New("quantity must be greater than zero")
+	ErrNotEnoughSeats  = errors.New("not enough seats are available that match the request")
+	ErrNoBlock         = errors.New("no block of adjacent seats is available, allow split seating to take separate seats")
+)
+
+// Seat is one seat of the map. Seats are adjacent when they are in the same
+// row and their numbers follow each other. Group keeps seats apart that
+// must not be sold together, such as seats in different ticket tiers.
+type Seat struct {
+	ID          string
+	SectionID   string
+	SectionRank int
+	RowPosition int
+	Row         string
+	Number      int
+	PriceCents  int64
+	Group       string
+	Available   bool
+}
+
+// Request is what the booking asks for. Sections limits the seats to those
+// sections when not empty, and MaxPriceCents to seats at or below a price.
+// With AllowSplit, seats that are not all together are accepted when no
+// block of Quantity adjacent seats is free.
+type Request struct {
+	Quantity      int
+	Sections      []string
+	MaxPriceCents *int64
+	AllowSplit    bool
+}
+
+// row is a row of the map with its seats in number order. Its centre is
+// taken over every seat, free or not, so a taken middle does not move it.
+type row struct {
+	sectionID   string
+	sectionRank int
+	position    int
+	label       string
+	center      float64
+	seats       []Seat
+}
+
+// block is a run of adjacent seats in one row.
+type block struct {
+	row   *row
+	start int
+	size  int
+	score float64
+}
+
+func (b block) seats() []Seat {
+	return b.row.seats[b.start : b.start+b.size]
+}
+
+// Allocate returns the seats to book. It prefers one block of adjacent
+// seats: in the best-ranked section, then the one with the lowest score,
+// where the score is the row's distance from the front plus centerWeight
+// per seat between the block's middle and the row's. Ties go to the lower
+// section ID, row and seat number. When no block fits and the request
+// allows it, seats are taken from one group in as few pieces as possible,
+// each piece placed the same way.
+func Allocate(seats []Seat, req Request) ([]Seat, error) {
+	if req.Quantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+
+	rows := buildRows(seats)
+	free := map[string]bool{}
+	groupFree := map[string]int{}
+	for _, seat := range seats {
+		if seat.Available && matches(seat, req) {
+			free[seat.ID] = true
+			groupFree[seat.Group]++
+		}
+	}
+	enough := false
+	for _, n := range groupFree {
+		enough = enough || n >= req.Quantity
+	}
+	if !enough {
+		return nil, ErrNotEnoughSeats
+	}
+
+	if best, ok := bestBlock(rows, free, req.Quantity, nil); ok {
+		return sortSeats(slices.Clone(best.seats())), nil
+	}
+	if !req.AllowSplit {
+		return nil, ErrNoBlock
+	}
+	return split(rows, free, groupFree, req.Quantity), nil
+}
+
+func matches(seat Seat, req Request) bool {
+	if len(req.Sections) > 0 && !slices.Contains(req.Sections, seat.SectionID) {
+		return false
+	}
+	return req.MaxPriceCents == nil || seat.PriceCents <= *req.MaxPriceCents
+}
+
+// split fills the quantity from the group whose best single seat is best,
+// among the groups with enough free seats. It repeatedly takes the best
+// placed of the longest blocks still free, so the party is split into as
+// few pieces as it can be.
+func split(rows []*row, free map[string]bool, groupFree map[string]int, quantity int) []Seat {
+	var group *string
+	for _, candidate := range singleSeats(rows, free) {
+		g := candidate.seats()[0].Group
+		if groupFree[g] >= quantity {
+			group = &g
+			break
+		}
+	}
+
+	var taken []Seat
+	for remaining := quantity; remaining > 0; {
+		size := min(longestRun(rows, free, *group), remaining)
+		best, _ := bestBlock(rows, free, size, group)
+		for _, seat := range best.seats() {
+			delete(free, seat.ID)
+			taken = append(taken, seat)
+		}
+		remaining -= size
+	}
+	return sortSeats(taken)
+}
+
+func buildRows(seats []Seat) []*row {
+	type rowKey struct {
+		sectionID string
+		position  int
+		label     string
+	}
+	byKey := map[rowKey]*row{}
+	var rows []*row
+	for _, seat := range seats {
+		key := rowKey{seat.SectionID, seat.RowPosition, seat.Row}
+		r, ok := byKey[key]
+		if !ok {
+			r = &row{sectionID: seat.SectionID, sectionRank: seat.SectionRank, position: seat.RowPosition, label: seat.Row}
+			byKey[key] = r
+			rows = append(rows, r)
+		}
+		r.seats = append(r.seats, seat)
+	}
+	for _, r := range rows {
+		slices.SortFunc(r.seats, func(a, b Seat) int { return cmp.Compare(a.Number, b.Number) })
+		r.center = float64(r.seats[0].Number+r.seats[len(r.seats)-1].Number) / 2
+	}
+	return rows
+}
+
+// runs calls fn with the start and length of each run of free, adjacent
+// seats of one group in the row.
+func (r *row) runs(free map[string]bool, fn func(start, length int)) {
+	start := -1
+	for i, seat := range r.seats {
+		if start >= 0 && (!free[seat.ID] || seat.Number != r.seats[i-1].Number+1 || seat.Group != r.seats[start].Group) {
+			fn(start, i-start)
+			start = -1
+		}
+		if start < 0 && free[seat.ID] {
+			start = i
+		}
+	}
+	if start >= 0 {
+		fn(start, len(r.seats)-start)
+	}
+}
+
+// bestBlock finds the best placed block of size free seats, in group when
+// it is not nil.
+func bestBlock(rows []*row, free map[string]bool, size int, group *string) (block, bool) {
+	var best block
+	found := false
+	for _, r := range rows {
+		r.runs(free, func(start, length int) {
+			if group != nil && r.seats[start].Group != *group {
+				return
+			}
+			for i := start; i+size <= start+length; i++ {
+				candidate := block{row: r, start: i, size: size}
+				middle := float64(r.seats[i].Number+r.seats[i+size-1].Number) / 2
+				candidate.score = float64(r.position) + centerWeight*math.Abs(middle-r.center)
+				if !found || better(candidate, best) {
+					best, found = candidate, true
+				}
+			}
+		})
+	}
+	return best, found
+}
+
+// singleSeats returns every free seat as a block of one, best first.
+func singleSeats(rows []*row, free map[string]bool) []block {
+	var blocks []block
+	for _, r := range rows {
+		r.runs(free, func(start, length int) {
+			for i := start; i < start+length; i++ {
+				score := float64(r.position) + centerWeight*math.Abs(float64(r.seats[i].Number)-r.center)
+				blocks = append(blocks, block{row: r, start: i, size: 1, score: score})
+			}
+		})
+	}
+	slices.SortFunc(blocks, func(a, b block) int {
+		if better(a, b) {
+			return -1
+		}
+		if better(b, a) {
+			return 1
+		}
+		return 0
+	})
+	return blocks
+}
+
+func longestRun(rows []*row, free map[string]bool, group string) int {
+	longest := 0
+	for _, r := range rows {
+		r.runs(free, func(start, length int) {
+			if r.seats[start].Group == group {
+				longest = max(longest, length)
+			}
+		})
+	}
+	return longest
+}
+
+func better(a, b block) bool {
+	if a.row.sectionRank != b.row.sectionRank {
+		return a.row.sectionRank < b.row.sectionRank
+	}
+	if a.score != b.score {
+		return a.score < b.score
+	}
+	if a.row.sectionID != b.row.sectionID {
+		return a.row.sectionID < b.row.sectionID
+	}
+	if a.row.position != b.row.position {
+		return a.row.position < b.row.position
+	}
+	if a.row.label != b.row.label {
+		return a.row.label < b.row.label
+	}
+	return a.row.seats[a.start].Number < b.row.seats[b.start].Number
+}
+
+func sortSeats(seats []Seat) []Seat {
+	slices.SortFunc(seats, func(a, b Seat) int {
+		return cmp.Or(
+			cmp.Compare(a.SectionRank, b.SectionRank),
+			cmp.Compare(a.SectionID, b.SectionID),
+			cmp.Compare(a.RowPosition, b.RowPosition),
+			cmp.Compare(a.Number, b.Number),
+		)
+	})
+	return seats
+}
@@ -0,0 +1,185 @@
+package seating
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// seatRow builds a row from a layout string, one character per seat
+// numbered from 1: 'o' is free and 'x' taken. Seats are grouped by section
+// and their IDs read like "stalls-A3".
+func seatRow(section string, rank, position int, label, layout string, price int64) []Seat {
+	seats := make([]Seat, len(layout))
+	for i, c := range layout {
+		seats[i] = Seat{
+			ID:          fmt.Sprintf("%s-%s%d", section, label, i+1),
+			SectionID:   section,
+			SectionRank: rank,
+			RowPosition: position,
+			Row:         label,
+			Number:      i + 1,
+			PriceCents:  price,
+			Group:       section,
+			Available:   c == 'o',
+		}
+	}
+	return seats
+}
+
+func seatMap(rows ...[]Seat) []Seat {
+	return slices.Concat(rows...)
+}
+
+func ids(seats []Seat) []string {
+	out := make([]string, len(seats))
+	for i, seat := range seats {
+		out[i] = seat.ID
+	}
+	return out
+}
+
+func TestAllocate(t *testing.T) {
+	price := func(cents int64) *int64 { return &cents }
+	twoSections := seatMap(
+		seatRow("stalls", 0, 0, "A", "oooooo", 5000),
+		seatRow("circle", 1, 0, "A", "oooooo", 3000),
+	)
+	// The first two seats of the row are sold as a different tier.
+	mixedGroups := seatRow("stalls", 0, 0, "A", "ooooo", 5000)
+	mixedGroups[0].Group, mixedGroups[1].Group = "vip", "vip"
+
+	tests := []struct {
+		name    string
+		seats   []Seat
+		req     Request
+		want    []string
+		wantErr error
+	}{
+		{
+			name:  "centred block in the front row",
+			seats: seatMap(seatRow("stalls", 0, 0, "A", "oooooooooo", 5000), seatRow("stalls", 0, 1, "B", "oooooooooo", 5000)),
+			req:   Request{Quantity: 2},
+			want:  []string{"stalls-A5", "stalls-A6"},
+		},
+		{
+			name:  "block skips taken seats",
+			seats: seatRow("stalls", 0, 0, "A", "ooxoooo", 5000),
+			req:   Request{Quantity: 3},
+			want:  []string{"stalls-A4", "stalls-A5", "stalls-A6"},
+		},
+		{
+			name:  "centred block further back beats the edge of the front row",
+			seats: seatMap(seatRow("stalls", 0, 0, "A", "oooxxxxooo", 5000), seatRow("stalls", 0, 1, "B", "oooooooooo", 5000)),
+			req:   Request{Quantity: 3},
+			want:  []string{"stalls-B4", "stalls-B5", "stalls-B6"},
+		},
+		{
+			name:  "equal scores go to the row nearer the front",
+			seats: seatMap(seatRow("stalls", 0, 0, "A", "ooooxxoooo", 5000), seatRow("stalls", 0, 1, "B", "oooooooooo", 5000)),
+			req:   Request{Quantity: 2},
+			want:  []string{"stalls-A3", "stalls-A4"},
+		},
+		{
+			name:  "better ranked section first",
+			seats: twoSections,
+			req:   Request{Quantity: 2},
+			want:  []string{"stalls-A3", "stalls-A4"},
+		},
+		{
+			name:  "section filter",
+			seats: twoSections,
+			req:   Request{Quantity: 2, Sections: []string{"circle"}},
+			want:  []string{"circle-A3", "circle-A4"},
+		},
+		{
+			name:  "price filter",
+			seats: twoSections,
+			req:   Request{Quantity: 2, MaxPriceCents: price(4000)},
+			want:  []string{"circle-A3", "circle-A4"},
+		},
+		{
+			name:  "blocks do not cross tiers",
+			seats: mixedGroups,
+			req:   Request{Quantity: 3},
+			want:  []string{"stalls-A3", "stalls-A4", "stalls-A5"},
+		},
+		{
+			name:    "no block without split",
+			seats:   seatMap(seatRow("stalls", 0, 0, "A", "ooxxx", 5000), seatRow("stalls", 0, 1, "B", "xxxoo", 5000)),
+			req:     Request{Quantity: 3},
+			wantErr: ErrNoBlock,
+		},
+		{
+			name:  "split across rows in as few pieces as possible",
+			seats: seatMap(seatRow("stalls", 0, 0, "A", "ooxxx", 5000), seatRow("stalls", 0, 1, "B", "xxxoo", 5000)),
+			req:   Request{Quantity: 3, AllowSplit: true},
+			want:  []string{"stalls-A1", "stalls-A2", "stalls-B4"},
+		},
+		{
+			name:  "split takes every seat from a tier with enough free",
+			seats: seatMap(seatRow("stalls", 0, 0, "A", "oxoxo", 5000), seatRow("circle", 1, 0, "A", "oxoxoxo", 3000)),
+			req:   Request{Quantity: 4, AllowSplit: true},
+			want:  []string{"circle-A1", "circle-A3", "circle-A5", "circle-A7"},
+		},
+		{
+			name:    "not enough seats",
+			seats:   seatRow("stalls", 0, 0, "A", "oooxx", 5000),
+			req:     Request{Quantity: 4, AllowSplit: true},
+			wantErr: ErrNotEnoughSeats,
+		},
+		{
+			name:    "not enough seats in any one tier",
+			seats:   twoSections,
+			req:     Request{Quantity: 7, AllowSplit: true},
+			wantErr: ErrNotEnoughSeats,
+		},
+		{
+			name:    "not enough seats after filters",
+			seats:   twoSections,
+			req:     Request{Quantity: 2, MaxPriceCents: price(1000)},
+			wantErr: ErrNotEnoughSeats,
+		},
+		{
+			name:    "zero quantity",
+			seats:   twoSections,
+			req:     Request{Quantity: 0},
+			wantErr: ErrInvalidQuantity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Allocate(tt.seats, tt.req)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Allocate() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Allocate() error = %v", err)
+			}
+			if !slices.Equal(ids(got), tt.want) {
+				t.Errorf("Allocate() = %v, want %v", ids(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestAllocateIsDeterministic(t *testing.T) {
+	seats := seatMap(seatRow("stalls", 0, 0, "A", "oxoxoxo", 5000), seatRow("stalls", 0, 1, "B", "ooxoo", 5000))
+	req := Request{Quantity: 4, AllowSplit: true}
+	first, err := Allocate(seats, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Reverse(seats)
+	again, err := Allocate(seats, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ids(first), ids(again)) {
+		t.Errorf("Allocate() = %v after reordering the map, want %v", ids(again), ids(first))
+	}
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"evently/internal/authz"
+	"evently/internal/data"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	apiKeyMarker     = "evk_"
+	apiKeyPrefixLen  = 8
+	maxAPIKeyNameLen = 100
+	// apiKeyTouchInterval limits how often last_used_at is written for a
+	// busy key.
+	apiKeyTouchInterval = time.Minute
+)
+
+var (
+	ErrInvalidAPIKeyName = errors.New("name is required and must be at most 100 characters")
+	ErrInvalidScopes     = errors.New("scopes must be a non-empty list of permissions the key's owner has")
+	ErrInvalidExpiry     = errors.New("expires_at must be in the future")
+)
+
+// CreatedAPIKey is returned once, when a key is created; Key is the only
+// time the secret is shown.
+type CreatedAPIKey struct {
+	data.APIKey
+	Key string `json:"key"`
+}
+
+type APIKeyService struct {
+	repo     *data.APIKeyRepository
+	userRepo *data.UserRepository
+	log      *slog.Logger
+}
+
+func NewAPIKeyService(repo *data.APIKeyRepository, userRepo *data.UserRepository, log *slog.Logger) *APIKeyService {
+	return &APIKeyService{repo: repo, userRepo: userRepo, log: log}
+}
+
+// Create issues a key for userID. createdBy is the user who asked for it,
+// which differs from userID when an admin creates a key for someone. Every
+// scope must be granted by userID's role, so a key never claims more than
+// its owner could do.
+func (s *APIKeyService) Create(ctx context.Context, userID, createdBy, name string, scopes []string, expiresAt *time.Time) (*CreatedAPIKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxAPIKeyNameLen {
+		return nil, ErrInvalidAPIKeyName
+	}
+	if len(scopes) == 0 {
+		return nil, ErrInvalidScopes
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, ErrInvalidExpiry
+	}
+	owner, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range scopes {
+		if !authz.ValidPermission(scope) || !authz.RoleAllows(owner.Role, authz.Permission(scope)) {
+			return nil, ErrInvalidScopes
+		}
+	}
+
+	secret, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	key := data.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Scopes:    scopes,
+		CreatedBy: &createdBy,
+		ExpiresAt: expiresAt,
+	}
+	// Prefixes are short enough to collide now and then; draw another one
+	// when the insert hits an existing prefix.
+	for i := 0; ; i++ {
+		prefixBytes := make([]byte, apiKeyPrefixLen/2)
+		if _, err := rand.Read(prefixBytes); err != nil {
+			return nil, err
+		}
+		key.Prefix = hex.EncodeToString(prefixBytes)
+		err := s.repo.Create(ctx, &key, hashToken(secret))
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, data.ErrDuplicate) || i == MaxRetries-1 {
+			return nil, err
+		}
+		s.log.Warn("api key prefix collision, retrying...", "attempt", i+1)
+	}
+	s.log.Info("api key created", "key_id", key.ID, "user_id", userID, "created_by", createdBy)
+	return &CreatedAPIKey{APIKey: key, Key: apiKeyMarker + key.Prefix + "_" + secret}, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context, userID string) ([]data.APIKey, error) {
+	return s.repo.ListByUser(ctx, userID)
+}
+
+func (s *APIKeyService) Revoke(ctx context.Context, userID, keyID string) error {
+	if err := s.repo.Revoke(ctx, userID, keyID); err != nil {
+		return err
+	}
+	s.log.Info("api key revoked", "key_id", keyID, "user_id", userID)
+	return nil
+}
+
+// VerifyAPIKey resolves a presented key to the user it acts as. It returns a
+// nil identity for any key that cannot be used.
+func (s *APIKeyService) VerifyAPIKey(ctx context.Context, raw string) (*data.APIKeyIdentity, error) {
+	rest, ok := strings.CutPrefix(raw, apiKeyMarker)
+	if !ok || len(rest) <= apiKeyPrefixLen+1 || rest[apiKeyPrefixLen] != '_' {
+		return nil, nil
+	}
+	prefix, secret := rest[:apiKeyPrefixLen], rest[apiKeyPrefixLen+1:]
+
+	identity, err := s.repo.GetIdentity(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(identity.SecretHash)) != 1 {
+		return nil, nil
+	}
+
+	if identity.LastUsedAt == nil || time.Since(*identity.LastUsedAt) > apiKeyTouchInterval {
+		if err := s.repo.TouchLastUsed(ctx, identity.KeyID); err != nil {
+			s.log.Error("failed to record api key use", "key_id", identity.KeyID, "error", err)
+		}
+	}
+	return identity, nil
+}
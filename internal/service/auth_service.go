@@ -2,21 +2,91 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"evently/internal/data"
+	"evently/internal/jwtkeys"
+	"evently/internal/notify"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrInvalidRefreshToken = errors.New("refresh token is invalid or expired")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used, all tokens for this session have been revoked")
+	ErrInvalidUserToken    = errors.New("token is invalid, expired or has already been used")
+	ErrWeakPassword        = fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	ErrAlreadyVerified     = errors.New("email address is already verified")
+	ErrEmailNotVerified    = errors.New("please verify your email address before booking")
+	ErrAccountDisabled     = errors.New("this account has been disabled")
+	ErrPasswordResetNeeded = errors.New("a password reset is required, check your email for the reset link")
+)
+
+const (
+	minPasswordLength    = 8
+	passwordResetTTL     = time.Hour
+	emailVerificationTTL = 48 * time.Hour
+)
+
+// TokenPair is what a successful login or refresh returns. The access token
+// keeps the "token" key older clients already read.
+type TokenPair struct {
+	AccessToken      string    `json:"token"`
+	AccessExpiresAt  time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// AuthConfig holds the AuthService settings that come from configuration.
+type AuthConfig struct {
+	Issuer     string
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+	// AppBaseURL is where links in account emails point.
+	AppBaseURL string
+}
+
 type AuthService struct {
-	userRepo  *data.UserRepository
-	jwtSecret string
+	db            *pgxpool.Pool
+	userRepo      *data.UserRepository
+	tokenRepo     *data.TokenRepository
+	twoFactorRepo *data.TwoFactorRepository
+	keys          *jwtkeys.KeySet
+	notifier      notify.Notifier
+	mail          *MailQueue
+	guard         *LoginGuard
+	cfg           AuthConfig
+	log           *slog.Logger
 }
 
-func NewAuthService(userRepo *data.UserRepository, jwtSecret string) *AuthService {
-	return &AuthService{userRepo: userRepo, jwtSecret: jwtSecret}
+func NewAuthService(db *pgxpool.Pool, userRepo *data.UserRepository, tokenRepo *data.TokenRepository, twoFactorRepo *data.TwoFactorRepository, keys *jwtkeys.KeySet, notifier notify.Notifier, mail *MailQueue, guard *LoginGuard, cfg AuthConfig, log *slog.Logger) *AuthService {
+	dummyPasswordHash()
+	cfg.AppBaseURL = strings.TrimRight(cfg.AppBaseURL, "/")
+	return &AuthService{
+		db:            db,
+		userRepo:      userRepo,
+		tokenRepo:     tokenRepo,
+		twoFactorRepo: twoFactorRepo,
+		keys:          keys,
+		notifier:      notifier,
+		mail:          mail,
+		guard:         guard,
+		cfg:           cfg,
+		log:           log,
+	}
 }
 
 func (s *AuthService) Register(ctx context.Context, name, email, password string) (*data.User, error) {
@@ -37,30 +107,404 @@ func (s *AuthService) Register(ctx context.Context, name, email, password string
 		return nil, err
 	}
 
+	// The account exists either way; a failed email can be re-sent.
+	if err := s.sendVerification(ctx, user); err != nil {
+		s.log.Error("failed to send verification email", "user_id", user.ID, "error", err)
+	}
+
 	return user, nil
 }
 
-func (s *AuthService) Login(ctx context.Context, email, password string) (string, error) {
+// ResendVerification mails a fresh verification link, invalidating older ones.
+func (s *AuthService) ResendVerification(ctx context.Context, userID string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return ErrAlreadyVerified
+	}
+	return s.sendVerification(ctx, user)
+}
+
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	userID, err := s.userRepo.ConsumeToken(ctx, tx, data.TokenPurposeEmailVerification, hashToken(token))
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return ErrInvalidUserToken
+		}
+		return err
+	}
+	if err := s.userRepo.MarkEmailVerified(ctx, tx, userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// RequestPasswordReset mails a reset link if the email belongs to an account.
+// It reports success for unknown addresses so it cannot be used to discover
+// who has an account. Requests are counted per address and a
+// *LoginThrottledError is returned once there are too many. The link is sent
+// through the mail queue: waiting for the mail server would make known
+// addresses answer measurably slower.
+func (s *AuthService) RequestPasswordReset(ctx context.Context, email string) error {
+	if err := s.guard.AttemptReset(ctx, email); err != nil {
+		return err
+	}
 	user, err := s.userRepo.GetByEmail(ctx, email)
 	if err != nil {
-		return "", errors.New("invalid credentials")
+		if errors.Is(err, data.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	err = s.mail.Enqueue(func(ctx context.Context) {
+		if err := s.sendUserToken(ctx, user, data.TokenPurposePasswordReset, passwordResetTTL, "auth.password_reset", "reset-password"); err != nil {
+			s.log.Error("failed to send password reset email", "user_id", user.ID, "error", err)
+		}
+	})
+	if err != nil {
+		s.log.Warn("password reset email not queued", "user_id", user.ID, "error", err)
+	}
+	return nil
+}
+
+// ForcePasswordReset blocks password logins for the user until they choose a
+// new password, ends their sessions and emails them a reset link.
+func (s *AuthService) ForcePasswordReset(ctx context.Context, userID string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback(ctx)
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
+	if err := s.userRepo.RequirePasswordReset(ctx, tx, userID); err != nil {
+		return err
+	}
+	if _, err := s.tokenRepo.RevokeUserFamilies(ctx, tx, userID, data.RevokedPassword); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
-		return "", errors.New("invalid credentials")
+		return err
 	}
+	return s.sendUserToken(ctx, user, data.TokenPurposePasswordReset, passwordResetTTL, "auth.password_reset", "reset-password")
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub":  user.ID,
-		"role": user.Role,
-		"exp":  time.Now().Add(time.Hour * 24).Unix(),
+// ResetPassword sets a new password using a reset token and signs the user
+// out everywhere. Completing a reset also proves control of the mailbox, so
+// the email address counts as verified.
+func (s *AuthService) ResetPassword(ctx context.Context, token, password string) error {
+	if len(password) < minPasswordLength {
+		return ErrWeakPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	userID, err := s.userRepo.ConsumeToken(ctx, tx, data.TokenPurposePasswordReset, hashToken(token))
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return ErrInvalidUserToken
+		}
+		return err
+	}
+	if err := s.userRepo.SetPassword(ctx, tx, userID, string(hash)); err != nil {
+		return err
+	}
+	if err := s.userRepo.MarkEmailVerified(ctx, tx, userID); err != nil {
+		return err
+	}
+	if _, err := s.tokenRepo.RevokeUserFamilies(ctx, tx, userID, data.RevokedPassword); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	s.log.Info("password reset", "user_id", userID)
+	return nil
+}
+
+func (s *AuthService) sendVerification(ctx context.Context, user *data.User) error {
+	return s.sendUserToken(ctx, user, data.TokenPurposeEmailVerification, emailVerificationTTL, "auth.verify_email", "verify-email")
+}
+
+// sendUserToken mails a single-use token directly rather than through the
+// outbox, so the plaintext token is never written to the database.
+func (s *AuthService) sendUserToken(ctx context.Context, user *data.User, purpose string, ttl time.Duration, template, action string) error {
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.CreateToken(ctx, user.ID, purpose, hashToken(token), time.Now().Add(ttl)); err != nil {
+		return err
+	}
+
+	link := s.cfg.AppBaseURL + "/?" + url.Values{"action": {action}, "token": {token}}.Encode()
+	msg, err := notify.Render(template, user.Email, map[string]any{
+		"name":       user.Name,
+		"link":       link,
+		"expires_in": formatHours(ttl),
 	})
+	if err != nil {
+		return err
+	}
+	return s.notifier.Send(ctx, msg)
+}
+
+// LoginResult is either a token pair or, for accounts with two-factor
+// enabled, a challenge to complete with VerifyLoginChallenge.
+type LoginResult struct {
+	*TokenPair
+	MFARequired        bool       `json:"mfa_required,omitempty"`
+	ChallengeToken     string     `json:"challenge_token,omitempty"`
+	ChallengeExpiresAt *time.Time `json:"challenge_expires_at,omitempty"`
+}
 
-	tokenString, err := token.SignedString([]byte(s.jwtSecret))
+// Login checks the credentials and starts a new session. ip is the client
+// address used for per-IP attempt limits.
+func (s *AuthService) Login(ctx context.Context, email, password, ip string) (*LoginResult, error) {
+	user, err := s.authenticate(ctx, email, password, ip)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled {
+		return s.createLoginChallenge(ctx, user.ID, false)
+	}
+	tokens, err := s.startSession(ctx, user.ID, user.Role, false, false)
 	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{TokenPair: tokens}, nil
+}
+
+// authenticate verifies a password under the login guard, which counts the
+// attempt before the comparison. Unknown emails still pay for a bcrypt
+// comparison so response times do not reveal which addresses are
+// registered.
+func (s *AuthService) authenticate(ctx context.Context, email, password, ip string) (*data.User, error) {
+	if err := s.guard.Attempt(ctx, email, ip); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil && !errors.Is(err, data.ErrNotFound) {
+		return nil, err
+	}
+
+	hash := dummyPasswordHash()
+	if user != nil {
+		hash = []byte(user.PasswordHash)
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil || user == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	// With two-factor on, the attempt stays counted until the second
+	// factor is proven too.
+	if !user.TwoFactorEnabled {
+		if err := s.guard.RecordSuccess(ctx, email, ip); err != nil {
+			s.log.Error("failed to clear login failures", "error", err)
+		}
+	}
+	// Account state is only revealed once the password has been proven.
+	if user.DisabledAt != nil {
+		return nil, ErrAccountDisabled
+	}
+	if user.PasswordResetRequired {
+		return nil, ErrPasswordResetNeeded
+	}
+	return user, nil
+}
+
+// startSession opens a new token family for the user and returns its first
+// token pair. sso and mfa say how the user signed in, as for CreateFamily.
+func (s *AuthService) startSession(ctx context.Context, userID, role string, sso, mfa bool) (*TokenPair, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	familyID, err := s.tokenRepo.CreateFamily(ctx, tx, userID, sso, mfa)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, refreshExpiresAt, err := s.issueRefreshToken(ctx, tx, familyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.newTokenPair(userID, role, familyID, sso, mfa, refreshToken, refreshExpiresAt)
+}
+
+// Refresh rotates a refresh token: the presented token is spent and a new
+// one in the same session is returned. Presenting a token that was already
+// spent means it leaked, so the whole session is revoked.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	stored, err := s.tokenRepo.GetRefreshTokenForUpdate(ctx, tx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	if stored.FamilyRevokedAt != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if stored.UsedAt != nil {
+		if err := s.tokenRepo.RevokeFamily(ctx, tx, stored.FamilyID, data.RevokedReuse); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	if !stored.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if err := s.tokenRepo.MarkRefreshTokenUsed(ctx, tx, stored.ID); err != nil {
+		return nil, err
+	}
+	next, nextExpiresAt, err := s.issueRefreshToken(ctx, tx, stored.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.newTokenPair(stored.UserID, stored.Role, stored.FamilyID, stored.SSO, stored.MFA, next, nextExpiresAt)
+}
+
+// Logout ends the session the refresh token belongs to.
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+	return s.tokenRepo.RevokeFamilyByToken(ctx, hashToken(refreshToken), data.RevokedLogout)
+}
+
+// LogoutAll ends every session of the user and returns how many were active.
+func (s *AuthService) LogoutAll(ctx context.Context, userID string) (int64, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	revoked, err := s.tokenRepo.RevokeUserFamilies(ctx, tx, userID, data.RevokedLogoutAll)
+	if err != nil {
+		return 0, err
+	}
+	return revoked, tx.Commit(ctx)
+}
+
+func (s *AuthService) issueRefreshToken(ctx context.Context, tx pgx.Tx, familyID string) (string, time.Time, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := time.Now().Add(s.cfg.RefreshTTL)
+	if err := s.tokenRepo.CreateRefreshToken(ctx, tx, familyID, hashToken(token), expiresAt); err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// newTokenPair signs an access token for the session. The amr claim lists
+// how the user authenticated: "pwd", or "fed" for a sign-in at the identity
+// provider, plus "otp" when a second factor was used.
+func (s *AuthService) newTokenPair(userID, role, sessionID string, sso, mfa bool, refreshToken string, refreshExpiresAt time.Time) (*TokenPair, error) {
+	jti, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	expiresAt := now.Add(s.cfg.AccessTTL)
+	amr := []string{"pwd"}
+	if sso {
+		amr = []string{"fed"}
+	}
+	if mfa {
+		amr = append(amr, "otp")
+	}
+
+	tokenString, err := s.keys.Sign(jwt.MapClaims{
+		"iss":  s.cfg.Issuer,
+		"sub":  userID,
+		"role": role,
+		"sid":  sessionID,
+		"amr":  amr,
+		"jti":  jti,
+		"iat":  now.Unix(),
+		"exp":  expiresAt.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:      tokenString,
+		AccessExpiresAt:  expiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt,
+	}, nil
+}
+
+// dummyPasswordHash is compared against when the email is unknown. It is
+// computed once, at DefaultCost like real hashes, so both paths cost the same.
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte("evently-no-such-user"), bcrypt.DefaultCost)
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
 		return "", err
 	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken is how refresh tokens are stored, so a database leak does not
+// hand out usable tokens.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
 
-	return tokenString, nil
+func formatHours(d time.Duration) string {
+	hours := int(d / time.Hour)
+	if hours == 1 {
+		return "1 hour"
+	}
+	return fmt.Sprintf("%d hours", hours)
 }
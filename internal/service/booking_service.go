@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 	"evently/internal/data"
+	"evently/internal/seating"
 	"log/slog"
+	"slices"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -14,29 +17,91 @@ var (
 	ErrBookingConflict = errors.New("booking conflict, please try again")
 	ErrJoinWaitlist    = errors.New("tickets are reserved for the waitlist, you have been added to the queue")
 	ErrAddedToWaitlist = errors.New("not enough tickets available, you have been added to the waitlist")
+	ErrEventNotOpen    = errors.New("this event is not open for booking")
+	ErrNoAdjacentSeats = errors.New("no block of adjacent seats is available, set allow_split to accept separate seats")
 	MaxRetries         = 3
 )
 
 type BookingRepo interface {
 	GetEventForUpdate(ctx context.Context, eventID string) (*data.EventForUpdate, error)
-	CreateBooking(ctx context.Context, event *data.EventForUpdate, userID string, quantity int) error
-	HasWaitlist(ctx context.Context, eventID string) (bool, error)
-	AddToWaitlist(ctx context.Context, eventID, userID string, quantity int) error
+	HasTiers(ctx context.Context, eventID string) (bool, error)
+	GetTierForUpdate(ctx context.Context, eventID, tierID string) (*data.TicketTier, error)
+	CreateBooking(ctx context.Context, event *data.EventForUpdate, tier *data.TicketTier, userID string, quantity int) error
+	HasSeatMap(ctx context.Context, eventID string) (bool, error)
+	ListSeats(ctx context.Context, eventID string) ([]data.EventSeat, error)
+	ListTiers(ctx context.Context, eventID string) ([]data.TicketTier, error)
+	BookSeats(ctx context.Context, eventID string, tierID *string, userID string, seatIDs []string) (*data.SeatBooking, error)
+	HasWaitlist(ctx context.Context, eventID string, tierID *string) (bool, error)
+	AddToWaitlist(ctx context.Context, eventID string, tierID *string, userID string, quantity int) error
 	CancelBooking(ctx context.Context, bookingID, userID string, quantity int) error
 	GetUserBookings(ctx context.Context, userID string) ([]data.UserBooking, error)
+	ListEventBookings(ctx context.Context, eventID string, after *data.EventBookingCursor, limit int) ([]data.EventBooking, error)
+}
+
+// EmailVerifier reports whether a user has confirmed their email address.
+// Services given a nil EmailVerifier do not require verification.
+type EmailVerifier interface {
+	IsEmailVerified(ctx context.Context, userID string) (bool, error)
+}
+
+func requireVerifiedEmail(ctx context.Context, verifier EmailVerifier, userID string) error {
+	if verifier == nil {
+		return nil
+	}
+	verified, err := verifier.IsEmailVerified(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !verified {
+		return ErrEmailNotVerified
+	}
+	return nil
 }
 
 type BookingService struct {
-	repo BookingRepo
-	log  *slog.Logger
+	repo     BookingRepo
+	verifier EmailVerifier
+	log      *slog.Logger
+}
+
+func NewBookingService(repo BookingRepo, verifier EmailVerifier, log *slog.Logger) *BookingService {
+	return &BookingService{repo: repo, verifier: verifier, log: log}
 }
 
-func NewBookingService(repo BookingRepo, log *slog.Logger) *BookingService {
-	return &BookingService{repo: repo, log: log}
+// SeatPreferences narrow the seats picked for a quantity booking at an
+// event with reserved seating. The zero value accepts any seats, as long
+// as they are together.
+type SeatPreferences struct {
+	Sections      []string
+	MaxPriceCents *int64
+	AllowSplit    bool
 }
 
-func (s *BookingService) CreateBooking(ctx context.Context, eventID, userID string, quantity int) error {
-	hasWaitlist, err := s.repo.HasWaitlist(ctx, eventID)
+// CreateBooking books quantity tickets, from tierID when the event sells by
+// tier. When the tickets are not available the user joins the waitlist for
+// the same tier instead. At events with reserved seating the best available
+// seats are picked, see bookBestAvailable, and returned with the booking.
+func (s *BookingService) CreateBooking(ctx context.Context, eventID, userID, tierID string, quantity int, prefs SeatPreferences) (*data.SeatBooking, error) {
+	if err := requireVerifiedEmail(ctx, s.verifier, userID); err != nil {
+		return nil, err
+	}
+
+	seated, err := s.repo.HasSeatMap(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if seated {
+		return s.bookBestAvailable(ctx, eventID, userID, tierID, quantity, prefs)
+	}
+	return nil, s.bookGeneralAdmission(ctx, eventID, userID, tierID, quantity)
+}
+
+func (s *BookingService) bookGeneralAdmission(ctx context.Context, eventID, userID, tierID string, quantity int) error {
+	tierRef, err := checkTierChoice(ctx, s.repo, eventID, tierID)
+	if err != nil {
+		return err
+	}
+	hasWaitlist, err := s.repo.HasWaitlist(ctx, eventID, tierRef)
 	if err != nil {
 		return err
 	}
@@ -46,26 +111,33 @@ func (s *BookingService) CreateBooking(ctx context.Context, eventID, userID stri
 		if err != nil {
 			return err
 		}
+		if event.Status != data.EventStatusScheduled {
+			return ErrEventNotOpen
+		}
+		tier, available, err := loadTier(ctx, s.repo, event, tierRef)
+		if err != nil {
+			return err
+		}
 
-		if hasWaitlist && (event.BookedTickets < event.Capacity) {
+		if hasWaitlist && available > 0 {
 			// This case is for when a ticket is cancelled, but a waitlist still exists.
 			// The spot should be reserved for the waitlist.
-			if err := s.repo.AddToWaitlist(ctx, eventID, userID, quantity); err != nil {
+			if err := s.repo.AddToWaitlist(ctx, eventID, tierRef, userID, quantity); err != nil {
 				return err
 			}
 			return ErrJoinWaitlist
 		}
 
-		if (event.BookedTickets + quantity) > event.Capacity {
-			if err := s.repo.AddToWaitlist(ctx, eventID, userID, quantity); err != nil {
+		if quantity > available {
+			if err := s.repo.AddToWaitlist(ctx, eventID, tierRef, userID, quantity); err != nil {
 				return err
 			}
 			return ErrAddedToWaitlist
 		}
 
-		err = s.repo.CreateBooking(ctx, event, userID, quantity)
+		err = s.repo.CreateBooking(ctx, event, tier, userID, quantity)
 		if err == nil {
-			s.log.Info("booking successful", "user_id", userID, "event_id", eventID, "quantity", quantity)
+			s.log.Info("booking successful", "user_id", userID, "event_id", eventID, "tier_id", tierID, "quantity", quantity)
 			return nil
 		}
 
@@ -78,6 +150,160 @@ func (s *BookingService) CreateBooking(ctx context.Context, eventID, userID stri
 	return ErrBookingConflict
 }
 
+// bookBestAvailable picks quantity seats with the seating allocator and
+// books them. Without a tier, seats of any tier on sale may be picked, all
+// from the same tier. If another booking takes one of the seats first, the
+// seats are picked again.
+func (s *BookingService) bookBestAvailable(ctx context.Context, eventID, userID, tierID string, quantity int, prefs SeatPreferences) (*data.SeatBooking, error) {
+	event, err := s.repo.GetEventForUpdate(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event.Status != data.EventStatusScheduled {
+		return nil, ErrEventNotOpen
+	}
+	tiers, err := s.repo.ListTiers(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	onSale := map[string]data.TicketTier{}
+	now := time.Now()
+	for _, tier := range tiers {
+		if tierID != "" && tier.ID != tierID {
+			continue
+		}
+		if !tier.SellingAt(now) {
+			if tier.ID == tierID {
+				return nil, ErrTierNotOnSale
+			}
+			continue
+		}
+		onSale[tier.ID] = tier
+	}
+	if tierID != "" && !slices.ContainsFunc(tiers, func(t data.TicketTier) bool { return t.ID == tierID }) {
+		return nil, ErrTierNotFound
+	}
+
+	request := seating.Request{
+		Quantity:      quantity,
+		Sections:      prefs.Sections,
+		MaxPriceCents: prefs.MaxPriceCents,
+		AllowSplit:    prefs.AllowSplit,
+	}
+	for i := 0; i < MaxRetries; i++ {
+		seats, err := s.repo.ListSeats(ctx, eventID)
+		if err != nil {
+			return nil, err
+		}
+		picked, err := seating.Allocate(allocatorSeats(seats, tiers, onSale), request)
+		if err != nil {
+			switch {
+			case errors.Is(err, seating.ErrNotEnoughSeats):
+				return nil, ErrEventSoldOut
+			case errors.Is(err, seating.ErrNoBlock):
+				return nil, ErrNoAdjacentSeats
+			}
+			return nil, err
+		}
+
+		seatIDs := make([]string, len(picked))
+		for i, seat := range picked {
+			seatIDs[i] = seat.ID
+		}
+		var tierRef *string
+		if picked[0].Group != "" {
+			tierRef = &picked[0].Group
+		}
+		booking, err := s.repo.BookSeats(ctx, eventID, tierRef, userID, seatIDs)
+		if err == nil {
+			s.log.Info("best available seats booked", "user_id", userID, "event_id", eventID, "booking_id", booking.BookingID, "seats", len(seatIDs))
+			return booking, nil
+		}
+		if errors.Is(err, data.ErrConflict) {
+			s.log.Warn("seat taken while booking, picking again", "attempt", i+1, "event_id", eventID)
+			continue
+		}
+		return nil, err
+	}
+	return nil, ErrBookingConflict
+}
+
+// allocatorSeats converts the event's seats for the allocator. Seats in
+// tiers that are not on sale, or not the chosen tier, count as taken; each
+// tier's seats are kept in their own group.
+func allocatorSeats(seats []data.EventSeat, tiers []data.TicketTier, onSale map[string]data.TicketTier) []seating.Seat {
+	out := make([]seating.Seat, len(seats))
+	for i, seat := range seats {
+		out[i] = seating.Seat{
+			ID:          seat.SeatID,
+			SectionID:   seat.SectionID,
+			SectionRank: seat.SectionRank,
+			RowPosition: seat.RowPosition,
+			Row:         seat.Row,
+			Number:      seat.Number,
+			Available:   seat.Status == data.SeatAvailable,
+		}
+		if len(tiers) == 0 {
+			continue
+		}
+		var tier data.TicketTier
+		ok := false
+		if seat.TierID != nil {
+			tier, ok = onSale[*seat.TierID]
+		}
+		out[i].Available = out[i].Available && ok
+		out[i].Group = tier.ID
+		out[i].PriceCents = tier.PriceCents
+	}
+	return out
+}
+
+// BookSeats books specific seats of an event with reserved seating. Seats
+// are taken atomically: when another booking gets to one of them first the
+// whole booking fails with ErrSeatTaken, and there is no waitlist.
+func (s *BookingService) BookSeats(ctx context.Context, eventID, userID, tierID string, seatIDs []string) (*data.SeatBooking, error) {
+	if err := requireVerifiedEmail(ctx, s.verifier, userID); err != nil {
+		return nil, err
+	}
+
+	event, err := s.repo.GetEventForUpdate(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event.Status != data.EventStatusScheduled {
+		return nil, ErrEventNotOpen
+	}
+	seated, err := s.repo.HasSeatMap(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if !seated {
+		return nil, ErrNotSeated
+	}
+	tierRef, err := checkTierChoice(ctx, s.repo, eventID, tierID)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := loadTier(ctx, s.repo, event, tierRef); err != nil {
+		return nil, err
+	}
+
+	slices.Sort(seatIDs)
+	seatIDs = slices.Compact(seatIDs)
+	booking, err := s.repo.BookSeats(ctx, eventID, tierRef, userID, seatIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNotFound):
+			return nil, ErrSeatNotFound
+		case errors.Is(err, data.ErrConflict):
+			return nil, ErrSeatTaken
+		}
+		return nil, err
+	}
+	s.log.Info("seats booked", "user_id", userID, "event_id", eventID, "booking_id", booking.BookingID, "seats", len(seatIDs))
+	return booking, nil
+}
+
 func (s *BookingService) CancelBooking(ctx context.Context, bookingID, userID string, quantity int) error {
 	if quantity <= 0 {
 		return errors.New("quantity to cancel must be positive")
@@ -90,6 +316,10 @@ func (s *BookingService) GetUserBookings(ctx context.Context, userID string) ([]
 	return s.repo.GetUserBookings(ctx, userID)
 }
 
+func (s *BookingService) ListEventBookings(ctx context.Context, eventID string, after *data.EventBookingCursor, limit int) ([]data.EventBooking, error) {
+	return s.repo.ListEventBookings(ctx, eventID, after, limit)
+}
+
 // --- Interface Implementation ---
 type BookingRepositoryWithTx struct {
 	DB *pgxpool.Pool
@@ -103,34 +333,29 @@ func (r *BookingRepositoryWithTx) CancelBooking(ctx context.Context, bookingID,
 	}
 	defer tx.Rollback(ctx)
 
-	eventID, qtyCancelled, err := r.UpdateBookingForCancellation(ctx, tx, bookingID, userID, quantity)
+	eventID, tierID, qtyCancelled, err := r.UpdateBookingForCancellation(ctx, tx, bookingID, userID, quantity)
 	if err != nil {
 		return err
 	}
 
-	ticketsAvailable := qtyCancelled
-	for ticketsAvailable > 0 {
-		waitlister, err := r.FindAndRemoveMatchingWaitlistEntry(ctx, tx, eventID, ticketsAvailable)
-		if err != nil {
-			return err
-		}
-		if waitlister == nil {
-			break
-		}
+	if err := r.DecrementEventTickets(ctx, tx, eventID, tierID, qtyCancelled); err != nil {
+		return err
+	}
 
-		insertQuery := `INSERT INTO bookings (user_id, event_id, quantity) VALUES ($1, $2, $3)`
-		if _, err := tx.Exec(ctx, insertQuery, waitlister.UserID, eventID, waitlister.Quantity); err != nil {
-			return err
-		}
-		slog.Default().Info("auto-booked tickets for waitlisted user", "user_id", waitlister.UserID, "quantity", waitlister.Quantity)
-		ticketsAvailable -= waitlister.Quantity
+	fields := map[string]any{"booking_id": bookingID, "quantity": qtyCancelled}
+	if err := data.EnqueueNotification(ctx, tx, data.NotificationBookingCancelled, userID, eventID, fields); err != nil {
+		return err
+	}
+	payload := map[string]any{"booking_id": bookingID, "event_id": eventID, "tier_id": tierID, "user_id": userID, "quantity": qtyCancelled}
+	if err := data.EmitWebhook(ctx, tx, data.WebhookBookingCancelled, payload); err != nil {
+		return err
 	}
 
-	if ticketsAvailable > 0 {
-		if err := r.DecrementEventTickets(ctx, tx, eventID, ticketsAvailable); err != nil {
-			return err
-		}
+	promoted, err := r.PromoteFromWaitlist(ctx, tx, eventID)
+	if err != nil {
+		return err
 	}
+	logPromotions(slog.Default(), eventID, promoted)
 
 	return tx.Commit(ctx)
 }
@@ -139,6 +364,6 @@ func (r *BookingRepositoryWithTx) GetUserBookings(ctx context.Context, userID st
 	return r.BookingRepository.GetByUserID(ctx, userID)
 }
 
-func (r *BookingRepositoryWithTx) CreateBooking(ctx context.Context, event *data.EventForUpdate, userID string, quantity int) error {
-	return r.BookingRepository.CreateBooking(ctx, event, userID, quantity)
+func (r *BookingRepositoryWithTx) CreateBooking(ctx context.Context, event *data.EventForUpdate, tier *data.TicketTier, userID string, quantity int) error {
+	return r.BookingRepository.CreateBooking(ctx, event, tier, userID, quantity)
 }
@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"evently/internal/data"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrCapacityBelowBooked = errors.New("capacity cannot be lower than the number of booked and held tickets")
+	ErrInvalidStatus       = errors.New("status must be one of scheduled, postponed or completed")
+	ErrEventCancelled      = errors.New("event has been cancelled")
+	ErrEventHasBookings    = errors.New("event has booking history, cancel it instead of deleting it")
+	ErrInvalidWaitlist     = errors.New("waitlist_policy must be auto_book or offer and waitlist_offer_minutes must be positive")
+	ErrVenueNotFound       = errors.New("venue_id does not refer to a venue")
+	ErrVenueLocked         = errors.New("the venue of an event with reserved seating cannot be changed")
+)
+
+// NewEvent is an event to create. With VenueID the event takes the venue's
+// name, and its default capacity when Capacity is nil. Otherwise Venue is
+// matched against the known venues by name and linked when one matches.
+type NewEvent struct {
+	Name           string
+	Venue          string
+	VenueID        *string
+	StartTime      time.Time
+	Capacity       *int
+	WaitlistPolicy string
+	OfferMinutes   int
+}
+
+// EventUpdate carries the fields an admin wants to change. Nil fields are left
+// untouched; Version must match the stored row for the update to apply. An
+// empty VenueID unlinks the event from its venue but keeps the venue name.
+type EventUpdate struct {
+	Name      *string
+	Venue     *string
+	VenueID   *string
+	StartTime *time.Time
+	Capacity  *int
+	Status    *string
+	// WaitlistPolicy and OfferMinutes configure how freed tickets reach the waitlist.
+	WaitlistPolicy *string
+	OfferMinutes   *int
+	Version        int
+}
+
+type EventService struct {
+	db          *pgxpool.Pool
+	eventRepo   *data.EventRepository
+	bookingRepo *data.BookingRepository
+	venueRepo   *data.VenueRepository
+	log         *slog.Logger
+}
+
+func NewEventService(db *pgxpool.Pool, eventRepo *data.EventRepository, bookingRepo *data.BookingRepository, venueRepo *data.VenueRepository, log *slog.Logger) *EventService {
+	return &EventService{db: db, eventRepo: eventRepo, bookingRepo: bookingRepo, venueRepo: venueRepo, log: log}
+}
+
+func (s *EventService) CreateEvent(ctx context.Context, input NewEvent, ownerID string) (*data.Event, error) {
+	if err := ValidateWaitlistPolicy(input.WaitlistPolicy, input.OfferMinutes); err != nil {
+		return nil, err
+	}
+	event := &data.Event{
+		Name:           input.Name,
+		StartTime:      input.StartTime,
+		WaitlistPolicy: input.WaitlistPolicy,
+		OfferMinutes:   input.OfferMinutes,
+	}
+	venue, err := s.resolveVenue(ctx, event, input.VenueID, input.Venue)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case input.Capacity != nil:
+		event.Capacity = *input.Capacity
+	case venue != nil && venue.DefaultCapacity != nil:
+		event.Capacity = *venue.DefaultCapacity
+	}
+
+	if err := s.eventRepo.Create(ctx, event, ownerID); err != nil {
+		return nil, err
+	}
+	event.VenueDetails = venue
+	return event, nil
+}
+
+// resolveVenue links the event to the venue venueID names, or else to the
+// venue whose name matches name, and sets the event's venue name. It
+// returns the linked venue, or nil when the event is left unlinked.
+func (s *EventService) resolveVenue(ctx context.Context, event *data.Event, venueID *string, name string) (*data.Venue, error) {
+	venue, err := findVenue(ctx, s.venueRepo, venueID, name)
+	if err != nil {
+		return nil, err
+	}
+	if venue == nil {
+		event.VenueID = nil
+		event.Venue = strings.TrimSpace(name)
+		return nil, nil
+	}
+	event.VenueID = &venue.ID
+	event.Venue = venue.Name
+	return venue, nil
+}
+
+// findVenue returns the venue venueID names, or else the venue whose name
+// matches name, or nil when neither is given or no venue has that name.
+func findVenue(ctx context.Context, venues *data.VenueRepository, venueID *string, name string) (*data.Venue, error) {
+	switch {
+	case venueID != nil && *venueID != "":
+		venue, err := venues.GetDetails(ctx, *venueID)
+		if errors.Is(err, data.ErrNotFound) {
+			return nil, ErrVenueNotFound
+		}
+		return venue, err
+	case strings.TrimSpace(name) != "":
+		venue, err := venues.FindByName(ctx, name)
+		if errors.Is(err, data.ErrNotFound) {
+			return nil, nil
+		}
+		return venue, err
+	}
+	return nil, nil
+}
+
+func (s *EventService) UpdateEvent(ctx context.Context, id string, update EventUpdate) (*data.Event, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	event, err := s.eventRepo.GetForUpdate(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if event.Version != update.Version {
+		return nil, data.ErrConflict
+	}
+	if event.Status == data.EventStatusCancelled {
+		return nil, ErrEventCancelled
+	}
+
+	previousCapacity := event.Capacity
+	if update.Name != nil {
+		event.Name = *update.Name
+	}
+	previousVenue := event.VenueID
+	switch {
+	case update.VenueID != nil && *update.VenueID == "":
+		event.VenueID = nil
+		if update.Venue != nil {
+			event.Venue = *update.Venue
+		}
+	case update.VenueID != nil || update.Venue != nil:
+		name := event.Venue
+		if update.Venue != nil {
+			name = *update.Venue
+		}
+		if _, err := s.resolveVenue(ctx, event, update.VenueID, name); err != nil {
+			return nil, err
+		}
+	}
+	if event.ReservedSeating && !sameVenue(previousVenue, event.VenueID) {
+		return nil, ErrVenueLocked
+	}
+	if update.StartTime != nil {
+		event.StartTime = *update.StartTime
+	}
+	if update.Capacity != nil {
+		event.Capacity = *update.Capacity
+	}
+	if update.Status != nil {
+		switch *update.Status {
+		case data.EventStatusScheduled, data.EventStatusPostponed, data.EventStatusCompleted:
+			event.Status = *update.Status
+		default:
+			return nil, ErrInvalidStatus
+		}
+	}
+	if update.WaitlistPolicy != nil {
+		event.WaitlistPolicy = *update.WaitlistPolicy
+	}
+	if update.OfferMinutes != nil {
+		event.OfferMinutes = *update.OfferMinutes
+	}
+	if err := ValidateWaitlistPolicy(event.WaitlistPolicy, event.OfferMinutes); err != nil {
+		return nil, err
+	}
+	if event.Capacity < event.BookedTickets+event.HeldTickets {
+		return nil, ErrCapacityBelowBooked
+	}
+
+	if err := s.eventRepo.Update(ctx, tx, event); err != nil {
+		return nil, err
+	}
+
+	if event.Capacity > previousCapacity {
+		promoted, err := s.bookingRepo.PromoteFromWaitlist(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		logPromotions(s.log, id, promoted)
+		if len(promoted) > 0 {
+			if event, err = s.eventRepo.GetForUpdate(ctx, tx, id); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func sameVenue(a, b *string) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
+
+func ValidateWaitlistPolicy(policy string, offerMinutes int) error {
+	if policy != data.WaitlistPolicyAutoBook && policy != data.WaitlistPolicyOffer {
+		return ErrInvalidWaitlist
+	}
+	if offerMinutes <= 0 {
+		return ErrInvalidWaitlist
+	}
+	return nil
+}
+
+// DeleteEvent only removes events nobody has booked. Any booking bumps the
+// event version, so a booking racing this check makes the delete conflict.
+func (s *EventService) DeleteEvent(ctx context.Context, id string, version int) error {
+	hasBookings, err := s.eventRepo.HasBookings(ctx, id)
+	if err != nil {
+		return err
+	}
+	if hasBookings {
+		return ErrEventHasBookings
+	}
+	return s.eventRepo.Delete(ctx, id, version)
+}
+
+func (s *EventService) CancelEvent(ctx context.Context, id, cancelledBy, reason string) (*data.EventCancellation, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	event, err := s.eventRepo.GetForUpdate(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if event.Status == data.EventStatusCancelled {
+		return nil, ErrEventCancelled
+	}
+
+	cancellation, err := s.eventRepo.Cancel(ctx, tx, id, cancelledBy, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	s.log.Info("event cancelled", "event_id", id, "cancelled_by", cancelledBy, "affected", len(cancellation.Recipients))
+	return cancellation, nil
+}
+
+func (s *EventService) GetCancellation(ctx context.Context, id string) (*data.EventCancellation, error) {
+	return s.eventRepo.GetCancellation(ctx, id)
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"evently/internal/data"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrHoldNotActive = errors.New("hold has already been confirmed or released")
+	ErrHoldExpired   = errors.New("hold has expired")
+)
+
+const sweepBatchSize = 100
+
+type HoldService struct {
+	db          *pgxpool.Pool
+	holdRepo    *data.HoldRepository
+	bookingRepo *data.BookingRepository
+	verifier    EmailVerifier
+	ttl         time.Duration
+	log         *slog.Logger
+}
+
+func NewHoldService(db *pgxpool.Pool, holdRepo *data.HoldRepository, bookingRepo *data.BookingRepository, verifier EmailVerifier, ttl time.Duration, log *slog.Logger) *HoldService {
+	return &HoldService{db: db, holdRepo: holdRepo, bookingRepo: bookingRepo, verifier: verifier, ttl: ttl, log: log}
+}
+
+// CreateHold reserves tickets for the hold TTL. Unlike CreateBooking it never
+// joins the waitlist: if the tickets are not free right now the hold fails.
+func (s *HoldService) CreateHold(ctx context.Context, eventID, userID, tierID string, quantity int) (*data.Hold, error) {
+	if err := requireVerifiedEmail(ctx, s.verifier, userID); err != nil {
+		return nil, err
+	}
+
+	seated, err := s.bookingRepo.HasSeatMap(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if seated {
+		return nil, ErrSeatsRequired
+	}
+	tierRef, err := checkTierChoice(ctx, s.bookingRepo, eventID, tierID)
+	if err != nil {
+		return nil, err
+	}
+	hasWaitlist, err := s.bookingRepo.HasWaitlist(ctx, eventID, tierRef)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < MaxRetries; i++ {
+		event, err := s.bookingRepo.GetEventForUpdate(ctx, eventID)
+		if err != nil {
+			return nil, err
+		}
+		if event.Status != data.EventStatusScheduled {
+			return nil, ErrEventNotOpen
+		}
+		tier, available, err := loadTier(ctx, s.bookingRepo, event, tierRef)
+		if err != nil {
+			return nil, err
+		}
+		if hasWaitlist || quantity > available {
+			return nil, ErrEventSoldOut
+		}
+
+		hold, err := s.holdRepo.Create(ctx, event, tier, userID, quantity, s.ttl)
+		if err == nil {
+			s.log.Info("tickets held", "hold_id", hold.ID, "user_id", userID, "event_id", eventID, "quantity", quantity)
+			return hold, nil
+		}
+
+		if errors.Is(err, data.ErrConflict) {
+			s.log.Warn("hold conflict detected, retrying...", "attempt", i+1, "event_id", eventID)
+			continue
+		}
+		return nil, err
+	}
+	return nil, ErrBookingConflict
+}
+
+// ConfirmHold books the held tickets. The event is locked first so it
+// cannot be cancelled or rescheduled while the booking is made, and only a
+// scheduled event accepts it.
+func (s *HoldService) ConfirmHold(ctx context.Context, holdID, userID string) (*data.Hold, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	status, err := s.holdRepo.LockEventStatus(ctx, tx, holdID, userID)
+	if err != nil {
+		return nil, err
+	}
+	hold, err := s.holdRepo.GetForUpdate(ctx, tx, holdID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if hold.Status != data.HoldStatusActive {
+		return nil, ErrHoldNotActive
+	}
+	if !hold.ExpiresAt.After(time.Now()) {
+		return nil, ErrHoldExpired
+	}
+	if status != data.EventStatusScheduled {
+		return nil, ErrEventNotOpen
+	}
+
+	if err := s.holdRepo.Confirm(ctx, tx, hold); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	s.log.Info("hold confirmed", "hold_id", hold.ID, "booking_id", *hold.BookingID, "user_id", userID)
+	return hold, nil
+}
+
+func (s *HoldService) ReleaseHold(ctx context.Context, holdID, userID string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	hold, err := s.holdRepo.GetForUpdate(ctx, tx, holdID, userID)
+	if err != nil {
+		return err
+	}
+	if hold.Status != data.HoldStatusActive {
+		return ErrHoldNotActive
+	}
+
+	if err := s.releaseAndPromote(ctx, tx, []data.Hold{*hold}, data.HoldStatusReleased); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ReleaseExpired expires overdue holds in batches and offers the freed tickets
+// to the waitlist. It returns the number of holds released.
+func (s *HoldService) ReleaseExpired(ctx context.Context) (int, error) {
+	released := 0
+	for {
+		tx, err := s.db.Begin(ctx)
+		if err != nil {
+			return released, err
+		}
+
+		holds, err := s.holdRepo.ClaimExpired(ctx, tx, sweepBatchSize)
+		if err == nil && len(holds) > 0 {
+			err = s.releaseAndPromote(ctx, tx, holds, data.HoldStatusExpired)
+		}
+		if err == nil {
+			err = tx.Commit(ctx)
+		}
+		if err != nil {
+			tx.Rollback(ctx)
+			return released, err
+		}
+
+		released += len(holds)
+		if len(holds) < sweepBatchSize {
+			return released, nil
+		}
+	}
+}
+
+// RunSweeper releases expired holds every interval until ctx is cancelled.
+func (s *HoldService) RunSweeper(ctx context.Context, interval time.Duration) {
+	runEvery(ctx, interval, func() {
+		released, err := s.ReleaseExpired(ctx)
+		if err != nil {
+			s.log.Error("failed to release expired holds", "error", err)
+			return
+		}
+		if released > 0 {
+			s.log.Info("released expired holds", "count", released)
+		}
+	})
+}
+
+func (s *HoldService) releaseAndPromote(ctx context.Context, tx pgx.Tx, holds []data.Hold, status string) error {
+	events := make(map[string]bool)
+	for i := range holds {
+		if err := s.holdRepo.Release(ctx, tx, &holds[i], status); err != nil {
+			return err
+		}
+		events[holds[i].EventID] = true
+	}
+
+	for eventID := range events {
+		promoted, err := s.bookingRepo.PromoteFromWaitlist(ctx, tx, eventID)
+		if err != nil {
+			return err
+		}
+		logPromotions(s.log, eventID, promoted)
+	}
+	return nil
+}
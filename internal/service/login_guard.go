@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"evently/internal/data"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// LoginPolicy configures brute-force protection. Each failure beyond
+// FreeAttempts doubles the wait before the next attempt, from BaseDelay up to
+// MaxDelay; reaching the failure limit locks the key for Lockout. Password
+// reset requests are paced the same way, up to MaxResetRequests per address.
+type LoginPolicy struct {
+	MaxAccountFailures int
+	MaxIPFailures      int
+	MaxResetRequests   int
+	Window             time.Duration
+	Lockout            time.Duration
+	FreeAttempts       int
+	BaseDelay          time.Duration
+	MaxDelay           time.Duration
+}
+
+// LoginThrottledError is returned while an account or IP must wait before
+// trying again. Locked distinguishes a lockout from a progressive delay.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("too many failed login attempts, try again in %s", e.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("please wait %s before trying again", e.RetryAfter.Round(time.Second))
+}
+
+type LoginGuard struct {
+	repo   *data.LoginAttemptRepository
+	policy LoginPolicy
+	log    *slog.Logger
+}
+
+func NewLoginGuard(repo *data.LoginAttemptRepository, policy LoginPolicy, log *slog.Logger) *LoginGuard {
+	return &LoginGuard{repo: repo, policy: policy, log: log}
+}
+
+// Attempt counts a login attempt against the client IP and the account
+// before the password is checked, or returns a *LoginThrottledError if
+// either has to wait. Counting up front means parallel requests cannot all
+// pass while the first password is still being compared; an attempt stays
+// counted as a failure unless RecordSuccess takes it back.
+func (g *LoginGuard) Attempt(ctx context.Context, email, ip string) error {
+	for _, k := range g.keys(email, ip) {
+		if err := g.count(ctx, k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AttemptReset counts a password reset request for the address, or returns
+// a *LoginThrottledError if it has to wait. Unknown addresses are counted
+// too, so being throttled says nothing about whether an account exists.
+func (g *LoginGuard) AttemptReset(ctx context.Context, email string) error {
+	return g.count(ctx, loginKey{data.LoginScopeReset, normalizeEmail(email), g.policy.MaxResetRequests})
+}
+
+// count adds the attempt to one counter. The counter is only written if it
+// still holds what was read, and re-read when another attempt got there
+// first, the same way bookings retry on a version conflict.
+func (g *LoginGuard) count(ctx context.Context, k loginKey) error {
+	for i := 0; i < MaxRetries; i++ {
+		f, err := g.repo.Get(ctx, k.scope, k.key)
+		if errors.Is(err, data.ErrNotFound) {
+			f, err = nil, nil
+		}
+		if err != nil {
+			return err
+		}
+
+		failures, lockedUntil, err := g.next(f, k.limit, time.Now())
+		if err != nil {
+			return err
+		}
+		ok, err := g.repo.CountAttempt(ctx, k.scope, k.key, f, failures, lockedUntil)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if lockedUntil != nil {
+			g.log.Warn("login locked after repeated failures", "scope", k.scope, "key", k.key, "failures", failures)
+		}
+		return nil
+	}
+	return &LoginThrottledError{RetryAfter: max(g.policy.BaseDelay, time.Second)}
+}
+
+// next decides whether an attempt may go ahead given the counter as read, f
+// being nil when there is none. It returns the count to store with the
+// attempt included, and the lockout to set if that count reaches limit. A
+// count starts over once the last attempt is older than the window or an
+// earlier lockout has ended.
+func (g *LoginGuard) next(f *data.LoginFailure, limit int, now time.Time) (int, *time.Time, error) {
+	failures := 0
+	if f != nil {
+		if f.LockedUntil != nil && f.LockedUntil.After(now) {
+			return 0, nil, &LoginThrottledError{RetryAfter: f.LockedUntil.Sub(now), Locked: true}
+		}
+		if f.LockedUntil == nil && !f.LastFailedAt.Before(now.Add(-g.policy.Window)) {
+			if wait := f.LastFailedAt.Add(g.delay(f.Failures)).Sub(now); wait > 0 {
+				return 0, nil, &LoginThrottledError{RetryAfter: wait}
+			}
+			failures = f.Failures
+		}
+	}
+
+	failures++
+	if failures < limit {
+		return failures, nil, nil
+	}
+	lockedUntil := now.Add(g.policy.Lockout)
+	return failures, &lockedUntil, nil
+}
+
+// RecordSuccess clears the account's counter. The IP only gets back the
+// attempt that succeeded, so one valid login cannot reset an attacker's
+// budget for other accounts.
+func (g *LoginGuard) RecordSuccess(ctx context.Context, email, ip string) error {
+	if _, err := g.repo.Clear(ctx, data.LoginScopeEmail, normalizeEmail(email)); err != nil {
+		return err
+	}
+	if ip == "" {
+		return nil
+	}
+	return g.repo.Forgive(ctx, data.LoginScopeIP, ip, g.policy.MaxIPFailures)
+}
+
+// Unlock lifts a lockout by email or IP and reports whether one existed.
+// Unlocking an email also lets it request password resets again.
+func (g *LoginGuard) Unlock(ctx context.Context, scope, key string) (bool, error) {
+	if scope != data.LoginScopeEmail {
+		return g.repo.Clear(ctx, scope, key)
+	}
+	key = normalizeEmail(key)
+	cleared, err := g.repo.Clear(ctx, scope, key)
+	if err != nil {
+		return false, err
+	}
+	reset, err := g.repo.Clear(ctx, data.LoginScopeReset, key)
+	return cleared || reset, err
+}
+
+func (g *LoginGuard) ListLocked(ctx context.Context) ([]data.LoginFailure, error) {
+	return g.repo.ListLocked(ctx)
+}
+
+func (g *LoginGuard) delay(failures int) time.Duration {
+	if failures < g.policy.FreeAttempts {
+		return 0
+	}
+	delay := g.policy.BaseDelay
+	for i := g.policy.FreeAttempts; i < failures && delay < g.policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, g.policy.MaxDelay)
+}
+
+type loginKey struct {
+	scope string
+	key   string
+	limit int
+}
+
+func (g *LoginGuard) keys(email, ip string) []loginKey {
+	// The IP goes first so a throttled client does not add to the
+	// account's count.
+	var keys []loginKey
+	if ip != "" {
+		keys = append(keys, loginKey{data.LoginScopeIP, ip, g.policy.MaxIPFailures})
+	}
+	return append(keys, loginKey{data.LoginScopeEmail, normalizeEmail(email), g.policy.MaxAccountFailures})
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"errors"
+	"evently/internal/data"
+	"testing"
+	"time"
+)
+
+var testLoginPolicy = LoginPolicy{
+	MaxAccountFailures: 5,
+	MaxIPFailures:      50,
+	Window:             15 * time.Minute,
+	Lockout:            15 * time.Minute,
+	FreeAttempts:       3,
+	BaseDelay:          time.Second,
+	MaxDelay:           30 * time.Second,
+}
+
+func TestLoginGuardDelay(t *testing.T) {
+	g := &LoginGuard{policy: testLoginPolicy}
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{7, 16 * time.Second},
+		{8, 30 * time.Second},
+		{40, 30 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := g.delay(tt.failures); got != tt.want {
+			t.Errorf("delay(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLoginGuardNext(t *testing.T) {
+	g := &LoginGuard{policy: testLoginPolicy}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) time.Time { return now.Add(-d) }
+	at := func(t time.Time) *time.Time { return &t }
+
+	tests := []struct {
+		name       string
+		seen       *data.LoginFailure
+		want       int
+		wantLock   bool
+		wantWait   time.Duration
+		wantLocked bool
+	}{
+		{name: "first attempt", seen: nil, want: 1},
+		{name: "free attempts are not delayed", seen: &data.LoginFailure{Failures: 2, LastFailedAt: now}, want: 3},
+		{name: "delayed after free attempts", seen: &data.LoginFailure{Failures: 3, LastFailedAt: now}, wantWait: time.Second},
+		{name: "delay has passed", seen: &data.LoginFailure{Failures: 3, LastFailedAt: ago(2 * time.Second)}, want: 4},
+		{name: "reaching the limit locks", seen: &data.LoginFailure{Failures: 4, LastFailedAt: ago(time.Minute)}, want: 5, wantLock: true},
+		{name: "locked", seen: &data.LoginFailure{Failures: 5, LastFailedAt: ago(time.Minute), LockedUntil: at(now.Add(10 * time.Minute))}, wantWait: 10 * time.Minute, wantLocked: true},
+		{name: "ended lockout starts over", seen: &data.LoginFailure{Failures: 5, LastFailedAt: ago(20 * time.Minute), LockedUntil: at(ago(5 * time.Minute))}, want: 1},
+		{name: "outside the window starts over", seen: &data.LoginFailure{Failures: 4, LastFailedAt: ago(16 * time.Minute)}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failures, lockedUntil, err := g.next(tt.seen, testLoginPolicy.MaxAccountFailures, now)
+			if tt.wantWait > 0 {
+				var throttled *LoginThrottledError
+				if !errors.As(err, &throttled) {
+					t.Fatalf("next() error = %v, want *LoginThrottledError", err)
+				}
+				if throttled.RetryAfter != tt.wantWait || throttled.Locked != tt.wantLocked {
+					t.Errorf("next() = wait %s locked %v, want wait %s locked %v", throttled.RetryAfter, throttled.Locked, tt.wantWait, tt.wantLocked)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("next() error = %v", err)
+			}
+			if failures != tt.want {
+				t.Errorf("next() failures = %d, want %d", failures, tt.want)
+			}
+			if (lockedUntil != nil) != tt.wantLock {
+				t.Errorf("next() lockedUntil = %v, want locked %v", lockedUntil, tt.wantLock)
+			}
+			if lockedUntil != nil && !lockedUntil.Equal(now.Add(testLoginPolicy.Lockout)) {
+				t.Errorf("next() lockedUntil = %s, want %s", lockedUntil, now.Add(testLoginPolicy.Lockout))
+			}
+		})
+	}
+}
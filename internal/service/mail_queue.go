@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+)
+
+var ErrMailQueueFull = errors.New("mail queue is full")
+
+// MailQueue sends mail on a fixed number of workers so a request can answer
+// without waiting for the mail server. At most size jobs wait at a time;
+// Enqueue refuses more rather than letting a burst of requests pile up
+// goroutines.
+type MailQueue struct {
+	jobs chan func(context.Context)
+	ctx  context.Context
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+
+	mu     sync.Mutex
+	closed bool
+	log    *slog.Logger
+}
+
+func NewMailQueue(size, workers int, log *slog.Logger) *MailQueue {
+	ctx, stop := context.WithCancel(context.Background())
+	q := &MailQueue{jobs: make(chan func(context.Context), size), ctx: ctx, stop: stop, log: log}
+	for range max(workers, 1) {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+func (q *MailQueue) work() {
+	defer q.wg.Done()
+	for job := range q.jobs {
+		job(q.ctx)
+	}
+}
+
+// Enqueue schedules job without blocking. It returns ErrMailQueueFull when
+// the queue is full or has been shut down.
+func (q *MailQueue) Enqueue(job func(context.Context)) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrMailQueueFull
+	}
+	select {
+	case q.jobs <- job:
+		return nil
+	default:
+		return ErrMailQueueFull
+	}
+}
+
+// Shutdown stops accepting jobs and waits for the queued ones to finish. If
+// ctx ends first, the jobs still running or queued get a cancelled context
+// so they give up instead of holding up the exit.
+func (q *MailQueue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		q.stop()
+		return nil
+	case <-ctx.Done():
+		q.stop()
+		q.log.Warn("mail queue shut down before sending everything", "queued", len(q.jobs))
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMailQueueDrainsOnShutdown(t *testing.T) {
+	q := NewMailQueue(2, 1, slog.Default())
+	release := make(chan struct{})
+	var sent atomic.Int32
+	job := func(context.Context) {
+		<-release
+		sent.Add(1)
+	}
+
+	// One job occupies the worker and two fill the queue.
+	for i := 0; i < 3; i++ {
+		if err := waitEnqueue(q, job); err != nil {
+			t.Fatalf("Enqueue() #%d error = %v", i+1, err)
+		}
+	}
+	if err := q.Enqueue(job); !errors.Is(err, ErrMailQueueFull) {
+		t.Fatalf("Enqueue() on a full queue error = %v, want ErrMailQueueFull", err)
+	}
+
+	close(release)
+	if err := q.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if got := sent.Load(); got != 3 {
+		t.Errorf("sent %d jobs, want 3", got)
+	}
+	if err := q.Enqueue(job); !errors.Is(err, ErrMailQueueFull) {
+		t.Errorf("Enqueue() after Shutdown error = %v, want ErrMailQueueFull", err)
+	}
+}
+
+func TestMailQueueShutdownTimeoutCancelsJobs(t *testing.T) {
+	q := NewMailQueue(1, 1, slog.Default())
+	cancelled := make(chan struct{})
+	if err := q.Enqueue(func(ctx context.Context) {
+		<-ctx.Done()
+		close(cancelled)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := q.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown() error = %v, want DeadlineExceeded", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("running job was not cancelled")
+	}
+}
+
+// waitEnqueue retries while the worker has yet to pick up an earlier job.
+func waitEnqueue(q *MailQueue, job func(context.Context)) error {
+	deadline := time.Now().Add(time.Second)
+	for {
+		err := q.Enqueue(job)
+		if !errors.Is(err, ErrMailQueueFull) || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
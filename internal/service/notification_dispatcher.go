@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"evently/internal/data"
+	"evently/internal/notify"
+	"log/slog"
+	"time"
+)
+
+const (
+	dispatchBatchSize   = 50
+	dispatchLease       = 5 * time.Minute
+	maxDispatchAttempts = 8
+	baseRetryDelay      = 30 * time.Second
+	maxRetryDelay       = time.Hour
+)
+
+// NotificationDispatcher drains the notification outbox, rendering each
+// message from its template and handing it to the configured Notifier.
+type NotificationDispatcher struct {
+	outboxRepo *data.OutboxRepository
+	notifier   notify.Notifier
+	log        *slog.Logger
+}
+
+func NewNotificationDispatcher(outboxRepo *data.OutboxRepository, notifier notify.Notifier, log *slog.Logger) *NotificationDispatcher {
+	return &NotificationDispatcher{outboxRepo: outboxRepo, notifier: notifier, log: log}
+}
+
+// DispatchPending sends every due message and returns how many were sent.
+func (d *NotificationDispatcher) DispatchPending(ctx context.Context) (int, error) {
+	sent := 0
+	for {
+		messages, err := d.outboxRepo.ClaimPending(ctx, dispatchBatchSize, dispatchLease)
+		if err != nil {
+			return sent, err
+		}
+
+		for _, msg := range messages {
+			if err := d.send(ctx, msg); err != nil {
+				d.fail(ctx, msg, err)
+				continue
+			}
+			if err := d.outboxRepo.MarkSent(ctx, msg.ID); err != nil {
+				return sent, err
+			}
+			sent++
+		}
+
+		if len(messages) < dispatchBatchSize {
+			return sent, nil
+		}
+	}
+}
+
+// Run dispatches pending notifications every interval until ctx is cancelled.
+func (d *NotificationDispatcher) Run(ctx context.Context, interval time.Duration) {
+	runEvery(ctx, interval, func() {
+		sent, err := d.DispatchPending(ctx)
+		if err != nil {
+			d.log.Error("failed to dispatch notifications", "error", err)
+			return
+		}
+		if sent > 0 {
+			d.log.Info("dispatched notifications", "count", sent)
+		}
+	})
+}
+
+func (d *NotificationDispatcher) send(ctx context.Context, msg data.OutboxMessage) error {
+	fields := make(map[string]any, len(msg.Payload)+1)
+	for k, v := range msg.Payload {
+		fields[k] = v
+	}
+	fields["name"] = msg.Name
+
+	rendered, err := notify.Render(msg.Kind, msg.Email, fields)
+	if err != nil {
+		return err
+	}
+	return d.notifier.Send(ctx, rendered)
+}
+
+func (d *NotificationDispatcher) fail(ctx context.Context, msg data.OutboxMessage, sendErr error) {
+	final := msg.Attempts >= maxDispatchAttempts
+	next := time.Now().Add(retryDelay(msg.Attempts))
+	if err := d.outboxRepo.MarkFailed(ctx, msg.ID, sendErr, next, final); err != nil {
+		d.log.Error("failed to record notification failure", "notification_id", msg.ID, "error", err)
+		return
+	}
+	if final {
+		d.log.Error("giving up on notification", "notification_id", msg.ID, "kind", msg.Kind, "attempts", msg.Attempts, "error", sendErr)
+		return
+	}
+	d.log.Warn("notification failed, will retry", "notification_id", msg.ID, "kind", msg.Kind, "attempts", msg.Attempts, "error", sendErr)
+}
+
+// retryDelay doubles from baseRetryDelay with each attempt, up to maxRetryDelay.
+func retryDelay(attempts int) time.Duration {
+	delay := baseRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
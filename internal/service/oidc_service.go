@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"evently/internal/authz"
+	"evently/internal/data"
+	"evently/internal/oidc"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// oidcLoginTTL is how long the user has to sign in at the provider.
+	oidcLoginTTL = 10 * time.Minute
+	// oidcHandoffTTL is how long the browser has to trade the handoff token
+	// for a session once it is back from the provider.
+	oidcHandoffTTL = time.Minute
+	maxNameLength  = 255
+)
+
+var (
+	ErrInvalidOIDCState    = errors.New("sign-in request is invalid or expired, please try again")
+	ErrOIDCEmailUnverified = errors.New("the identity provider did not confirm your email address")
+	ErrInvalidHandoff      = errors.New("sign-in link is invalid or expired, please try again")
+)
+
+// OIDCService signs users in through an OpenID Connect provider. The
+// provider's response arrives as a browser redirect, so a successful
+// callback sends the browser back to the app with a short-lived handoff
+// token, which CompleteLogin trades for the same result as a password login.
+type OIDCService struct {
+	db         *pgxpool.Pool
+	provider   *oidc.Provider
+	repo       *data.OIDCRepository
+	userRepo   *data.UserRepository
+	tokenRepo  *data.TokenRepository
+	auth       *AuthService
+	appBaseURL string
+	log        *slog.Logger
+}
+
+func NewOIDCService(db *pgxpool.Pool, provider *oidc.Provider, repo *data.OIDCRepository, userRepo *data.UserRepository, tokenRepo *data.TokenRepository, auth *AuthService, appBaseURL string, log *slog.Logger) *OIDCService {
+	return &OIDCService{
+		db:         db,
+		provider:   provider,
+		repo:       repo,
+		userRepo:   userRepo,
+		tokenRepo:  tokenRepo,
+		auth:       auth,
+		appBaseURL: strings.TrimRight(appBaseURL, "/"),
+		log:        log,
+	}
+}
+
+// OIDCLogin is a started sign-in: the provider URL to send the browser to,
+// and the state the browser must keep until the callback.
+type OIDCLogin struct {
+	URL       string
+	State     string
+	ExpiresAt time.Time
+}
+
+// StartLogin records a new login and returns where to send the browser.
+func (s *OIDCService) StartLogin(ctx context.Context) (*OIDCLogin, error) {
+	state, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(oidcLoginTTL)
+	if err := s.repo.CreateLogin(ctx, hashToken(state), nonce, verifier, expiresAt); err != nil {
+		return nil, err
+	}
+	target, err := s.provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return nil, err
+	}
+	return &OIDCLogin{URL: target, State: state, ExpiresAt: expiresAt}, nil
+}
+
+// HandleCallback finishes the provider side of a login: it checks state,
+// exchanges the code, links or provisions the account and returns the
+// handoff token for CompleteLogin. browserState is the state the browser
+// kept from StartLogin; requiring it stops an attacker from having a
+// victim's browser finish the attacker's own sign-in.
+func (s *OIDCService) HandleCallback(ctx context.Context, state, browserState, code string) (string, error) {
+	if browserState == "" || subtle.ConstantTimeCompare([]byte(state), []byte(browserState)) != 1 {
+		return "", ErrInvalidOIDCState
+	}
+	login, err := s.repo.ClaimState(ctx, hashToken(state))
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return "", ErrInvalidOIDCState
+		}
+		return "", err
+	}
+
+	claims, err := s.provider.Exchange(ctx, code, login.CodeVerifier, login.Nonce)
+	if err != nil {
+		return "", err
+	}
+	user, err := s.resolveUser(ctx, claims)
+	if err != nil {
+		return "", err
+	}
+
+	handoff, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	if err := s.repo.SetHandoff(ctx, login.ID, user.ID, hashToken(handoff), time.Now().Add(oidcHandoffTTL)); err != nil {
+		return "", err
+	}
+	return handoff, nil
+}
+
+// resolveUser finds the account for a provider identity. An identity seen
+// before maps to its linked account. Otherwise the provider must have
+// verified the email address, and the identity is linked to the account
+// with that address or to a new one.
+func (s *OIDCService) resolveUser(ctx context.Context, claims *oidc.Claims) (*data.User, error) {
+	issuer := s.provider.Issuer()
+	userID, err := s.repo.GetIdentityUserID(ctx, issuer, claims.Subject)
+	if err == nil {
+		return s.userRepo.GetByID(ctx, userID)
+	}
+	if !errors.Is(err, data.ErrNotFound) {
+		return nil, err
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, ErrOIDCEmailUnverified
+	}
+	user, err := s.userRepo.GetByEmail(ctx, claims.Email)
+	if err != nil && !errors.Is(err, data.ErrNotFound) {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Nobody proved control of an unverified account's mailbox, so its
+	// password may have been set by someone else who registered the
+	// address first. The provider has now proved it, so that password and
+	// any sessions opened with it stop working.
+	if user != nil && user.EmailVerifiedAt == nil {
+		if err := s.userRepo.RequirePasswordReset(ctx, tx, user.ID); err != nil {
+			return nil, err
+		}
+		if _, err := s.tokenRepo.RevokeUserFamilies(ctx, tx, user.ID, data.RevokedPassword); err != nil {
+			return nil, err
+		}
+	}
+
+	if user == nil {
+		// An empty password hash never matches, so the account can only
+		// sign in through the provider until the user sets a password
+		// with a reset link.
+		user = &data.User{
+			Name:  ssoDisplayName(claims),
+			Email: claims.Email,
+			Role:  authz.RoleUser,
+		}
+		if err := s.userRepo.CreateVerified(ctx, tx, user); err != nil {
+			return nil, err
+		}
+	} else if err := s.userRepo.MarkEmailVerified(ctx, tx, user.ID); err != nil {
+		return nil, err
+	}
+	if err := s.repo.LinkIdentity(ctx, tx, user.ID, issuer, claims.Subject, claims.Email); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	s.log.Info("sso identity linked", "user_id", user.ID, "issuer", issuer, "subject", claims.Subject)
+	return user, nil
+}
+
+// CompleteLogin trades a handoff token for a session, or for a two-factor
+// challenge when the account has two-factor enabled.
+func (s *OIDCService) CompleteLogin(ctx context.Context, handoff string) (*LoginResult, error) {
+	userID, err := s.repo.ConsumeHandoff(ctx, hashToken(handoff))
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return nil, ErrInvalidHandoff
+		}
+		return nil, err
+	}
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.DisabledAt != nil {
+		return nil, ErrAccountDisabled
+	}
+
+	if user.TwoFactorEnabled {
+		return s.auth.createLoginChallenge(ctx, user.ID, true)
+	}
+	tokens, err := s.auth.startSession(ctx, user.ID, user.Role, true, false)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{TokenPair: tokens}, nil
+}
+
+// AppURL is where the callback sends the browser: with the handoff token
+// on success, or with an error code.
+func (s *OIDCService) AppURL(handoff, errCode string) string {
+	params := url.Values{"action": {"sso-login"}}
+	if handoff != "" {
+		params.Set("token", handoff)
+	}
+	if errCode != "" {
+		params.Set("error", errCode)
+	}
+	return s.appBaseURL + "/?" + params.Encode()
+}
+
+func ssoDisplayName(claims *oidc.Claims) string {
+	name := strings.TrimSpace(claims.Name)
+	if name == "" {
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+	if runes := []rune(name); len(runes) > maxNameLength {
+		name = string(runes[:maxNameLength])
+	}
+	return name
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"evently/internal/data"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrWrongPassword = errors.New("current password is incorrect")
+	ErrEmailTaken    = errors.New("a user with this email already exists")
+	ErrInvalidName   = errors.New("name cannot be empty")
+	ErrSSORequired   = errors.New("this account has no password, sign in with single sign-on to confirm the change")
+)
+
+// ProfileUpdate holds the fields a user may change on their own account.
+// Nil fields are left alone. Changing the email or password requires
+// CurrentPassword, or a single sign-on session for accounts that have no
+// password.
+type ProfileUpdate struct {
+	Name            *string
+	Email           *string
+	NewPassword     *string
+	CurrentPassword string
+}
+
+// UserExport is everything the service stores about a user, for data
+// portability requests.
+type UserExport struct {
+	ExportedAt      time.Time            `json:"exported_at"`
+	Profile         *data.User           `json:"profile"`
+	Bookings        []data.UserBooking   `json:"bookings"`
+	Holds           []data.Hold          `json:"holds"`
+	WaitlistEntries []data.WaitlistEntry `json:"waitlist_entries"`
+	WaitlistOffers  []data.WaitlistOffer `json:"waitlist_offers"`
+}
+
+// ProfileService lets users view, change, export and erase their own account.
+type ProfileService struct {
+	db           *pgxpool.Pool
+	userRepo     *data.UserRepository
+	tokenRepo    *data.TokenRepository
+	bookingRepo  *data.BookingRepository
+	holdRepo     *data.HoldRepository
+	waitlistRepo *data.WaitlistRepository
+	auth         *AuthService
+	log          *slog.Logger
+}
+
+func NewProfileService(db *pgxpool.Pool, userRepo *data.UserRepository, tokenRepo *data.TokenRepository, bookingRepo *data.BookingRepository, holdRepo *data.HoldRepository, waitlistRepo *data.WaitlistRepository, auth *AuthService, log *slog.Logger) *ProfileService {
+	return &ProfileService{
+		db:           db,
+		userRepo:     userRepo,
+		tokenRepo:    tokenRepo,
+		bookingRepo:  bookingRepo,
+		holdRepo:     holdRepo,
+		waitlistRepo: waitlistRepo,
+		auth:         auth,
+		log:          log,
+	}
+}
+
+func (s *ProfileService) GetProfile(ctx context.Context, userID string) (*data.User, error) {
+	return s.userRepo.GetByID(ctx, userID)
+}
+
+// UpdateProfile applies the update. A new email address is unverified until
+// the user follows the link sent to it. A new password ends every session
+// except sessionID, the one making the change.
+func (s *ProfileService) UpdateProfile(ctx context.Context, userID, sessionID string, update ProfileUpdate) (*data.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	emailChanged := update.Email != nil && !strings.EqualFold(strings.TrimSpace(*update.Email), user.Email)
+	if emailChanged || update.NewPassword != nil {
+		if err := s.confirmIdentity(ctx, user, sessionID, update.CurrentPassword); err != nil {
+			return nil, err
+		}
+	}
+
+	if update.Name != nil || emailChanged {
+		if update.Name != nil {
+			user.Name = strings.TrimSpace(*update.Name)
+			if user.Name == "" {
+				return nil, ErrInvalidName
+			}
+		}
+		if emailChanged {
+			user.Email = strings.TrimSpace(*update.Email)
+		}
+		if err := s.userRepo.UpdateProfile(ctx, user); err != nil {
+			if errors.Is(err, data.ErrDuplicate) {
+				return nil, ErrEmailTaken
+			}
+			return nil, err
+		}
+	}
+
+	if update.NewPassword != nil {
+		if err := s.changePassword(ctx, userID, sessionID, *update.NewPassword); err != nil {
+			return nil, err
+		}
+	}
+
+	if emailChanged {
+		if err := s.auth.sendVerification(ctx, user); err != nil {
+			s.log.Error("failed to send verification email", "user_id", userID, "error", err)
+		}
+		s.log.Info("user changed email", "user_id", userID)
+	}
+	return user, nil
+}
+
+// confirmIdentity checks that the user, not just someone holding their
+// access token, asked for a sensitive change. Accounts created through
+// single sign-on have no password; for them the request must come from a
+// session that was started at the identity provider.
+func (s *ProfileService) confirmIdentity(ctx context.Context, user *data.User, sessionID, password string) error {
+	if user.PasswordHash != "" {
+		if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+			return ErrWrongPassword
+		}
+		return nil
+	}
+	if sessionID == "" {
+		return ErrSSORequired
+	}
+	sso, err := s.tokenRepo.IsSSOSession(ctx, sessionID, user.ID)
+	if err != nil {
+		return err
+	}
+	if !sso {
+		return ErrSSORequired
+	}
+	return nil
+}
+
+func (s *ProfileService) changePassword(ctx context.Context, userID, sessionID, password string) error {
+	if len(password) < minPasswordLength {
+		return ErrWeakPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.userRepo.SetPassword(ctx, tx, userID, string(hash)); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	if _, err := s.tokenRepo.RevokeOtherFamilies(ctx, userID, sessionID, data.RevokedPassword); err != nil {
+		return err
+	}
+	s.log.Info("user changed password", "user_id", userID)
+	return nil
+}
+
+func (s *ProfileService) Export(ctx context.Context, userID string) (*UserExport, error) {
+	export := UserExport{ExportedAt: time.Now().UTC()}
+	var err error
+	if export.Profile, err = s.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.Bookings, err = s.bookingRepo.GetByUserID(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.Holds, err = s.holdRepo.GetByUserID(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.WaitlistEntries, err = s.waitlistRepo.GetEntriesByUserID(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.WaitlistOffers, err = s.waitlistRepo.GetOfferHistory(ctx, userID); err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// DeleteAccount erases the user's personal data. Active holds and pending
+// waitlist offers are released to the next people in line; bookings stay, so
+// events keep their attendance counts. The password, or for accounts
+// without one the single sign-on session, confirms the request as in
+// UpdateProfile.
+func (s *ProfileService) DeleteAccount(ctx context.Context, userID, sessionID, password string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.confirmIdentity(ctx, user, sessionID, password); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	events := make(map[string]bool)
+	holds, err := s.holdRepo.LockActiveByUserID(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	for i := range holds {
+		if err := s.holdRepo.Release(ctx, tx, &holds[i], data.HoldStatusReleased); err != nil {
+			return err
+		}
+		events[holds[i].EventID] = true
+	}
+	offers, err := s.waitlistRepo.LockPendingOffersByUserID(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	for i := range offers {
+		if err := s.waitlistRepo.CloseOffer(ctx, tx, &offers[i], data.OfferStatusDeclined); err != nil {
+			return err
+		}
+		events[offers[i].EventID] = true
+	}
+
+	if err := s.userRepo.Anonymize(ctx, tx, userID); err != nil {
+		return err
+	}
+	for eventID := range events {
+		promoted, err := s.bookingRepo.PromoteFromWaitlist(ctx, tx, eventID)
+		if err != nil {
+			return err
+		}
+		logPromotions(s.log, eventID, promoted)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	s.log.Info("user account erased", "user_id", userID)
+	return nil
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"evently/internal/data"
+	"log/slog"
+	"slices"
+)
+
+var (
+	ErrSeatsRequired     = errors.New("this event has reserved seating, book seats instead of holding them")
+	ErrNotSeated         = errors.New("this event does not have reserved seating")
+	ErrSeatNotFound      = errors.New("one or more seats are not part of this event or ticket tier")
+	ErrSeatTaken         = errors.New("one or more of the seats have already been taken")
+	ErrSeatMapLocked     = errors.New("the seat map cannot be changed once tickets have been sold, held or waitlisted")
+	ErrNoSeats           = errors.New("the venue has no seats")
+	ErrSectionTierNeeded = errors.New("every section of the venue must be assigned one of the event's tiers")
+	ErrInvalidSectionMap = errors.New("section_tiers may only map the venue's sections to the event's tiers")
+)
+
+// SeatingService attaches venue seat maps to events and shows their seats.
+type SeatingService struct {
+	seatRepo  *data.SeatRepository
+	eventRepo *data.EventRepository
+	venueRepo *data.VenueRepository
+	tierRepo  *data.TierRepository
+	log       *slog.Logger
+}
+
+func NewSeatingService(seatRepo *data.SeatRepository, eventRepo *data.EventRepository, venueRepo *data.VenueRepository, tierRepo *data.TierRepository, log *slog.Logger) *SeatingService {
+	return &SeatingService{seatRepo: seatRepo, eventRepo: eventRepo, venueRepo: venueRepo, tierRepo: tierRepo, log: log}
+}
+
+// AttachSeatMap gives the event reserved seating with the venue's seats.
+// When the event sells by tier, sectionTiers must assign every section of
+// the venue one of its tiers; otherwise it must be empty.
+func (s *SeatingService) AttachSeatMap(ctx context.Context, eventID, venueID string, sectionTiers map[string]string) (int, error) {
+	venue, err := s.venueRepo.Get(ctx, venueID)
+	if err != nil {
+		return 0, err
+	}
+	if len(venue.Sections) == 0 {
+		return 0, ErrNoSeats
+	}
+	sections := make([]string, len(venue.Sections))
+	for i, section := range venue.Sections {
+		sections[i] = section.ID
+	}
+	tiers, err := s.tierRepo.ListByEvent(ctx, eventID)
+	if err != nil {
+		return 0, err
+	}
+
+	for section, tier := range sectionTiers {
+		isTier := func(t data.TicketTier) bool { return t.ID == tier }
+		if !slices.Contains(sections, section) || !slices.ContainsFunc(tiers, isTier) {
+			return 0, ErrInvalidSectionMap
+		}
+	}
+	if len(tiers) > 0 && len(sectionTiers) != len(sections) {
+		return 0, ErrSectionTierNeeded
+	}
+
+	seats, err := s.seatRepo.AttachSeatMap(ctx, eventID, venueID, sectionTiers)
+	if err != nil {
+		if errors.Is(err, data.ErrConflict) {
+			return 0, ErrSeatMapLocked
+		}
+		return 0, err
+	}
+	s.log.Info("seat map attached", "event_id", eventID, "venue_id", venueID, "seats", seats)
+	return seats, nil
+}
+
+// ListEventSeats returns the event's seats and whether each is still
+// available.
+func (s *SeatingService) ListEventSeats(ctx context.Context, eventID string) ([]data.EventSeat, error) {
+	seats, err := s.seatRepo.ListEventSeats(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if len(seats) == 0 {
+		if _, err := s.eventRepo.GetByID(ctx, eventID); err != nil {
+			return nil, err
+		}
+		return nil, ErrNotSeated
+	}
+	return seats, nil
+}
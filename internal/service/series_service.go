@@ -0,0 +1,454 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"evently/internal/authz"
+	"evently/internal/data"
+	"evently/internal/recurrence"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// firstOccurrenceDays is how far from its start a new series must have an
+// occurrence, so rules that can never match are refused.
+const firstOccurrenceDays = 5 * 366
+
+var (
+	ErrSeriesStartRequired = errors.New("start_time is required")
+	ErrInvalidExclusion    = errors.New("exclusions must be dates like 2026-12-31")
+	ErrNoOccurrences       = errors.New("the recurrence rule has no occurrences in the five years from start_time")
+	ErrInvalidCapacity     = errors.New("capacity cannot be negative")
+	ErrInvalidStartClock   = errors.New("start_time must be a local time like 18:30")
+	ErrNotInSeries         = errors.New("this event is not part of a series")
+	ErrNotSeriesOwner      = errors.New("only the owner of the series can edit its following occurrences")
+)
+
+// SeriesInput describes a new series. StartTime is the start of the first
+// occurrence; later ones start at the same local time in Timezone, which
+// defaults to the venue's, then UTC. RRule is an RRULE such as
+// "FREQ=WEEKLY;BYDAY=TU,TH;COUNT=12". The venue is resolved as for events.
+type SeriesInput struct {
+	Name           string    `json:"name"`
+	Venue          string    `json:"venue"`
+	VenueID        *string   `json:"venue_id"`
+	Timezone       string    `json:"timezone"`
+	StartTime      time.Time `json:"start_time"`
+	Capacity       *int      `json:"capacity"`
+	WaitlistPolicy string    `json:"waitlist_policy"`
+	OfferMinutes   int       `json:"waitlist_offer_minutes"`
+	RRule          string    `json:"rrule"`
+	Exclusions     []string  `json:"exclusions"`
+}
+
+// SeriesUpdate holds the changes for one occurrence and those after it;
+// nil fields are left alone. StartTime is a local time like 18:30, which
+// keeps each occurrence on its day. An empty VenueID unlinks the venue.
+type SeriesUpdate struct {
+	Name           *string `json:"name"`
+	Venue          *string `json:"venue"`
+	VenueID        *string `json:"venue_id"`
+	Capacity       *int    `json:"capacity"`
+	WaitlistPolicy *string `json:"waitlist_policy"`
+	OfferMinutes   *int    `json:"waitlist_offer_minutes"`
+	StartTime      *string `json:"start_time"`
+}
+
+// SeriesService creates event series and keeps their occurrences generated
+// a rolling horizon ahead.
+type SeriesService struct {
+	db          *pgxpool.Pool
+	seriesRepo  *data.SeriesRepository
+	eventRepo   *data.EventRepository
+	bookingRepo *data.BookingRepository
+	venueRepo   *data.VenueRepository
+	horizon     time.Duration
+	log         *slog.Logger
+}
+
+func NewSeriesService(db *pgxpool.Pool, seriesRepo *data.SeriesRepository, eventRepo *data.EventRepository, bookingRepo *data.BookingRepository, venueRepo *data.VenueRepository, horizon time.Duration, log *slog.Logger) *SeriesService {
+	return &SeriesService{
+		db: db, seriesRepo: seriesRepo, eventRepo: eventRepo, bookingRepo: bookingRepo, venueRepo: venueRepo, horizon: horizon, log: log,
+	}
+}
+
+// Create saves the series and generates its occurrences up to the horizon,
+// which are returned with it. ownerID owns the series and every occurrence.
+func (s *SeriesService) Create(ctx context.Context, input SeriesInput, ownerID string) (*data.EventSeries, error) {
+	if input.StartTime.IsZero() {
+		return nil, ErrSeriesStartRequired
+	}
+	if err := ValidateWaitlistPolicy(input.WaitlistPolicy, input.OfferMinutes); err != nil {
+		return nil, err
+	}
+	rule, err := recurrence.Parse(input.RRule)
+	if err != nil {
+		return nil, err
+	}
+	exclusions, err := parseExclusions(input.Exclusions)
+	if err != nil {
+		return nil, err
+	}
+
+	series := &data.EventSeries{
+		Name:           input.Name,
+		RRule:          rule.String(),
+		WaitlistPolicy: input.WaitlistPolicy,
+		OfferMinutes:   input.OfferMinutes,
+		OwnerID:        &ownerID,
+	}
+	venue, err := s.applyVenue(ctx, series, input.VenueID, input.Venue)
+	if err != nil {
+		return nil, err
+	}
+	series.Timezone = input.Timezone
+	if series.Timezone == "" && venue != nil {
+		series.Timezone = venue.Timezone
+	}
+	if series.Timezone == "" {
+		series.Timezone = "UTC"
+	}
+	loc, err := loadTimezone(series.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case input.Capacity != nil:
+		series.Capacity = *input.Capacity
+	case venue != nil && venue.DefaultCapacity != nil:
+		series.Capacity = *venue.DefaultCapacity
+	}
+	if series.Capacity < 0 {
+		return nil, ErrInvalidCapacity
+	}
+
+	series.StartsAt = input.StartTime.In(loc)
+	series.Exclusions = make([]string, len(exclusions))
+	for i, d := range exclusions {
+		series.Exclusions[i] = d.String()
+	}
+	first := recurrence.DateOf(series.StartsAt)
+	schedule := recurrence.Schedule{Start: series.StartsAt, Rule: *rule, Exclude: exclusions}
+	if len(schedule.Between(first, first.AddDays(firstOccurrenceDays))) == 0 {
+		return nil, ErrNoOccurrences
+	}
+	series.GeneratedThrough = first.AddDays(-1).String()
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.seriesRepo.Create(ctx, tx, series); err != nil {
+		return nil, err
+	}
+	if series.Occurrences, err = s.generate(ctx, tx, series); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	s.log.Info("event series created", "series_id", series.ID, "rrule", series.RRule, "occurrences", len(series.Occurrences))
+	return series, nil
+}
+
+// Get returns the series with its occurrences, leaving out those that have
+// started unless includePast is set.
+func (s *SeriesService) Get(ctx context.Context, id string, includePast bool) (*data.EventSeries, error) {
+	series, err := s.seriesRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var from *time.Time
+	if !includePast {
+		now := time.Now()
+		from = &now
+	}
+	if series.Occurrences, err = s.seriesRepo.ListOccurrences(ctx, id, from); err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+// UpdateFollowing applies input to the event and every later occurrence
+// that is not cancelled, and to the series template so occurrences yet to
+// be generated match. Earlier occurrences are left as they are. The series
+// version must match, and only the series owner or a user whose role can
+// edit every event may do this. It returns the series with the changed
+// occurrences.
+func (s *SeriesService) UpdateFollowing(ctx context.Context, eventID, userID, role string, version int, input SeriesUpdate) (*data.EventSeries, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	event, err := s.eventRepo.GetForUpdate(ctx, tx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event.SeriesID == nil {
+		return nil, ErrNotInSeries
+	}
+	series, err := s.seriesRepo.GetForUpdate(ctx, tx, *event.SeriesID)
+	if err != nil {
+		return nil, err
+	}
+	// Edit rights on one occurrence are not enough: this rewrites the
+	// template and every later occurrence.
+	if !authz.RoleAllows(role, authz.EventsEdit) && (series.OwnerID == nil || *series.OwnerID != userID) {
+		return nil, ErrNotSeriesOwner
+	}
+	if series.Version != version {
+		return nil, data.ErrConflict
+	}
+	loc, err := loadTimezone(series.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Name != nil {
+		series.Name = *input.Name
+	}
+	venueChanged := input.VenueID != nil || input.Venue != nil
+	if venueChanged {
+		name := series.Venue
+		if input.Venue != nil {
+			name = *input.Venue
+		}
+		if _, err := s.applyVenue(ctx, series, input.VenueID, name); err != nil {
+			return nil, err
+		}
+	}
+	if input.Capacity != nil {
+		if *input.Capacity < 0 {
+			return nil, ErrInvalidCapacity
+		}
+		series.Capacity = *input.Capacity
+	}
+	if input.WaitlistPolicy != nil {
+		series.WaitlistPolicy = *input.WaitlistPolicy
+	}
+	if input.OfferMinutes != nil {
+		series.OfferMinutes = *input.OfferMinutes
+	}
+	if err := ValidateWaitlistPolicy(series.WaitlistPolicy, series.OfferMinutes); err != nil {
+		return nil, err
+	}
+	var clock *time.Time
+	if input.StartTime != nil {
+		t, err := time.Parse("15:04", *input.StartTime)
+		if err != nil {
+			return nil, ErrInvalidStartClock
+		}
+		clock = &t
+		series.StartsAt = atClock(series.StartsAt.In(loc), t)
+	}
+
+	following, err := s.seriesRepo.ListFollowingForUpdate(ctx, tx, series.ID, eventID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range following {
+		occurrence := &following[i]
+		previousCapacity := occurrence.Capacity
+		if input.Name != nil {
+			occurrence.Name = series.Name
+		}
+		if venueChanged {
+			if occurrence.ReservedSeating && !sameVenue(occurrence.VenueID, series.VenueID) {
+				return nil, fmt.Errorf("%w: the occurrence on %s", ErrVenueLocked, occurrence.StartTime.In(loc).Format(time.DateOnly))
+			}
+			occurrence.VenueID, occurrence.Venue = series.VenueID, series.Venue
+		}
+		if input.Capacity != nil {
+			occurrence.Capacity = series.Capacity
+			if occurrence.Capacity < occurrence.BookedTickets+occurrence.HeldTickets {
+				return nil, fmt.Errorf("%w: the occurrence on %s", ErrCapacityBelowBooked, occurrence.StartTime.In(loc).Format(time.DateOnly))
+			}
+		}
+		if input.WaitlistPolicy != nil || input.OfferMinutes != nil {
+			occurrence.WaitlistPolicy, occurrence.OfferMinutes = series.WaitlistPolicy, series.OfferMinutes
+		}
+		if clock != nil {
+			occurrence.StartTime = atClock(occurrence.StartTime.In(loc), *clock)
+		}
+
+		if err := s.eventRepo.Update(ctx, tx, occurrence); err != nil {
+			return nil, err
+		}
+		if occurrence.Capacity > previousCapacity {
+			promoted, err := s.bookingRepo.PromoteFromWaitlist(ctx, tx, occurrence.ID)
+			if err != nil {
+				return nil, err
+			}
+			logPromotions(s.log, occurrence.ID, promoted)
+			if len(promoted) > 0 {
+				reloaded, err := s.eventRepo.GetForUpdate(ctx, tx, occurrence.ID)
+				if err != nil {
+					return nil, err
+				}
+				*occurrence = *reloaded
+			}
+		}
+	}
+
+	if err := s.seriesRepo.Update(ctx, tx, series); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	s.log.Info("event series updated", "series_id", series.ID, "from_event_id", eventID, "occurrences", len(following))
+	series.Occurrences = following
+	return series, nil
+}
+
+// GenerateDue tops up every series whose occurrences do not reach the
+// horizon yet, and returns how many occurrences it created. A series that
+// fails is logged and retried on the next run.
+func (s *SeriesService) GenerateDue(ctx context.Context) (int, error) {
+	ids, err := s.seriesRepo.ListDue(ctx, time.Now().Add(s.horizon))
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, id := range ids {
+		created, err := s.generateSeries(ctx, id)
+		if err != nil {
+			s.log.Error("failed to generate series occurrences", "series_id", id, "error", err)
+			continue
+		}
+		total += created
+	}
+	return total, nil
+}
+
+// RunGenerator generates due occurrences every interval until ctx is
+// cancelled.
+func (s *SeriesService) RunGenerator(ctx context.Context, interval time.Duration) {
+	runEvery(ctx, interval, func() {
+		created, err := s.GenerateDue(ctx)
+		if err != nil {
+			s.log.Error("failed to generate series occurrences", "error", err)
+			return
+		}
+		if created > 0 {
+			s.log.Info("generated series occurrences", "count", created)
+		}
+	})
+}
+
+func (s *SeriesService) generateSeries(ctx context.Context, id string) (int, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	series, err := s.seriesRepo.GetForUpdate(ctx, tx, id)
+	if err != nil {
+		return 0, err
+	}
+	created, err := s.generate(ctx, tx, series)
+	if err != nil {
+		return 0, err
+	}
+	return len(created), tx.Commit(ctx)
+}
+
+// generate creates the series' occurrences for the dates after
+// GeneratedThrough up to the horizon, and returns them. Dates that already
+// have an occurrence are skipped.
+func (s *SeriesService) generate(ctx context.Context, tx pgx.Tx, series *data.EventSeries) ([]data.Event, error) {
+	schedule, err := seriesSchedule(series)
+	if err != nil {
+		return nil, err
+	}
+	through, err := recurrence.ParseDate(series.GeneratedThrough)
+	if err != nil {
+		return nil, err
+	}
+	horizon := recurrence.DateOf(time.Now().Add(s.horizon).In(schedule.Start.Location()))
+	if !through.Before(horizon) {
+		return nil, nil
+	}
+
+	created := []data.Event{}
+	for _, start := range schedule.Between(through.AddDays(1), horizon) {
+		event, err := s.seriesRepo.InsertOccurrence(ctx, tx, series, recurrence.DateOf(start).String(), start)
+		if err != nil {
+			return nil, err
+		}
+		if event != nil {
+			created = append(created, *event)
+		}
+	}
+	series.GeneratedThrough = horizon.String()
+	if err := s.seriesRepo.SetGeneratedThrough(ctx, tx, series.ID, series.GeneratedThrough); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// applyVenue links the series to a venue as EventService.resolveVenue does
+// for events. An empty venueID unlinks it and keeps its venue name.
+func (s *SeriesService) applyVenue(ctx context.Context, series *data.EventSeries, venueID *string, name string) (*data.Venue, error) {
+	if venueID != nil && *venueID == "" {
+		series.VenueID = nil
+		series.Venue = strings.TrimSpace(name)
+		return nil, nil
+	}
+	venue, err := findVenue(ctx, s.venueRepo, venueID, name)
+	if err != nil {
+		return nil, err
+	}
+	if venue == nil {
+		series.VenueID = nil
+		series.Venue = strings.TrimSpace(name)
+		return nil, nil
+	}
+	series.VenueID = &venue.ID
+	series.Venue = venue.Name
+	return venue, nil
+}
+
+func seriesSchedule(series *data.EventSeries) (recurrence.Schedule, error) {
+	loc, err := loadTimezone(series.Timezone)
+	if err != nil {
+		return recurrence.Schedule{}, err
+	}
+	rule, err := recurrence.Parse(series.RRule)
+	if err != nil {
+		return recurrence.Schedule{}, err
+	}
+	exclusions, err := parseExclusions(series.Exclusions)
+	if err != nil {
+		return recurrence.Schedule{}, err
+	}
+	return recurrence.Schedule{Start: series.StartsAt.In(loc), Rule: *rule, Exclude: exclusions}, nil
+}
+
+func parseExclusions(values []string) ([]recurrence.Date, error) {
+	dates := make([]recurrence.Date, 0, len(values))
+	for _, value := range values {
+		d, err := recurrence.ParseDate(strings.TrimSpace(value))
+		if err != nil {
+			return nil, ErrInvalidExclusion
+		}
+		dates = append(dates, d)
+	}
+	return dates, nil
+}
+
+// atClock moves t to clock's hour and minute on the same local day.
+func atClock(t, clock time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, clock.Hour(), clock.Minute(), 0, 0, t.Location())
+}
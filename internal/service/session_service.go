@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"evently/internal/data"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxSessionTitleLength = 255
+	maxRoomLength         = 100
+)
+
+var (
+	ErrSessionFieldsRequired = errors.New("title, starts_at, ends_at and capacity are required")
+	ErrInvalidSessionTitle   = errors.New("session title is required and must be at most 255 characters")
+	ErrInvalidRoom           = errors.New("room must be at most 100 characters")
+	ErrInvalidSessionTime    = errors.New("ends_at must be after starts_at")
+	ErrSessionCapBelowTaken  = errors.New("capacity cannot be lower than the number of registrations")
+	ErrSessionInUse          = errors.New("users are registered for this session, it cannot be deleted")
+	ErrSessionFull           = errors.New("this session is full")
+	ErrSessionStarted        = errors.New("this session has already started")
+	ErrBookingRequired       = errors.New("a booking for the event is required to register for its sessions")
+	ErrAlreadyRegistered     = errors.New("you are already registered for this session")
+	ErrSessionOverlap        = errors.New("you are registered for another session at the same time")
+	ErrRegistrantsOverlap    = errors.New("registered users have another session at the new time")
+)
+
+// SessionInput holds the editable fields of a session. On update, nil
+// fields are left alone.
+type SessionInput struct {
+	Title    *string    `json:"title"`
+	Room     *string    `json:"room"`
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+	Capacity *int       `json:"capacity"`
+}
+
+// SessionService manages an event's sessions and registrations for them.
+type SessionService struct {
+	repo     *data.SessionRepository
+	events   *data.EventRepository
+	verifier EmailVerifier
+	log      *slog.Logger
+}
+
+func NewSessionService(repo *data.SessionRepository, events *data.EventRepository, verifier EmailVerifier, log *slog.Logger) *SessionService {
+	return &SessionService{repo: repo, events: events, verifier: verifier, log: log}
+}
+
+// Agenda returns the event's sessions in the order they run.
+func (s *SessionService) Agenda(ctx context.Context, eventID string) ([]data.EventSession, error) {
+	sessions, err := s.repo.ListByEvent(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		if _, err := s.events.GetByID(ctx, eventID); err != nil {
+			return nil, err
+		}
+	}
+	return sessions, nil
+}
+
+func (s *SessionService) Create(ctx context.Context, eventID string, input SessionInput) (*data.EventSession, error) {
+	if input.Title == nil || input.StartsAt == nil || input.EndsAt == nil || input.Capacity == nil {
+		return nil, ErrSessionFieldsRequired
+	}
+	session := data.EventSession{EventID: eventID}
+	applySessionInput(&session, input)
+	if err := validateSession(&session); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, &session); err != nil {
+		return nil, err
+	}
+	s.log.Info("session created", "event_id", eventID, "session_id", session.ID)
+	return &session, nil
+}
+
+// Update applies input to the session if version still matches. A capacity
+// below the registrations is refused, as are new times that clash with
+// another session a registered user attends.
+func (s *SessionService) Update(ctx context.Context, eventID, sessionID string, version int, input SessionInput) (*data.EventSession, error) {
+	session, err := s.repo.Get(ctx, eventID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Version != version {
+		return nil, data.ErrConflict
+	}
+	startsAt, endsAt := session.StartsAt, session.EndsAt
+	applySessionInput(session, input)
+	if err := validateSession(session); err != nil {
+		return nil, err
+	}
+	if session.Capacity < session.Registered {
+		return nil, ErrSessionCapBelowTaken
+	}
+	// Times that stay within the old ones cannot create a clash.
+	widened := session.StartsAt.Before(startsAt) || session.EndsAt.After(endsAt)
+	var overlap *data.SessionOverlapError
+	if err := s.repo.Update(ctx, session, widened); err != nil {
+		if errors.As(err, &overlap) {
+			return nil, fmt.Errorf("%w: %s, %s to %s", ErrRegistrantsOverlap, overlap.Session.Title,
+				overlap.Session.StartsAt.Format(time.RFC3339), overlap.Session.EndsAt.Format(time.RFC3339))
+		}
+		return nil, err
+	}
+	session.Available = session.Capacity - session.Registered
+	return session, nil
+}
+
+func (s *SessionService) Delete(ctx context.Context, eventID, sessionID string) error {
+	if err := s.repo.Delete(ctx, eventID, sessionID); err != nil {
+		if errors.Is(err, data.ErrConflict) {
+			return ErrSessionInUse
+		}
+		return err
+	}
+	s.log.Info("session deleted", "event_id", eventID, "session_id", sessionID)
+	return nil
+}
+
+// Register gives the user a place in the session. They must hold a booking
+// for the event and not be registered for a session at the same time.
+// Places are taken with the same version check and retries as bookings.
+func (s *SessionService) Register(ctx context.Context, eventID, sessionID, userID string) (*data.SessionRegistration, error) {
+	if err := requireVerifiedEmail(ctx, s.verifier, userID); err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < MaxRetries; i++ {
+		session, err := s.repo.Get(ctx, eventID, sessionID)
+		if err != nil {
+			return nil, err
+		}
+		if session.EventStatus != data.EventStatusScheduled {
+			return nil, ErrEventNotOpen
+		}
+		if !time.Now().Before(session.StartsAt) {
+			return nil, ErrSessionStarted
+		}
+		if session.Available <= 0 {
+			return nil, ErrSessionFull
+		}
+
+		registration, err := s.repo.Register(ctx, session, userID)
+		var overlap *data.SessionOverlapError
+		switch {
+		case err == nil:
+			s.log.Info("session registration successful", "user_id", userID, "event_id", eventID, "session_id", sessionID)
+			return registration, nil
+		case errors.Is(err, data.ErrConflict):
+			s.log.Warn("session registration conflict detected, retrying...", "attempt", i+1, "session_id", sessionID)
+			continue
+		case errors.Is(err, data.ErrNotFound):
+			return nil, ErrBookingRequired
+		case errors.Is(err, data.ErrDuplicate):
+			return nil, ErrAlreadyRegistered
+		case errors.As(err, &overlap):
+			return nil, fmt.Errorf("%w: %s, %s to %s", ErrSessionOverlap, overlap.Session.Title,
+				overlap.Session.StartsAt.Format(time.RFC3339), overlap.Session.EndsAt.Format(time.RFC3339))
+		default:
+			return nil, err
+		}
+	}
+	return nil, ErrBookingConflict
+}
+
+func (s *SessionService) Unregister(ctx context.Context, eventID, sessionID, userID string) error {
+	return s.repo.Unregister(ctx, eventID, sessionID, userID)
+}
+
+// UserAgenda returns the sessions the user is registered for that have not
+// ended yet.
+func (s *SessionService) UserAgenda(ctx context.Context, userID string) ([]data.SessionRegistration, error) {
+	return s.repo.ListUserAgenda(ctx, userID, time.Now())
+}
+
+func applySessionInput(session *data.EventSession, input SessionInput) {
+	if input.Title != nil {
+		session.Title = strings.TrimSpace(*input.Title)
+	}
+	if input.Room != nil {
+		session.Room = strings.TrimSpace(*input.Room)
+	}
+	if input.StartsAt != nil {
+		session.StartsAt = *input.StartsAt
+	}
+	if input.EndsAt != nil {
+		session.EndsAt = *input.EndsAt
+	}
+	if input.Capacity != nil {
+		session.Capacity = *input.Capacity
+	}
+}
+
+func validateSession(session *data.EventSession) error {
+	if session.Title == "" || utf8.RuneCountInString(session.Title) > maxSessionTitleLength {
+		return ErrInvalidSessionTitle
+	}
+	if utf8.RuneCountInString(session.Room) > maxRoomLength {
+		return ErrInvalidRoom
+	}
+	if !session.EndsAt.After(session.StartsAt) {
+		return ErrInvalidSessionTime
+	}
+	if session.Capacity < 0 {
+		return ErrInvalidCapacity
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"evently/internal/data"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const maxTierNameLength = 100
+
+var (
+	ErrTierRequired       = errors.New("this event sells tickets by tier, tier_id is required")
+	ErrTierFieldsRequired = errors.New("name, price_cents, currency and quantity are required")
+	ErrTierNotFound       = errors.New("ticket tier not found for this event")
+	ErrTierNotOnSale      = errors.New("this ticket tier is not on sale right now")
+	ErrInvalidTierName    = errors.New("tier name is required and must be at most 100 characters")
+	ErrInvalidPrice       = errors.New("price_cents cannot be negative")
+	ErrInvalidCurrency    = errors.New("currency must be a three-letter ISO 4217 code")
+	ErrInvalidTierQty     = errors.New("quantity cannot be negative")
+	ErrInvalidSalesWindow = errors.New("sales_end must be after sales_start")
+	ErrTierNameTaken      = errors.New("this event already has a tier with that name")
+	ErrTierQtyBelowSold   = errors.New("quantity cannot be lower than the tickets already sold or held")
+	ErrTierInUse          = errors.New("tickets in this tier have been sold or held, it cannot be deleted")
+)
+
+// tierSource is what booking and holds need to pick a tier.
+type tierSource interface {
+	HasTiers(ctx context.Context, eventID string) (bool, error)
+	GetTierForUpdate(ctx context.Context, eventID, tierID string) (*data.TicketTier, error)
+}
+
+// checkTierChoice enforces that events with tiers are booked by tier and
+// events without tiers are not. It returns the tier ID to record, or nil.
+func checkTierChoice(ctx context.Context, repo tierSource, eventID, tierID string) (*string, error) {
+	hasTiers, err := repo.HasTiers(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case hasTiers && tierID == "":
+		return nil, ErrTierRequired
+	case !hasTiers && tierID != "":
+		return nil, ErrTierNotFound
+	case tierID == "":
+		return nil, nil
+	}
+	return &tierID, nil
+}
+
+// loadTier reads the chosen tier for one booking attempt and returns it with
+// how many tickets can be taken from it: the lower of what is left of the
+// tier and of the event. It returns a nil tier for untiered events.
+func loadTier(ctx context.Context, repo tierSource, event *data.EventForUpdate, tierID *string) (*data.TicketTier, int, error) {
+	if tierID == nil {
+		return nil, event.Available(), nil
+	}
+	tier, err := repo.GetTierForUpdate(ctx, event.ID, *tierID)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return nil, 0, ErrTierNotFound
+		}
+		return nil, 0, err
+	}
+	if !tier.SellingAt(time.Now()) {
+		return nil, 0, ErrTierNotOnSale
+	}
+	return tier, min(tier.Remaining(), event.Available()), nil
+}
+
+// TierInput holds the editable fields of a tier. On update, nil fields are
+// left alone.
+type TierInput struct {
+	Name        *string    `json:"name"`
+	Description *string    `json:"description"`
+	PriceCents  *int64     `json:"price_cents"`
+	Currency    *string    `json:"currency"`
+	Quantity    *int       `json:"quantity"`
+	SalesStart  *time.Time `json:"sales_start"`
+	SalesEnd    *time.Time `json:"sales_end"`
+	Position    *int       `json:"position"`
+}
+
+type TicketTierService struct {
+	repo *data.TierRepository
+	log  *slog.Logger
+}
+
+func NewTicketTierService(repo *data.TierRepository, log *slog.Logger) *TicketTierService {
+	return &TicketTierService{repo: repo, log: log}
+}
+
+func (s *TicketTierService) List(ctx context.Context, eventID string) ([]data.TicketTier, error) {
+	return s.repo.ListByEvent(ctx, eventID)
+}
+
+func (s *TicketTierService) Create(ctx context.Context, eventID string, input TierInput) (*data.TicketTier, error) {
+	tier := data.TicketTier{EventID: eventID}
+	applyTierInput(&tier, input)
+	if input.Name == nil || input.PriceCents == nil || input.Currency == nil || input.Quantity == nil {
+		return nil, ErrTierFieldsRequired
+	}
+	if err := validateTier(&tier); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, &tier); err != nil {
+		if errors.Is(err, data.ErrDuplicate) {
+			return nil, ErrTierNameTaken
+		}
+		return nil, err
+	}
+	s.log.Info("ticket tier created", "event_id", eventID, "tier_id", tier.ID)
+	return &tier, nil
+}
+
+// Update applies input to the tier if version still matches. A quantity
+// below what is sold and held is refused.
+func (s *TicketTierService) Update(ctx context.Context, eventID, tierID string, version int, input TierInput) (*data.TicketTier, error) {
+	tier, err := s.repo.Get(ctx, eventID, tierID)
+	if err != nil {
+		return nil, err
+	}
+	if tier.Version != version {
+		return nil, data.ErrConflict
+	}
+	applyTierInput(tier, input)
+	if err := validateTier(tier); err != nil {
+		return nil, err
+	}
+	if tier.Quantity < tier.Sold+tier.Held {
+		return nil, ErrTierQtyBelowSold
+	}
+
+	if err := s.repo.Update(ctx, tier); err != nil {
+		if errors.Is(err, data.ErrDuplicate) {
+			return nil, ErrTierNameTaken
+		}
+		return nil, err
+	}
+	return tier, nil
+}
+
+func (s *TicketTierService) Delete(ctx context.Context, eventID, tierID string) error {
+	if err := s.repo.Delete(ctx, eventID, tierID); err != nil {
+		if errors.Is(err, data.ErrConflict) {
+			return ErrTierInUse
+		}
+		return err
+	}
+	s.log.Info("ticket tier deleted", "event_id", eventID, "tier_id", tierID)
+	return nil
+}
+
+func applyTierInput(tier *data.TicketTier, input TierInput) {
+	if input.Name != nil {
+		tier.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Description != nil {
+		tier.Description = *input.Description
+	}
+	if input.PriceCents != nil {
+		tier.PriceCents = *input.PriceCents
+	}
+	if input.Currency != nil {
+		tier.Currency = strings.ToUpper(strings.TrimSpace(*input.Currency))
+	}
+	if input.Quantity != nil {
+		tier.Quantity = *input.Quantity
+	}
+	if input.SalesStart != nil {
+		tier.SalesStart = input.SalesStart
+	}
+	if input.SalesEnd != nil {
+		tier.SalesEnd = input.SalesEnd
+	}
+	if input.Position != nil {
+		tier.Position = *input.Position
+	}
+}
+
+func validateTier(tier *data.TicketTier) error {
+	if tier.Name == "" || utf8.RuneCountInString(tier.Name) > maxTierNameLength {
+		return ErrInvalidTierName
+	}
+	if tier.PriceCents < 0 {
+		return ErrInvalidPrice
+	}
+	if !validCurrency(tier.Currency) {
+		return ErrInvalidCurrency
+	}
+	if tier.Quantity < 0 {
+		return ErrInvalidTierQty
+	}
+	if tier.SalesStart != nil && tier.SalesEnd != nil && !tier.SalesEnd.After(*tier.SalesStart) {
+		return ErrInvalidSalesWindow
+	}
+	return nil
+}
+
+func validCurrency(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
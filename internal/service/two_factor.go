@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"time"
+
+	"evently/internal/data"
+	"evently/internal/totp"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	loginChallengeTTL         = 5 * time.Minute
+	maxLoginChallengeAttempts = 5
+	recoveryCodeCount         = 10
+)
+
+var (
+	ErrTwoFactorEnabled    = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotStarted = errors.New("two-factor setup has not been started")
+	ErrInvalidTOTPCode     = errors.New("invalid authentication code")
+	ErrInvalidChallenge    = errors.New("login challenge is invalid or expired, please sign in again")
+)
+
+// TwoFactorSetup is what an authenticator app needs to enrol. The secret is
+// shown for manual entry when the URI cannot be scanned as a QR code.
+type TwoFactorSetup struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// SetupTwoFactor generates a new TOTP secret for the user. Two-factor is not
+// enforced until the secret is confirmed with ConfirmTwoFactor; calling this
+// again before then replaces the pending secret.
+func (s *AuthService) SetupTwoFactor(ctx context.Context, userID string) (*TwoFactorSetup, error) {
+	state, err := s.twoFactorRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if state.EnabledAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorRepo.SetPendingSecret(ctx, userID, secret); err != nil {
+		if errors.Is(err, data.ErrConflict) {
+			return nil, ErrTwoFactorEnabled
+		}
+		return nil, err
+	}
+	return &TwoFactorSetup{Secret: secret, OTPAuthURI: totp.URI(s.cfg.Issuer, state.Email, secret)}, nil
+}
+
+// ConfirmTwoFactor enables two-factor once the user proves their app produces
+// valid codes. It returns the recovery codes, which are only ever shown here.
+func (s *AuthService) ConfirmTwoFactor(ctx context.Context, userID, code string) ([]string, error) {
+	state, err := s.twoFactorRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if state.EnabledAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+	if state.Secret == "" {
+		return nil, ErrTwoFactorNotStarted
+	}
+	step, ok := totp.Validate(state.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTOTPCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.twoFactorRepo.Enable(ctx, tx, userID, step); err != nil {
+		if errors.Is(err, data.ErrConflict) {
+			return nil, ErrTwoFactorEnabled
+		}
+		return nil, err
+	}
+	if err := s.twoFactorRepo.ReplaceRecoveryCodes(ctx, tx, userID, hashes); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	s.log.Info("two-factor enabled", "user_id", userID)
+	return codes, nil
+}
+
+// createLoginChallenge is the first half of a two-factor login: the password
+// was correct, or the identity provider vouched for the user when sso is
+// set, and the returned token lets the client submit a code.
+func (s *AuthService) createLoginChallenge(ctx context.Context, userID string, sso bool) (*LoginResult, error) {
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(loginChallengeTTL)
+	if err := s.twoFactorRepo.CreateChallenge(ctx, userID, hashToken(token), sso, expiresAt); err != nil {
+		return nil, err
+	}
+	return &LoginResult{MFARequired: true, ChallengeToken: token, ChallengeExpiresAt: &expiresAt}, nil
+}
+
+// VerifyLoginChallenge completes a two-factor login with either a TOTP code
+// or an unused recovery code. A challenge allows a few wrong codes before the
+// user has to enter their password again, and every code counts as a login
+// attempt under the login guard, so new challenges do not give an attacker
+// who knows the password more tries. ip is the client address.
+func (s *AuthService) VerifyLoginChallenge(ctx context.Context, challengeToken, code, recoveryCode, ip string) (*TokenPair, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	challenge, err := s.twoFactorRepo.GetChallengeForUpdate(ctx, tx, hashToken(challengeToken))
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return nil, ErrInvalidChallenge
+		}
+		return nil, err
+	}
+	if challenge.UsedAt != nil || time.Now().After(challenge.ExpiresAt) || challenge.Attempts >= maxLoginChallengeAttempts {
+		return nil, ErrInvalidChallenge
+	}
+	if err := s.guard.Attempt(ctx, challenge.Email, ip); err != nil {
+		return nil, err
+	}
+
+	ok, err := s.checkSecondFactor(ctx, tx, challenge.UserID, code, recoveryCode)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// The failed attempt must be counted even though the login fails.
+		if err := s.twoFactorRepo.FailChallenge(ctx, tx, challenge.ID); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidTOTPCode
+	}
+
+	if err := s.twoFactorRepo.ConsumeChallenge(ctx, tx, challenge.ID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.guard.RecordSuccess(ctx, challenge.Email, ip); err != nil {
+		s.log.Error("failed to clear login failures", "error", err)
+	}
+	if recoveryCode != "" {
+		s.log.Info("recovery code used", "user_id", challenge.UserID)
+	}
+	return s.startSession(ctx, challenge.UserID, challenge.Role, challenge.SSO, true)
+}
+
+// checkSecondFactor validates a TOTP code, refusing one that was already
+// accepted, or spends a recovery code. Neither is accepted once two-factor
+// has been turned off.
+func (s *AuthService) checkSecondFactor(ctx context.Context, tx pgx.Tx, userID, code, recoveryCode string) (bool, error) {
+	state, err := s.twoFactorRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if state.EnabledAt == nil {
+		return false, nil
+	}
+	if recoveryCode != "" {
+		return s.twoFactorRepo.UseRecoveryCode(ctx, tx, userID, hashToken(normalizeRecoveryCode(recoveryCode)))
+	}
+	step, ok := totp.Validate(state.Secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	return s.twoFactorRepo.UseStep(ctx, tx, userID, step)
+}
+
+// recoveryCodeAlphabet has 32 symbols, so a random byte maps onto it without
+// bias, and leaves out characters that are easy to misread.
+const recoveryCodeAlphabet = "abcdefghijkmnpqrstuvwxyz23456789"
+
+// newRecoveryCodes returns recovery codes formatted as "xxxxx-xxxxx" and the
+// hashes to store for them.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	buf := make([]byte, 10)
+	for i := range codes {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		for j, b := range buf {
+			buf[j] = recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)]
+		}
+		codes[i] = string(buf[:5]) + "-" + string(buf[5:])
+		hashes[i] = hashToken(normalizeRecoveryCode(codes[i]))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(code)), "-", "")
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"evently/internal/authz"
+	"evently/internal/data"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrInvalidRole      = errors.New("role must be one of user, organizer, admin")
+	ErrSelfModification = errors.New("you cannot change the role of, or disable, your own account")
+)
+
+// UserWaitlist is a user's place in waitlist queues and their open offers.
+type UserWaitlist struct {
+	Entries []data.WaitlistEntry `json:"entries"`
+	Offers  []data.WaitlistOffer `json:"offers"`
+}
+
+// UserAdminService backs the admin user management endpoints.
+type UserAdminService struct {
+	db           *pgxpool.Pool
+	userRepo     *data.UserRepository
+	tokenRepo    *data.TokenRepository
+	bookingRepo  *data.BookingRepository
+	waitlistRepo *data.WaitlistRepository
+	auth         *AuthService
+	log          *slog.Logger
+}
+
+func NewUserAdminService(db *pgxpool.Pool, userRepo *data.UserRepository, tokenRepo *data.TokenRepository, bookingRepo *data.BookingRepository, waitlistRepo *data.WaitlistRepository, auth *AuthService, log *slog.Logger) *UserAdminService {
+	return &UserAdminService{
+		db:           db,
+		userRepo:     userRepo,
+		tokenRepo:    tokenRepo,
+		bookingRepo:  bookingRepo,
+		waitlistRepo: waitlistRepo,
+		auth:         auth,
+		log:          log,
+	}
+}
+
+func (s *UserAdminService) ListUsers(ctx context.Context, filter data.UserFilter) ([]data.User, error) {
+	if filter.Role != "" && !authz.ValidRole(filter.Role) {
+		return nil, ErrInvalidRole
+	}
+	return s.userRepo.List(ctx, filter)
+}
+
+func (s *UserAdminService) GetUser(ctx context.Context, id string) (*data.User, error) {
+	return s.userRepo.GetByID(ctx, id)
+}
+
+func (s *UserAdminService) GetUserBookings(ctx context.Context, id string) ([]data.UserBooking, error) {
+	if _, err := s.userRepo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.bookingRepo.GetByUserID(ctx, id)
+}
+
+func (s *UserAdminService) GetUserWaitlist(ctx context.Context, id string) (*UserWaitlist, error) {
+	if _, err := s.userRepo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	entries, err := s.waitlistRepo.GetEntriesByUserID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	offers, err := s.waitlistRepo.GetOffersByUserID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &UserWaitlist{Entries: entries, Offers: offers}, nil
+}
+
+// SetRole changes the user's global role. The user's sessions are ended in
+// the same transaction so no access token keeps the old role until it
+// expires.
+func (s *UserAdminService) SetRole(ctx context.Context, actorID, id, role string) (*data.User, error) {
+	if !authz.ValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+	if actorID == id {
+		return nil, ErrSelfModification
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.userRepo.SetRole(ctx, tx, id, role); err != nil {
+		return nil, err
+	}
+	if _, err := s.tokenRepo.RevokeUserFamilies(ctx, tx, id, data.RevokedRole); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	s.log.Info("user role changed", "user_id", id, "role", role, "changed_by", actorID)
+	return s.userRepo.GetByID(ctx, id)
+}
+
+// SetDisabled disables or re-enables the account. Disabling also ends the
+// user's sessions, in the same transaction; re-enabling does not restore
+// them.
+func (s *UserAdminService) SetDisabled(ctx context.Context, actorID, id string, disabled bool) (*data.User, error) {
+	if actorID == id {
+		return nil, ErrSelfModification
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.userRepo.SetDisabled(ctx, tx, id, disabled); err != nil {
+		return nil, err
+	}
+	if disabled {
+		if _, err := s.tokenRepo.RevokeUserFamilies(ctx, tx, id, data.RevokedDisabled); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	s.log.Info("user account updated", "user_id", id, "disabled", disabled, "changed_by", actorID)
+	return s.userRepo.GetByID(ctx, id)
+}
+
+func (s *UserAdminService) ForcePasswordReset(ctx context.Context, actorID, id string) error {
+	if err := s.auth.ForcePasswordReset(ctx, id); err != nil {
+		return err
+	}
+	s.log.Info("password reset forced", "user_id", id, "requested_by", actorID)
+	return nil
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"evently/internal/data"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxVenueNameLength   = 255
+	maxAddressLength     = 500
+	maxSectionNameLength = 100
+	maxRowLabelLength    = 10
+	maxSeatsPerRow       = 500
+	maxSeatsPerVenue     = 100000
+)
+
+var (
+	ErrInvalidVenueName = errors.New("venue name is required and must be at most 255 characters")
+	ErrInvalidSection   = errors.New("each section needs a unique name of at most 100 characters and at least one row")
+	ErrInvalidSeatRow   = errors.New("each row needs a unique label of at most 10 characters and 1 to 500 seats")
+	ErrTooManySeats     = errors.New("a venue can have at most 100000 seats")
+	ErrInvalidAddress   = errors.New("address must be at most 500 characters")
+	ErrInvalidTimezone  = errors.New("timezone must be an IANA time zone such as Europe/London")
+	ErrInvalidVenueCap  = errors.New("default_capacity cannot be negative")
+	ErrInvalidLocation  = errors.New("latitude must be between -90 and 90 and longitude between -180 and 180, and both must be given together")
+	ErrVenueNameTaken   = errors.New("a venue with that name already exists")
+	ErrVenueInUse       = errors.New("events are held at this venue, it cannot be deleted")
+)
+
+// VenueInput describes a venue and its seat map, which is optional. Rows
+// are listed front to back and their seats are numbered from FirstNumber,
+// or 1. Timezone defaults to UTC.
+type VenueInput struct {
+	Name            string         `json:"name"`
+	Address         string         `json:"address"`
+	Timezone        string         `json:"timezone"`
+	DefaultCapacity *int           `json:"default_capacity"`
+	Latitude        *float64       `json:"latitude"`
+	Longitude       *float64       `json:"longitude"`
+	Sections        []SectionInput `json:"sections"`
+}
+
+// VenueUpdate holds the editable details of a venue; nil fields are left
+// alone. The seat map cannot be changed once created.
+type VenueUpdate struct {
+	Name            *string  `json:"name"`
+	Address         *string  `json:"address"`
+	Timezone        *string  `json:"timezone"`
+	DefaultCapacity *int     `json:"default_capacity"`
+	Latitude        *float64 `json:"latitude"`
+	Longitude       *float64 `json:"longitude"`
+}
+
+type SectionInput struct {
+	Name string     `json:"name"`
+	Rank int        `json:"rank"`
+	Rows []RowInput `json:"rows"`
+}
+
+type RowInput struct {
+	Label       string `json:"label"`
+	Seats       int    `json:"seats"`
+	FirstNumber *int   `json:"first_number"`
+}
+
+type VenueService struct {
+	repo *data.VenueRepository
+	log  *slog.Logger
+}
+
+func NewVenueService(repo *data.VenueRepository, log *slog.Logger) *VenueService {
+	return &VenueService{repo: repo, log: log}
+}
+
+func (s *VenueService) Create(ctx context.Context, input VenueInput) (*data.Venue, error) {
+	venue, err := buildVenue(input)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, venue); err != nil {
+		if errors.Is(err, data.ErrDuplicate) {
+			return nil, ErrVenueNameTaken
+		}
+		return nil, err
+	}
+	s.log.Info("venue created", "venue_id", venue.ID, "sections", len(venue.Sections))
+	return venue, nil
+}
+
+func (s *VenueService) Get(ctx context.Context, id string) (*data.Venue, error) {
+	return s.repo.Get(ctx, id)
+}
+
+func (s *VenueService) List(ctx context.Context, filter data.VenueFilter) ([]data.Venue, error) {
+	return s.repo.List(ctx, filter)
+}
+
+// Update applies input to the venue if version still matches. Events at the
+// venue are renamed with it.
+func (s *VenueService) Update(ctx context.Context, id string, version int, input VenueUpdate) (*data.Venue, error) {
+	venue, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if venue.Version != version {
+		return nil, data.ErrConflict
+	}
+	if input.Name != nil {
+		venue.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Address != nil {
+		venue.Address = strings.TrimSpace(*input.Address)
+	}
+	if input.Timezone != nil {
+		venue.Timezone = strings.TrimSpace(*input.Timezone)
+	}
+	if input.DefaultCapacity != nil {
+		venue.DefaultCapacity = input.DefaultCapacity
+	}
+	if input.Latitude != nil || input.Longitude != nil {
+		venue.Latitude, venue.Longitude = input.Latitude, input.Longitude
+	}
+	if err := validateVenue(venue); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(ctx, venue); err != nil {
+		if errors.Is(err, data.ErrDuplicate) {
+			return nil, ErrVenueNameTaken
+		}
+		return nil, err
+	}
+	s.log.Info("venue updated", "venue_id", venue.ID, "version", venue.Version)
+	return venue, nil
+}
+
+func (s *VenueService) Delete(ctx context.Context, id string) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		if errors.Is(err, data.ErrConflict) {
+			return ErrVenueInUse
+		}
+		return err
+	}
+	s.log.Info("venue deleted", "venue_id", id)
+	return nil
+}
+
+func validateVenue(venue *data.Venue) error {
+	if venue.Name == "" || utf8.RuneCountInString(venue.Name) > maxVenueNameLength {
+		return ErrInvalidVenueName
+	}
+	if utf8.RuneCountInString(venue.Address) > maxAddressLength {
+		return ErrInvalidAddress
+	}
+	if _, err := loadTimezone(venue.Timezone); err != nil {
+		return err
+	}
+	if venue.DefaultCapacity != nil && *venue.DefaultCapacity < 0 {
+		return ErrInvalidVenueCap
+	}
+	lat, lng := venue.Latitude, venue.Longitude
+	if (lat == nil) != (lng == nil) || lat != nil && (*lat < -90 || *lat > 90 || *lng < -180 || *lng > 180) {
+		return ErrInvalidLocation
+	}
+	return nil
+}
+
+// loadTimezone returns the IANA time zone called name. LoadLocation also
+// accepts "" and "Local", which would depend on the server, so they are
+// refused.
+func loadTimezone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, ErrInvalidTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrInvalidTimezone
+	}
+	return loc, nil
+}
+
+// buildVenue checks the input and expands its rows into numbered seats.
+func buildVenue(input VenueInput) (*data.Venue, error) {
+	venue := &data.Venue{
+		Name:            strings.TrimSpace(input.Name),
+		Address:         strings.TrimSpace(input.Address),
+		Timezone:        strings.TrimSpace(input.Timezone),
+		DefaultCapacity: input.DefaultCapacity,
+		Latitude:        input.Latitude,
+		Longitude:       input.Longitude,
+	}
+	if venue.Timezone == "" {
+		venue.Timezone = "UTC"
+	}
+	if err := validateVenue(venue); err != nil {
+		return nil, err
+	}
+
+	total := 0
+	sectionNames := map[string]bool{}
+	for _, in := range input.Sections {
+		section := data.VenueSection{Name: strings.TrimSpace(in.Name), Rank: in.Rank}
+		if section.Name == "" || utf8.RuneCountInString(section.Name) > maxSectionNameLength || sectionNames[section.Name] || len(in.Rows) == 0 {
+			return nil, ErrInvalidSection
+		}
+		sectionNames[section.Name] = true
+
+		rowLabels := map[string]bool{}
+		for _, rowIn := range in.Rows {
+			row := data.SeatRow{Label: strings.TrimSpace(rowIn.Label)}
+			if row.Label == "" || utf8.RuneCountInString(row.Label) > maxRowLabelLength || rowLabels[row.Label] ||
+				rowIn.Seats < 1 || rowIn.Seats > maxSeatsPerRow {
+				return nil, ErrInvalidSeatRow
+			}
+			rowLabels[row.Label] = true
+
+			total += rowIn.Seats
+			if total > maxSeatsPerVenue {
+				return nil, ErrTooManySeats
+			}
+			first := 1
+			if rowIn.FirstNumber != nil {
+				first = *rowIn.FirstNumber
+			}
+			for n := 0; n < rowIn.Seats; n++ {
+				row.Seats = append(row.Seats, data.Seat{Number: first + n})
+			}
+			section.Rows = append(section.Rows, row)
+		}
+		venue.Sections = append(venue.Sections, section)
+	}
+	if venue.Sections == nil {
+		venue.Sections = []data.VenueSection{}
+	}
+	return venue, nil
+}
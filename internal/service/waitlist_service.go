@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"evently/internal/data"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrOfferNotPending = errors.New("offer has already been answered or has expired")
+	ErrOfferExpired    = errors.New("offer has expired")
+)
+
+type WaitlistService struct {
+	db           *pgxpool.Pool
+	waitlistRepo *data.WaitlistRepository
+	bookingRepo  *data.BookingRepository
+	log          *slog.Logger
+}
+
+func NewWaitlistService(db *pgxpool.Pool, waitlistRepo *data.WaitlistRepository, bookingRepo *data.BookingRepository, log *slog.Logger) *WaitlistService {
+	return &WaitlistService{db: db, waitlistRepo: waitlistRepo, bookingRepo: bookingRepo, log: log}
+}
+
+func (s *WaitlistService) GetEntries(ctx context.Context, userID string) ([]data.WaitlistEntry, error) {
+	return s.waitlistRepo.GetEntriesByUserID(ctx, userID)
+}
+
+// UpdateEntry changes the quantity on the user's entry and re-runs promotion,
+// as a smaller request may now fit tickets held back for the waitlist. It
+// returns nil when the entry was promoted off the waitlist as a result.
+func (s *WaitlistService) UpdateEntry(ctx context.Context, entryID, userID string, quantity int) (*data.WaitlistEntry, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	eventID, err := s.waitlistRepo.UpdateEntryQuantity(ctx, tx, entryID, userID, quantity)
+	if err != nil {
+		return nil, err
+	}
+	promoted, err := s.bookingRepo.PromoteFromWaitlist(ctx, tx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	logPromotions(s.log, eventID, promoted)
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	entry, err := s.waitlistRepo.GetEntry(ctx, entryID, userID)
+	if errors.Is(err, data.ErrNotFound) {
+		return nil, nil
+	}
+	return entry, err
+}
+
+func (s *WaitlistService) LeaveWaitlist(ctx context.Context, entryID, userID string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	eventID, err := s.waitlistRepo.DeleteEntry(ctx, tx, entryID, userID)
+	if err != nil {
+		return err
+	}
+	promoted, err := s.bookingRepo.PromoteFromWaitlist(ctx, tx, eventID)
+	if err != nil {
+		return err
+	}
+	logPromotions(s.log, eventID, promoted)
+
+	return tx.Commit(ctx)
+}
+
+func (s *WaitlistService) GetOffers(ctx context.Context, userID string) ([]data.WaitlistOffer, error) {
+	return s.waitlistRepo.GetOffersByUserID(ctx, userID)
+}
+
+// AcceptOffer books the offered tickets. As with ConfirmHold, the event is
+// locked first and must still be scheduled.
+func (s *WaitlistService) AcceptOffer(ctx context.Context, offerID, userID string) (*data.WaitlistOffer, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	status, err := s.waitlistRepo.LockEventStatus(ctx, tx, offerID, userID)
+	if err != nil {
+		return nil, err
+	}
+	offer, err := s.waitlistRepo.GetOfferForUpdate(ctx, tx, offerID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if offer.Status != data.OfferStatusPending {
+		return nil, ErrOfferNotPending
+	}
+	if !offer.ExpiresAt.After(time.Now()) {
+		return nil, ErrOfferExpired
+	}
+	if status != data.EventStatusScheduled {
+		return nil, ErrEventNotOpen
+	}
+
+	if err := s.waitlistRepo.AcceptOffer(ctx, tx, offer); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	s.log.Info("waitlist offer accepted", "offer_id", offer.ID, "booking_id", *offer.BookingID, "user_id", userID)
+	return offer, nil
+}
+
+// DeclineOffer gives the offered tickets back and immediately passes them on
+// to the next matching waitlist entry.
+func (s *WaitlistService) DeclineOffer(ctx context.Context, offerID, userID string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := s.waitlistRepo.LockEventStatus(ctx, tx, offerID, userID); err != nil {
+		return err
+	}
+	offer, err := s.waitlistRepo.GetOfferForUpdate(ctx, tx, offerID, userID)
+	if err != nil {
+		return err
+	}
+	if offer.Status != data.OfferStatusPending {
+		return ErrOfferNotPending
+	}
+
+	if err := s.closeAndPromote(ctx, tx, []data.WaitlistOffer{*offer}, data.OfferStatusDeclined); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ReleaseExpiredOffers expires lapsed offers in batches and cascades their
+// tickets down the waitlist. It returns the number of offers expired.
+func (s *WaitlistService) ReleaseExpiredOffers(ctx context.Context) (int, error) {
+	expired := 0
+	for {
+		tx, err := s.db.Begin(ctx)
+		if err != nil {
+			return expired, err
+		}
+
+		offers, err := s.waitlistRepo.ClaimExpiredOffers(ctx, tx, sweepBatchSize)
+		if err == nil && len(offers) > 0 {
+			err = s.closeAndPromote(ctx, tx, offers, data.OfferStatusExpired)
+		}
+		if err == nil {
+			err = tx.Commit(ctx)
+		}
+		if err != nil {
+			tx.Rollback(ctx)
+			return expired, err
+		}
+
+		expired += len(offers)
+		if len(offers) < sweepBatchSize {
+			return expired, nil
+		}
+	}
+}
+
+// RunOfferSweeper expires lapsed offers every interval until ctx is cancelled.
+func (s *WaitlistService) RunOfferSweeper(ctx context.Context, interval time.Duration) {
+	runEvery(ctx, interval, func() {
+		expired, err := s.ReleaseExpiredOffers(ctx)
+		if err != nil {
+			s.log.Error("failed to expire waitlist offers", "error", err)
+			return
+		}
+		if expired > 0 {
+			s.log.Info("expired waitlist offers", "count", expired)
+		}
+	})
+}
+
+func (s *WaitlistService) closeAndPromote(ctx context.Context, tx pgx.Tx, offers []data.WaitlistOffer, status string) error {
+	events := make(map[string]bool)
+	for i := range offers {
+		if err := s.waitlistRepo.CloseOffer(ctx, tx, &offers[i], status); err != nil {
+			return err
+		}
+		events[offers[i].EventID] = true
+	}
+
+	for eventID := range events {
+		promoted, err := s.bookingRepo.PromoteFromWaitlist(ctx, tx, eventID)
+		if err != nil {
+			return err
+		}
+		logPromotions(s.log, eventID, promoted)
+	}
+	return nil
+}
+
+func logPromotions(log *slog.Logger, eventID string, promoted []data.WaitlistUser) {
+	for _, waitlister := range promoted {
+		if waitlister.OfferID != "" {
+			log.Info("offered tickets to waitlisted user", "event_id", eventID, "user_id", waitlister.UserID, "quantity", waitlister.Quantity, "offer_id", waitlister.OfferID)
+			continue
+		}
+		log.Info("auto-booked tickets for waitlisted user", "event_id", eventID, "user_id", waitlister.UserID, "quantity", waitlister.Quantity)
+	}
+}
+
+// runEvery calls fn every interval until ctx is cancelled.
+func runEvery(ctx context.Context, interval time.Duration, fn func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fn()
+		}
+	}
+}
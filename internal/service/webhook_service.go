@@ -0,0 +1,249 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"evently/internal/data"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrInvalidWebhookURL  = errors.New("url must be an absolute http or https URL")
+	ErrInvalidEventTypes  = fmt.Errorf("event_types must be a non-empty subset of %v", data.WebhookEventTypes)
+	ErrDeliveryNotDead    = errors.New("only dead deliveries can be retried")
+	errUnsuccessfulStatus = errors.New("endpoint returned a non-2xx status")
+)
+
+const (
+	webhookBatchSize   = 50
+	webhookLease       = 5 * time.Minute
+	maxWebhookAttempts = 10
+)
+
+// WebhookUpdate carries the subscription fields an admin wants to change.
+// Nil fields are left untouched.
+type WebhookUpdate struct {
+	URL        *string
+	EventTypes []string
+	Active     *bool
+}
+
+type WebhookService struct {
+	webhookRepo *data.WebhookRepository
+}
+
+func NewWebhookService(webhookRepo *data.WebhookRepository) *WebhookService {
+	return &WebhookService{webhookRepo: webhookRepo}
+}
+
+// CreateSubscription stores a new subscription. A signing secret is
+// generated when none is given; it is returned only from this call.
+func (s *WebhookService) CreateSubscription(ctx context.Context, sub *data.WebhookSubscription) error {
+	if err := validateSubscription(sub.URL, sub.EventTypes); err != nil {
+		return err
+	}
+	if sub.Secret == "" {
+		secret, err := generateWebhookSecret()
+		if err != nil {
+			return err
+		}
+		sub.Secret = secret
+	}
+	return s.webhookRepo.CreateSubscription(ctx, sub)
+}
+
+func (s *WebhookService) ListSubscriptions(ctx context.Context) ([]data.WebhookSubscription, error) {
+	return s.webhookRepo.ListSubscriptions(ctx)
+}
+
+func (s *WebhookService) GetSubscription(ctx context.Context, id string) (*data.WebhookSubscription, error) {
+	return s.webhookRepo.GetSubscription(ctx, id)
+}
+
+func (s *WebhookService) UpdateSubscription(ctx context.Context, id string, update WebhookUpdate) (*data.WebhookSubscription, error) {
+	sub, err := s.webhookRepo.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if update.URL != nil {
+		sub.URL = *update.URL
+	}
+	if update.EventTypes != nil {
+		sub.EventTypes = update.EventTypes
+	}
+	if update.Active != nil {
+		sub.Active = *update.Active
+	}
+	if err := validateSubscription(sub.URL, sub.EventTypes); err != nil {
+		return nil, err
+	}
+	if err := s.webhookRepo.UpdateSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *WebhookService) DeleteSubscription(ctx context.Context, id string) error {
+	return s.webhookRepo.DeleteSubscription(ctx, id)
+}
+
+func (s *WebhookService) ListDeliveries(ctx context.Context, subscriptionID, status string, after *data.DeliveryCursor, limit int) ([]data.WebhookDelivery, error) {
+	if _, err := s.webhookRepo.GetSubscription(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+	return s.webhookRepo.ListDeliveries(ctx, subscriptionID, status, after, limit)
+}
+
+// RetryDelivery takes a delivery out of the dead-letter state so the
+// dispatcher tries it again from scratch.
+func (s *WebhookService) RetryDelivery(ctx context.Context, subscriptionID, deliveryID string) error {
+	err := s.webhookRepo.RequeueDelivery(ctx, subscriptionID, deliveryID)
+	if errors.Is(err, data.ErrConflict) {
+		return ErrDeliveryNotDead
+	}
+	return err
+}
+
+func validateSubscription(rawURL string, eventTypes []string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidWebhookURL
+	}
+	if len(eventTypes) == 0 {
+		return ErrInvalidEventTypes
+	}
+	for _, eventType := range eventTypes {
+		if !slices.Contains(data.WebhookEventTypes, eventType) {
+			return ErrInvalidEventTypes
+		}
+	}
+	return nil
+}
+
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+// WebhookDispatcher posts queued deliveries to subscriber endpoints. Each
+// request carries:
+//
+//	X-Evently-Event:     the event type
+//	X-Evently-Delivery:  the delivery id, stable across retries
+//	X-Evently-Signature: t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">
+//
+// keyed with the subscription secret. Any 2xx response counts as delivered;
+// anything else is retried with exponential backoff until the delivery is
+// moved to the dead-letter state.
+type WebhookDispatcher struct {
+	webhookRepo *data.WebhookRepository
+	client      *http.Client
+	log         *slog.Logger
+}
+
+func NewWebhookDispatcher(webhookRepo *data.WebhookRepository, timeout time.Duration, log *slog.Logger) *WebhookDispatcher {
+	return &WebhookDispatcher{webhookRepo: webhookRepo, client: &http.Client{Timeout: timeout}, log: log}
+}
+
+// DispatchPending attempts every due delivery and returns how many succeeded.
+func (d *WebhookDispatcher) DispatchPending(ctx context.Context) (int, error) {
+	delivered := 0
+	for {
+		deliveries, err := d.webhookRepo.ClaimDeliveries(ctx, webhookBatchSize, webhookLease)
+		if err != nil {
+			return delivered, err
+		}
+
+		for _, delivery := range deliveries {
+			statusCode, err := d.post(ctx, delivery)
+			if err != nil {
+				d.fail(ctx, delivery, statusCode, err)
+				continue
+			}
+			if err := d.webhookRepo.MarkDelivered(ctx, delivery.ID, statusCode); err != nil {
+				return delivered, err
+			}
+			delivered++
+		}
+
+		if len(deliveries) < webhookBatchSize {
+			return delivered, nil
+		}
+	}
+}
+
+// Run dispatches pending webhooks every interval until ctx is cancelled.
+func (d *WebhookDispatcher) Run(ctx context.Context, interval time.Duration) {
+	runEvery(ctx, interval, func() {
+		delivered, err := d.DispatchPending(ctx)
+		if err != nil {
+			d.log.Error("failed to dispatch webhooks", "error", err)
+			return
+		}
+		if delivered > 0 {
+			d.log.Info("delivered webhooks", "count", delivered)
+		}
+	})
+}
+
+func (d *WebhookDispatcher) post(ctx context.Context, delivery data.PendingDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Evently-Webhooks/1.0")
+	req.Header.Set("X-Evently-Event", delivery.EventType)
+	req.Header.Set("X-Evently-Delivery", delivery.ID)
+	req.Header.Set("X-Evently-Signature", "t="+timestamp+",v1="+signWebhook(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("%w: %s", errUnsuccessfulStatus, resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *WebhookDispatcher) fail(ctx context.Context, delivery data.PendingDelivery, statusCode int, sendErr error) {
+	dead := delivery.Attempts >= maxWebhookAttempts
+	next := time.Now().Add(retryDelay(delivery.Attempts))
+	if err := d.webhookRepo.MarkFailed(ctx, delivery.ID, statusCode, sendErr, next, dead); err != nil {
+		d.log.Error("failed to record webhook failure", "delivery_id", delivery.ID, "error", err)
+		return
+	}
+	if dead {
+		d.log.Error("webhook delivery dead-lettered", "delivery_id", delivery.ID, "subscription_id", delivery.SubscriptionID, "attempts", delivery.Attempts, "error", sendErr)
+		return
+	}
+	d.log.Warn("webhook delivery failed, will retry", "delivery_id", delivery.ID, "subscription_id", delivery.SubscriptionID, "attempts", delivery.Attempts, "error", sendErr)
+}
+
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
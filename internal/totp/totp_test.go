@@ -0,0 +1,84 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key from RFC 6238 appendix B, "12345678901234567890".
+var rfcSecret = encoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestGenerateRFCVectors(t *testing.T) {
+	// The RFC lists 8-digit codes; these are their last 6 digits.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	key := []byte("12345678901234567890")
+	for _, tt := range tests {
+		if got := generate(key, tt.unix/period); got != tt.want {
+			t.Errorf("generate at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / period
+	key := []byte("12345678901234567890")
+
+	tests := []struct {
+		name     string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "current step", code: generate(key, step), wantStep: step, wantOK: true},
+		{name: "previous step", code: generate(key, step-1), wantStep: step - 1, wantOK: true},
+		{name: "next step", code: generate(key, step+1), wantStep: step + 1, wantOK: true},
+		{name: "two steps ago", code: generate(key, step-2)},
+		{name: "spaces are ignored", code: " " + generate(key, step)[:3] + " " + generate(key, step)[3:], wantStep: step, wantOK: true},
+		{name: "wrong length", code: "12345"},
+		{name: "empty", code: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := Validate(rfcSecret, tt.code, now)
+			if ok != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("Validate(%q) = %d, %v, want %d, %v", tt.code, gotStep, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestValidateLowercaseSecret(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	code := generate([]byte("12345678901234567890"), now.Unix()/period)
+	if _, ok := Validate(strings.ToLower(rfcSecret), code, now); !ok {
+		t.Error("Validate rejected a lowercase secret")
+	}
+	if _, ok := Validate("not base32!", code, now); ok {
+		t.Error("Validate accepted an invalid secret")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := encoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret %q is not base32: %v", secret, err)
+	}
+	if len(key) != keyBytes {
+		t.Errorf("secret has %d bytes, want %d", len(key), keyBytes)
+	}
+}
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS event_cancellation_recipients;
+DROP TABLE IF EXISTS event_cancellations;
+DROP INDEX IF EXISTS bookings_event_id_status_idx;
+ALTER TABLE bookings DROP COLUMN IF EXISTS cancelled_at;
+ALTER TABLE bookings DROP COLUMN IF EXISTS status;
+DROP TYPE IF EXISTS booking_status;
+ALTER TABLE events DROP COLUMN IF EXISTS status;
+DROP TYPE IF EXISTS event_status;
@@ -0,0 +1,32 @@
+CREATE TYPE event_status AS ENUM ('scheduled', 'cancelled', 'postponed', 'completed');
+ALTER TABLE events ADD COLUMN status event_status NOT NULL DEFAULT 'scheduled';
+
+-- Bookings are kept when an event is called off so history and refunds survive.
+CREATE TYPE booking_status AS ENUM ('confirmed', 'cancelled');
+ALTER TABLE bookings ADD COLUMN status booking_status NOT NULL DEFAULT 'confirmed';
+ALTER TABLE bookings ADD COLUMN cancelled_at TIMESTAMPTZ;
+
+CREATE TABLE event_cancellations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    event_id UUID NOT NULL UNIQUE REFERENCES events(id) ON DELETE CASCADE,
+    cancelled_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- One row per booking or waitlist entry affected by a cancellation. The
+-- notified_at/refunded_at columns are for the jobs that act on them.
+CREATE TABLE event_cancellation_recipients (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    cancellation_id UUID NOT NULL REFERENCES event_cancellations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    booking_id UUID REFERENCES bookings(id) ON DELETE SET NULL,
+    source VARCHAR(20) NOT NULL CHECK (source IN ('booking', 'waitlist')),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    notified_at TIMESTAMPTZ,
+    refunded_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX ON event_cancellation_recipients (cancellation_id);
+CREATE INDEX ON bookings (event_id, status);
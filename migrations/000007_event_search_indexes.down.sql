@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS events_venue_trgm_idx;
+DROP INDEX IF EXISTS events_name_trgm_idx;
+DROP INDEX IF EXISTS events_lower_venue_idx;
+DROP INDEX IF EXISTS events_name_id_idx;
+DROP INDEX IF EXISTS events_start_time_id_idx;
@@ -0,0 +1,10 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Keyset pagination walks these indexes for each supported sort order.
+CREATE INDEX events_start_time_id_idx ON events (start_time, id);
+CREATE INDEX events_name_id_idx ON events (name, id);
+CREATE INDEX events_lower_venue_idx ON events (LOWER(venue));
+
+-- Trigram indexes back the ILIKE text search on name and venue.
+CREATE INDEX events_name_trgm_idx ON events USING gin (name gin_trgm_ops);
+CREATE INDEX events_venue_trgm_idx ON events USING gin (venue gin_trgm_ops);
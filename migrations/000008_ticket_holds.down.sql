@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS ticket_holds;
+DROP TYPE IF EXISTS hold_status;
+ALTER TABLE events DROP COLUMN IF EXISTS held_tickets;
@@ -0,0 +1,20 @@
+-- Tickets reserved by an active hold count against capacity until the hold
+-- is confirmed, released or expires.
+ALTER TABLE events ADD COLUMN held_tickets INT NOT NULL DEFAULT 0 CHECK (held_tickets >= 0);
+
+CREATE TYPE hold_status AS ENUM ('active', 'confirmed', 'released', 'expired');
+
+CREATE TABLE ticket_holds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    event_id UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    status hold_status NOT NULL DEFAULT 'active',
+    expires_at TIMESTAMPTZ NOT NULL,
+    booking_id UUID REFERENCES bookings(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX ON ticket_holds (user_id);
+CREATE INDEX ticket_holds_active_expiry_idx ON ticket_holds (expires_at) WHERE status = 'active';
@@ -0,0 +1,17 @@
+-- Keys are scoped per user so two clients can never collide on each other's key.
+-- response_status stays NULL while the original request is still in flight.
+CREATE TABLE idempotency_keys (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key VARCHAR(255) NOT NULL,
+    request_method VARCHAR(10) NOT NULL,
+    request_path TEXT NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    response_status INT,
+    response_content_type TEXT,
+    response_body BYTEA,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ,
+    PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX ON idempotency_keys (created_at);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS waitlist_offers;
+DROP TYPE IF EXISTS waitlist_offer_status;
+ALTER TABLE events DROP COLUMN IF EXISTS waitlist_offer_minutes;
+ALTER TABLE events DROP COLUMN IF EXISTS waitlist_policy;
+DROP TYPE IF EXISTS waitlist_policy;
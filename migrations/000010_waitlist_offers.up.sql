@@ -0,0 +1,23 @@
+-- auto_book keeps the original behaviour of booking the next waitlisted user
+-- directly; offer reserves the tickets and lets the user accept or decline.
+CREATE TYPE waitlist_policy AS ENUM ('auto_book', 'offer');
+ALTER TABLE events ADD COLUMN waitlist_policy waitlist_policy NOT NULL DEFAULT 'auto_book';
+ALTER TABLE events ADD COLUMN waitlist_offer_minutes INT NOT NULL DEFAULT 1440 CHECK (waitlist_offer_minutes > 0);
+
+CREATE TYPE waitlist_offer_status AS ENUM ('pending', 'accepted', 'declined', 'expired');
+
+-- Offered tickets are counted in events.held_tickets while the offer is pending.
+CREATE TABLE waitlist_offers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    event_id UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    status waitlist_offer_status NOT NULL DEFAULT 'pending',
+    expires_at TIMESTAMPTZ NOT NULL,
+    booking_id UUID REFERENCES bookings(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    responded_at TIMESTAMPTZ
+);
+
+CREATE INDEX ON waitlist_offers (user_id, status);
+CREATE INDEX waitlist_offers_pending_expiry_idx ON waitlist_offers (expires_at) WHERE status = 'pending';
@@ -0,0 +1,24 @@
+-- A token family is one login session. Every refresh rotates the refresh
+-- token within its family; presenting an already-rotated token revokes the
+-- whole family. Access tokens carry the family id as "sid" so revoking a
+-- family also invalidates its outstanding access tokens.
+CREATE TABLE token_families (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ,
+    revoked_reason VARCHAR(32)
+);
+
+CREATE INDEX token_families_user_idx ON token_families (user_id) WHERE revoked_at IS NULL;
+
+CREATE TABLE refresh_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    family_id UUID NOT NULL REFERENCES token_families(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX refresh_tokens_family_idx ON refresh_tokens (family_id);
@@ -0,0 +1,13 @@
+-- Failed login counters, keyed by normalised email ('email' scope) and by
+-- client IP ('ip' scope). Unknown emails are tracked too so lockouts do not
+-- reveal which addresses are registered.
+CREATE TABLE login_failures (
+    scope VARCHAR(8) NOT NULL CHECK (scope IN ('email', 'ip')),
+    key TEXT NOT NULL,
+    failures INT NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMPTZ,
+    PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX login_failures_locked_idx ON login_failures (locked_until) WHERE locked_until IS NOT NULL;
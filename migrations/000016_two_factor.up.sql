@@ -0,0 +1,32 @@
+-- TOTP secrets live on the user. A secret without totp_enabled_at is an
+-- enrolment that has not been confirmed yet. totp_last_step records the last
+-- accepted time step so a code cannot be replayed.
+ALTER TABLE users
+    ADD COLUMN totp_secret TEXT,
+    ADD COLUMN totp_enabled_at TIMESTAMPTZ,
+    ADD COLUMN totp_last_step BIGINT;
+
+CREATE TABLE recovery_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL UNIQUE,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX recovery_codes_user_idx ON recovery_codes (user_id);
+
+-- A challenge bridges the password step and the second-factor step of a login.
+CREATE TABLE login_challenges (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Sessions remember whether they were opened with a second factor, so
+-- refreshed access tokens keep the same assurance.
+ALTER TABLE token_families ADD COLUMN mfa BOOLEAN NOT NULL DEFAULT FALSE;
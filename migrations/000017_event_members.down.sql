@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS event_members;
+DROP TYPE IF EXISTS event_member_role;
+
+-- Postgres cannot drop an enum value, so the type is rebuilt without it.
+UPDATE users SET role = 'user' WHERE role = 'organizer';
+ALTER TABLE users ALTER COLUMN role DROP DEFAULT;
+ALTER TYPE user_role RENAME TO user_role_old;
+CREATE TYPE user_role AS ENUM ('user', 'admin');
+ALTER TABLE users ALTER COLUMN role TYPE user_role USING role::text::user_role;
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'user';
+DROP TYPE user_role_old;
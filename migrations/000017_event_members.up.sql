@@ -0,0 +1,16 @@
+-- Organizers can create events and manage the ones they are members of,
+-- without being global admins.
+ALTER TYPE user_role ADD VALUE IF NOT EXISTS 'organizer';
+
+CREATE TYPE event_member_role AS ENUM ('owner', 'staff');
+
+CREATE TABLE event_members (
+    event_id UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role event_member_role NOT NULL,
+    added_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (event_id, user_id)
+);
+
+CREATE INDEX event_members_user_idx ON event_members (user_id);
@@ -0,0 +1,10 @@
+-- disabled_at blocks logins and ends existing sessions. password_reset_required
+-- makes the user set a new password through the reset email before logging in.
+ALTER TABLE users
+    ADD COLUMN disabled_at TIMESTAMPTZ,
+    ADD COLUMN password_reset_required BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Backs the admin user list (newest first) and its name/email search.
+CREATE INDEX users_created_at_id_idx ON users (created_at DESC, id DESC);
+CREATE INDEX users_name_trgm_idx ON users USING gin (name gin_trgm_ops);
+CREATE INDEX users_email_trgm_idx ON users USING gin (email gin_trgm_ops);
@@ -0,0 +1,5 @@
+ALTER TABLE bookings
+    DROP CONSTRAINT bookings_user_id_fkey,
+    ADD CONSTRAINT bookings_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,8 @@
+-- Erased accounts are anonymized rather than deleted. Bookings make up
+-- events.booked_tickets, so deleting a user must no longer silently take
+-- their bookings with it.
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMPTZ;
+
+ALTER TABLE bookings
+    DROP CONSTRAINT bookings_user_id_fkey,
+    ADD CONSTRAINT bookings_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
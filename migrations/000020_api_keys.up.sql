@@ -0,0 +1,19 @@
+-- Long-lived credentials for integrations. The key shown to the user is
+-- "evk_<prefix>_<secret>"; only the prefix (for lookup) and a hash of the
+-- secret are stored. Scopes are authz permission names and narrow what the
+-- owner's role would otherwise allow.
+CREATE TABLE api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix CHAR(8) NOT NULL UNIQUE,
+    secret_hash CHAR(64) NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX api_keys_user_idx ON api_keys (user_id);
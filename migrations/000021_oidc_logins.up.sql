@@ -0,0 +1,31 @@
+-- Links an account to an identity at an OpenID Connect provider. The subject
+-- is the provider's stable user ID; email is what it reported when linked.
+CREATE TABLE user_identities (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    issuer TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    email TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (issuer, subject)
+);
+
+CREATE INDEX user_identities_user_idx ON user_identities (user_id);
+
+-- An SSO login in progress. The state, nonce and PKCE verifier are created
+-- when the user is sent to the provider and checked on the way back. Once
+-- the provider has vouched for a user, user_id and handoff_hash are set and
+-- the browser trades the handoff token for a session.
+CREATE TABLE oidc_logins (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    state_hash CHAR(64) NOT NULL UNIQUE,
+    nonce TEXT NOT NULL,
+    code_verifier TEXT NOT NULL,
+    state_used_at TIMESTAMPTZ,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    handoff_hash CHAR(64) UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX oidc_logins_expires_idx ON oidc_logins (expires_at);
@@ -0,0 +1,5 @@
+ALTER TABLE waitlist_entries DROP COLUMN IF EXISTS tier_id;
+ALTER TABLE waitlist_offers DROP COLUMN IF EXISTS tier_id;
+ALTER TABLE ticket_holds DROP COLUMN IF EXISTS tier_id;
+ALTER TABLE bookings DROP COLUMN IF EXISTS tier_id;
+DROP TABLE IF EXISTS ticket_tiers;
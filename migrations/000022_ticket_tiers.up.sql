@@ -0,0 +1,33 @@
+-- Ticket types on sale for an event, each with its own price and inventory.
+-- sold and held mirror the event's booked_tickets and held_tickets for the
+-- tier; the event's capacity still caps all tiers together, so tier
+-- quantities may add up to more than the capacity.
+CREATE TABLE ticket_tiers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    event_id UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    price_cents BIGINT NOT NULL CHECK (price_cents >= 0),
+    currency CHAR(3) NOT NULL,
+    quantity INT NOT NULL CHECK (quantity >= 0),
+    sold INT NOT NULL DEFAULT 0,
+    held INT NOT NULL DEFAULT 0,
+    sales_start TIMESTAMPTZ,
+    sales_end TIMESTAMPTZ,
+    position INT NOT NULL DEFAULT 0,
+    version INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (event_id, name),
+    CHECK (sold >= 0 AND held >= 0 AND sold + held <= quantity),
+    CHECK (sales_start IS NULL OR sales_end IS NULL OR sales_end > sales_start)
+);
+
+CREATE INDEX ticket_tiers_event_idx ON ticket_tiers (event_id, position);
+
+-- Bookings, holds and offers made before tiers existed, or for events
+-- without tiers, have no tier. A tier that has been sold cannot be deleted.
+ALTER TABLE bookings ADD COLUMN tier_id UUID REFERENCES ticket_tiers(id);
+ALTER TABLE ticket_holds ADD COLUMN tier_id UUID REFERENCES ticket_tiers(id);
+ALTER TABLE waitlist_offers ADD COLUMN tier_id UUID REFERENCES ticket_tiers(id);
+ALTER TABLE waitlist_entries ADD COLUMN tier_id UUID REFERENCES ticket_tiers(id) ON DELETE CASCADE;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS event_seats;
+ALTER TABLE events DROP COLUMN IF EXISTS venue_id;
+DROP TABLE IF EXISTS venue_seats;
+DROP TABLE IF EXISTS venue_sections;
+DROP TABLE IF EXISTS venues;
@@ -0,0 +1,41 @@
+-- A venue's seat map: sections of numbered seats in labelled rows. Lower
+-- section ranks are better seats; row_position counts rows back from the
+-- front of the section.
+CREATE TABLE venues (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE venue_sections (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    venue_id UUID NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    rank INT NOT NULL DEFAULT 0,
+    UNIQUE (venue_id, name)
+);
+
+CREATE TABLE venue_seats (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    section_id UUID NOT NULL REFERENCES venue_sections(id) ON DELETE CASCADE,
+    row_label VARCHAR(10) NOT NULL,
+    row_position INT NOT NULL,
+    number INT NOT NULL,
+    UNIQUE (section_id, row_label, number)
+);
+
+ALTER TABLE events ADD COLUMN venue_id UUID REFERENCES venues(id);
+
+-- The seats of an event with reserved seating, copied from its venue when
+-- the seat map is attached. A seat is sold when booking_id is set; booking
+-- a seat locks its row, so two bookings can never take the same seat.
+CREATE TABLE event_seats (
+    event_id UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    seat_id UUID NOT NULL REFERENCES venue_seats(id),
+    tier_id UUID REFERENCES ticket_tiers(id),
+    booking_id UUID REFERENCES bookings(id) ON DELETE SET NULL,
+    PRIMARY KEY (event_id, seat_id)
+);
+
+CREATE INDEX event_seats_booking_idx ON event_seats (booking_id) WHERE booking_id IS NOT NULL;
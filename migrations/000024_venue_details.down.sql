@@ -0,0 +1,19 @@
+-- Events keep their venue names; only links to venues without a seat map
+-- are dropped, along with those venues.
+UPDATE events SET venue_id = NULL
+WHERE NOT EXISTS (SELECT 1 FROM event_seats WHERE event_id = events.id);
+DELETE FROM venues v
+WHERE NOT EXISTS (SELECT 1 FROM venue_sections WHERE venue_id = v.id)
+  AND NOT EXISTS (SELECT 1 FROM events WHERE venue_id = v.id);
+
+DROP INDEX IF EXISTS events_venue_idx;
+DROP INDEX IF EXISTS venues_key_idx;
+DROP FUNCTION IF EXISTS venue_key(TEXT);
+ALTER TABLE venues
+    DROP CONSTRAINT IF EXISTS venues_coordinates_check,
+    DROP COLUMN IF EXISTS version,
+    DROP COLUMN IF EXISTS longitude,
+    DROP COLUMN IF EXISTS latitude,
+    DROP COLUMN IF EXISTS default_capacity,
+    DROP COLUMN IF EXISTS timezone,
+    DROP COLUMN IF EXISTS address;
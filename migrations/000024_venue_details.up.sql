@@ -0,0 +1,52 @@
+-- Venues become a resource of their own, and every event with a venue
+-- refers to one instead of repeating its name.
+ALTER TABLE venues
+    ADD COLUMN address TEXT NOT NULL DEFAULT '',
+    ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    ADD COLUMN default_capacity INT CHECK (default_capacity >= 0),
+    ADD COLUMN latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90),
+    ADD COLUMN longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180),
+    ADD COLUMN version INT NOT NULL DEFAULT 1,
+    ADD CONSTRAINT venues_coordinates_check CHECK ((latitude IS NULL) = (longitude IS NULL));
+
+-- venue_key is what two spellings of the same venue have in common: case,
+-- punctuation and runs of whitespace are ignored.
+CREATE FUNCTION venue_key(name TEXT) RETURNS TEXT
+LANGUAGE sql IMMUTABLE PARALLEL SAFE
+AS $$
+    SELECT lower(btrim(regexp_replace(regexp_replace(name, '[[:punct:]]+', '', 'g'), '\s+', ' ', 'g')))
+$$;
+
+-- Venues that already share a key keep their seat maps and events; all
+-- but the oldest get their id appended so the key can be made unique.
+UPDATE venues v SET name = v.name || ' (' || left(v.id::text, 8) || ')'
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY venue_key(name) ORDER BY created_at, id) AS n
+    FROM venues
+) d
+WHERE d.id = v.id AND d.n > 1;
+UPDATE events e SET venue = v.name FROM venues v WHERE e.venue_id = v.id AND e.venue <> v.name;
+
+CREATE UNIQUE INDEX venues_key_idx ON venues (venue_key(name));
+CREATE INDEX events_venue_idx ON events (venue_id);
+
+-- One venue for each spelling group of the free-text venues, named after
+-- the group's most used spelling, unless a venue with that key exists.
+INSERT INTO venues (name)
+SELECT DISTINCT ON (key) spelling
+FROM (
+    SELECT venue_key(venue) AS key, regexp_replace(btrim(venue), '\s+', ' ', 'g') AS spelling, COUNT(*) AS uses
+    FROM events
+    WHERE venue_id IS NULL AND venue_key(venue) <> ''
+    GROUP BY 1, 2
+) spellings
+WHERE NOT EXISTS (SELECT 1 FROM venues v WHERE venue_key(v.name) = spellings.key)
+ORDER BY key, uses DESC, spelling;
+
+UPDATE events e SET venue_id = v.id, venue = v.name
+FROM (
+    SELECT DISTINCT ON (venue_key(name)) id, name, venue_key(name) AS key
+    FROM venues
+    ORDER BY venue_key(name), created_at, id
+) v
+WHERE e.venue_id IS NULL AND venue_key(e.venue) = v.key;
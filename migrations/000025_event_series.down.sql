@@ -0,0 +1,5 @@
+ALTER TABLE events
+    DROP CONSTRAINT IF EXISTS events_series_date_key,
+    DROP COLUMN IF EXISTS series_date,
+    DROP COLUMN IF EXISTS series_id;
+DROP TABLE IF EXISTS event_series;
@@ -0,0 +1,31 @@
+-- A series is a template for recurring events. Occurrences are ordinary
+-- events generated from it a rolling horizon ahead; each keeps its own
+-- capacity, bookings and waitlist. generated_through is the last local
+-- date occurrences have been generated for.
+CREATE TABLE event_series (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    venue VARCHAR(255) NOT NULL,
+    venue_id UUID REFERENCES venues(id),
+    capacity INT NOT NULL CHECK (capacity >= 0),
+    waitlist_policy waitlist_policy NOT NULL DEFAULT 'auto_book',
+    waitlist_offer_minutes INT NOT NULL DEFAULT 1440 CHECK (waitlist_offer_minutes > 0),
+    timezone VARCHAR(64) NOT NULL,
+    starts_at TIMESTAMPTZ NOT NULL,
+    rrule TEXT NOT NULL,
+    exclusions DATE[] NOT NULL DEFAULT '{}',
+    generated_through DATE NOT NULL,
+    owner_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    version INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX event_series_generated_idx ON event_series (generated_through);
+
+-- series_date is the local date the occurrence was generated for. It stays
+-- put when the occurrence is moved, so it is never generated twice.
+ALTER TABLE events
+    ADD COLUMN series_id UUID REFERENCES event_series(id) ON DELETE SET NULL,
+    ADD COLUMN series_date DATE,
+    ADD CONSTRAINT events_series_date_key UNIQUE (series_id, series_date);
@@ -0,0 +1,35 @@
+-- Sessions are parts of an event, such as conference breakouts, with their
+-- own room and capacity. registered mirrors the session's registrations
+-- and, like an event's booked_tickets, only changes with a version check.
+CREATE TABLE event_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    event_id UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    room VARCHAR(100) NOT NULL DEFAULT '',
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    capacity INT NOT NULL CHECK (capacity >= 0),
+    registered INT NOT NULL DEFAULT 0,
+    version INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (ends_at > starts_at),
+    CHECK (registered >= 0 AND registered <= capacity)
+);
+
+CREATE INDEX event_sessions_event_idx ON event_sessions (event_id, starts_at);
+
+-- A registration holds one place for the user, who must hold a booking for
+-- the event; booking_id is that booking. Sessions with registrations
+-- cannot be deleted.
+CREATE TABLE session_registrations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    session_id UUID NOT NULL REFERENCES event_sessions(id),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    booking_id UUID NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (session_id, user_id)
+);
+
+CREATE INDEX session_registrations_user_idx ON session_registrations (user_id);
+CREATE INDEX session_registrations_booking_idx ON session_registrations (booking_id);
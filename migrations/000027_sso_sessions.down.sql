@@ -0,0 +1,2 @@
+ALTER TABLE login_challenges DROP COLUMN IF EXISTS sso;
+ALTER TABLE token_families DROP COLUMN IF EXISTS sso;
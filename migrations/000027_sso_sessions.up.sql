@@ -0,0 +1,5 @@
+-- Sessions and pending two-factor logins remember whether the user signed
+-- in through the identity provider rather than with a password, so access
+-- tokens can say so in their amr claim.
+ALTER TABLE token_families ADD COLUMN sso BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE login_challenges ADD COLUMN sso BOOLEAN NOT NULL DEFAULT FALSE;
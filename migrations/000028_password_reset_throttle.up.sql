@@ -0,0 +1,4 @@
+-- Password reset requests are throttled per email address with the same
+-- counters as failed logins, under their own scope.
+ALTER TABLE login_failures DROP CONSTRAINT login_failures_scope_check;
+ALTER TABLE login_failures ADD CONSTRAINT login_failures_scope_check CHECK (scope IN ('email', 'ip', 'reset'));